@@ -1,10 +1,13 @@
 import {config} from '@client/config'
 import type {ApiResponse, Item} from '@client/types'
+import {newTraceparent} from '@client/utils'
 
 const API_BASE_URL = config.VITE_API_URL
 
 export async function getItems(): Promise<Item[]> {
-  const response = await fetch(`${API_BASE_URL}/api/items`)
+  const response = await fetch(`${API_BASE_URL}/api/items`, {
+    headers: {traceparent: newTraceparent()},
+  })
   const data: ApiResponse<Item[]> = await response.json()
 
   if (!data.success || !data.data) {
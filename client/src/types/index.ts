@@ -40,6 +40,198 @@ export interface Item {
   title: string
   description: string
   status: ItemStatus
+  externalId?: string // Caller-supplied ID for import upserts
+  parentId?: string // TypeID: item_xxx (subtasks only)
+  rank: string // Manual sort key; lists are ordered by rank ascending
+  dueAt?: Date
+  tags: string[]
+  seriesId?: string // TypeID: series_xxx (recurring items only)
+  recurrenceId?: Date // Originally scheduled time of this occurrence
+  createdAt: Date
+  updatedAt: Date
+  progress?: ItemProgress // Present for items with subtasks
+}
+
+export interface ItemProgress {
+  total: number
+  completed: number
+  percent: number
+}
+
+export interface ItemNode extends Item {
+  depth: number
+  children: ItemNode[]
+}
+
+export interface ItemImportRowError {
+  row: number
+  field?: string
+  message: string
+}
+
+export interface ItemImportReport {
+  format: 'csv' | 'json' | 'ndjson'
+  dryRun: boolean
+  committed: boolean
+  total: number
+  valid: number
+  created: number
+  updated: number
+  errors: ItemImportRowError[]
+}
+
+export type ItemEditScope = 'this' | 'future'
+
+export interface ItemSeries {
+  id: string // TypeID: series_xxx
+  userId: string
+  title: string
+  description: string
+  rrule: string // RFC 5545 RRULE value, e.g. FREQ=WEEKLY;BYDAY=MO
+  timezone: string
+  dtstart: Date
+  exdates: Date[]
+  createdAt: Date
+  updatedAt: Date
+}
+
+export interface CalendarFeedResponse {
+  url: string
+  webcalUrl: string
+  createdAt: Date
+}
+
+export type ItemEventType = 'item.created' | 'item.updated' | 'item.deleted' | 'items.imported'
+
+export interface ItemEvent {
+  id: number
+  userId: string
+  type: ItemEventType
+  itemId?: string
+  payload: unknown
+  createdAt: Date
+}
+
+export interface PresenceMember {
+  connectionId: string
+  userId: string
+  name: string
+  typing: boolean
+  editing: boolean
+  joinedAt: Date
+  lockedAt?: Date
+}
+
+export type PresenceClientMessage =
+  | { type: 'join' | 'leave' | 'lock' | 'unlock'; itemId: string }
+  | { type: 'typing'; itemId: string; typing: boolean }
+  | { type: 'ping' }
+
+export interface PresenceServerMessage {
+  type: 'presence' | 'lock' | 'lockLost' | 'error' | 'pong'
+  itemId?: string
+  members?: PresenceMember[]
+  lockedBy?: PresenceMember
+  granted?: boolean
+  error?: string
+}
+
+export type WebhookEventType =
+  | 'item.created'
+  | 'item.updated'
+  | 'item.deleted'
+  | 'items.imported'
+  | 'user.login'
+  | 'user.registered'
+  | 'webhook.ping'
+  | '*'
+
+export interface WebhookEndpoint {
+  id: string
+  userId: string
+  url: string
+  description: string
+  eventTypes: WebhookEventType[]
+  enabled: boolean
+  previousSecretExpiresAt?: Date
+  createdAt: Date
+  updatedAt: Date
+}
+
+export interface WebhookEndpointWithSecret extends WebhookEndpoint {
+  secret: string
+}
+
+export type WebhookDeliveryStatus = 'pending' | 'succeeded' | 'dead'
+
+export interface WebhookDeliveryAttempt {
+  id: number
+  deliveryId: string
+  attempt: number
+  responseStatus?: number
+  responseBody?: string
+  error?: string
+  durationMs: number
+  createdAt: Date
+}
+
+export interface WebhookDelivery {
+  id: string
+  endpointId: string
+  eventId: string
+  eventType: WebhookEventType
+  requestId?: string // API request that raised the event
+  payload: unknown
+  status: WebhookDeliveryStatus
+  attempts: number
+  nextAttemptAt: Date
+  lastAttemptAt?: Date
+  responseStatus?: number
+  lastError?: string
+  createdAt: Date
+  completedAt?: Date
+  attemptLog?: WebhookDeliveryAttempt[]
+}
+
+export type JobStatus = 'pending' | 'running' | 'completed' | 'failed' | 'cancelled'
+
+export interface Job {
+  id: string // TypeID: job_xxx
+  kind: string
+  args: unknown
+  priority: number
+  runAt: Date
+  uniqueKey?: string
+  status: JobStatus
+  attempts: number
+  maxAttempts: number
+  lockedUntil?: Date
+  lastError?: string
+  createdAt: Date
+  updatedAt: Date
+  finishedAt?: Date
+}
+
+export interface JobStats {
+  kind: string
+  status: JobStatus
+  count: number
+}
+
+export type TaskStatus = 'running' | 'succeeded' | 'failed'
+
+export interface ScheduledTask {
+  name: string
+  schedule: string // Cron expression (UTC)
+  nextRunAt: Date
+  lastRunAt?: Date
+  lastFinishedAt?: Date
+  lastStatus?: TaskStatus
+  lastError?: string
+  lastDurationMs?: number
+  lastRunBy?: string
+  runCount: number
+  failureCount: number
   createdAt: Date
   updatedAt: Date
 }
@@ -128,6 +320,7 @@ export interface ApiResponse<T> {
   data?: T
   error?: string
   message?: string
+  requestId?: string // Set on errors; quote it when reporting a problem
 }
 
 export interface PaginatedResponse<T> {
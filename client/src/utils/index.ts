@@ -18,3 +18,14 @@ export function validateEmail(email: string): boolean {
   const emailRegex = /^[^\s@]+@[^\s@]+\.[^\s@]+$/
   return emailRegex.test(email)
 }
+
+/**
+ * Create a W3C traceparent header value starting a new trace, so the server's
+ * spans and logs for a request share the client's trace ID. The client records
+ * no spans, so the trace is marked unsampled and the server's ratio decides.
+ */
+export function newTraceparent(): string {
+  const hex = (bytes: number) =>
+    Array.from(crypto.getRandomValues(new Uint8Array(bytes)), (b) => b.toString(16).padStart(2, '0')).join('')
+  return `00-${hex(16)}-${hex(8)}-00`
+}
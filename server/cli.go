@@ -0,0 +1,176 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+)
+
+// command is a subcommand of the server binary. run returns the exit code.
+type command struct {
+	name    string
+	summary string
+	run     func(cfg *config.Config, args []string) int
+}
+
+var commands = []command{
+	{"serve", "Start the HTTP server and background workers (default)", runServe},
+	{"migrate", "Apply, revert or list database migrations", runMigrate},
+	{"seed", "Insert sample users and items for development", runSeed},
+	{"user", "Create users, change roles and reset passwords", runUser},
+	{"sessions", "Purge sessions", runSessions},
+	{"keys", "Rotate the JWT signing secret", runKeys},
+	{"config", "Print the effective configuration", runConfig},
+}
+
+// run dispatches to the command named by the first argument
+func run(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		if err := cfg.Validate(); err != nil {
+			return invalidConfig(err)
+		}
+		return runServe(cfg, nil)
+	}
+
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage(os.Stdout)
+		return 0
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			// config reports problems itself, so a broken configuration can be inspected
+			if name != "config" {
+				if err := cfg.Validate(); err != nil {
+					return invalidConfig(err)
+				}
+			}
+			return cmd.run(cfg, args[1:])
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+	printUsage(os.Stderr)
+	return 2
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Monorepo API server v%s\n\nUsage: server <command> [arguments]\n\nCommands:\n", Version)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprint(w, "\nGlobal flags, before the command:\n"+
+		"  -config file      YAML or TOML config file (default $CONFIG_FILE)\n"+
+		"  -<setting> value  Override a setting, e.g. -port 8080 or -database-max-conns 50\n")
+	fmt.Fprintln(w, "\nRun 'server <command> -h' for the arguments of a command, and 'server config print'\nfor every setting.")
+}
+
+// openDB connects to the configured database for a command
+func openDB(cfg *config.Config) (*database.DB, error) {
+	if cfg.DatabaseURL == "" {
+		return nil, fmt.Errorf("DATABASE_URL is required")
+	}
+	// Commands read what they just wrote, so they stay on the primary
+	opts := databaseOptions(cfg)
+	opts.ReplicaURLs = nil
+	return database.Connect(cfg.DatabaseURL, opts)
+}
+
+func databaseOptions(cfg *config.Config) database.Options {
+	return database.Options{
+		ApplicationName:    "server",
+		MaxConns:           int32(cfg.DatabaseMaxConns),
+		MinConns:           int32(cfg.DatabaseMinConns),
+		MaxConnLifetime:    cfg.DatabaseMaxConnLifetime,
+		MaxConnIdleTime:    cfg.DatabaseMaxConnIdleTime,
+		HealthCheckPeriod:  cfg.DatabaseHealthCheckPeriod,
+		StatementTimeout:   cfg.DatabaseStatementTimeout,
+		ReadTimeout:        cfg.DatabaseReadTimeout,
+		WriteTimeout:       cfg.DatabaseWriteTimeout,
+		SlowQueryThreshold: cfg.DatabaseSlowQueryThreshold,
+		ReplicaURLs:        cfg.DatabaseReplicaURLs,
+		ReplicaMaxLag:      cfg.DatabaseReplicaMaxLag,
+	}
+}
+
+// newFlagSet returns a flag set for a command whose usage starts with the
+// given synopsis
+func newFlagSet(synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(synopsis, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: server %s\n", synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses flags that may come before or after positional
+// arguments, and returns the positional arguments
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// usageError reports a flag parsing error, or invalid positional arguments
+// when err is nil, and returns the exit code. -h is not an error.
+func usageError(fs *flag.FlagSet, err error) int {
+	switch {
+	case err == flag.ErrHelp:
+		return 0
+	case err == nil:
+		fs.Usage() // The flag package already printed usage for its own errors
+	}
+	return 2
+}
+
+// runSubcommand dispatches to the subcommand named by the first argument
+func runSubcommand(cfg *config.Config, group string, args []string, subcommands map[string]func(*config.Config, []string) int) int {
+	if len(args) > 0 {
+		if sub, ok := subcommands[args[0]]; ok {
+			return sub(cfg, args[1:])
+		}
+		fmt.Fprintf(os.Stderr, "Unknown %s command %q\n", group, args[0])
+	}
+
+	fmt.Fprintf(os.Stderr, "Usage: server %s <%s>\n", group, strings.Join(slices.Sorted(maps.Keys(subcommands)), "|"))
+	return 2
+}
+
+func parseRole(s string) (models.UserRole, error) {
+	switch role := models.UserRole(s); role {
+	case models.RoleUser, models.RoleModerator, models.RoleAdmin:
+		return role, nil
+	}
+	return "", fmt.Errorf("invalid role %q (want user, moderator or admin)", s)
+}
+
+// invalidConfig lists configuration problems and returns the exit code
+func invalidConfig(err error) int {
+	fmt.Fprintln(os.Stderr, "Invalid configuration:")
+	for _, line := range strings.Split(err.Error(), "\n") {
+		fmt.Fprintf(os.Stderr, "  %s\n", line)
+	}
+	return 1
+}
+
+func fail(format string, args ...any) int {
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
+	return 1
+}
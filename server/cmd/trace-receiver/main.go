@@ -0,0 +1,122 @@
+// Command trace-receiver is a local stand-in for an OpenTelemetry collector.
+// It accepts OTLP/HTTP trace exports and logs each span.
+//
+//	go run ./cmd/trace-receiver [-addr :4318]
+//
+// Start the server with OTEL_EXPORTER_OTLP_ENDPOINT=http://localhost:4318 to
+// see the spans of each request, its database queries and password checks.
+package main
+
+import (
+	"compress/gzip"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func main() {
+	addr := flag.String("addr", ":4318", "listen address")
+	flag.Parse()
+
+	http.HandleFunc("POST /v1/traces", func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = io.LimitReader(r.Body, 16<<20)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(body)
+			if err != nil {
+				http.Error(w, "bad gzip body", http.StatusBadRequest)
+				return
+			}
+			defer gz.Close()
+			body = gz
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			http.Error(w, "read failed", http.StatusBadRequest)
+			return
+		}
+
+		var req collectortrace.ExportTraceServiceRequest
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			err = protojson.Unmarshal(data, &req)
+		} else {
+			err = proto.Unmarshal(data, &req)
+		}
+		if err != nil {
+			log.Printf("REJECTED export: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, resourceSpans := range req.ResourceSpans {
+			for _, scopeSpans := range resourceSpans.ScopeSpans {
+				for _, span := range scopeSpans.Spans {
+					logSpan(span)
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		out, _ := proto.Marshal(&collectortrace.ExportTraceServiceResponse{})
+		w.Write(out)
+	})
+
+	log.Printf("Trace receiver listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+func logSpan(span *tracepb.Span) {
+	duration := time.Duration(span.EndTimeUnixNano - span.StartTimeUnixNano)
+	parent := "root"
+	if len(span.ParentSpanId) > 0 {
+		parent = hex.EncodeToString(span.ParentSpanId)
+	}
+
+	var attrs []string
+	for _, attr := range span.Attributes {
+		attrs = append(attrs, attr.Key+"="+anyValue(attr.Value))
+	}
+	status := ""
+	if span.Status != nil && span.Status.Code == tracepb.Status_STATUS_CODE_ERROR {
+		status = " ERROR " + span.Status.Message
+	}
+
+	line := fmt.Sprintf("trace=%s span=%s parent=%s %q %s%s",
+		hex.EncodeToString(span.TraceId),
+		hex.EncodeToString(span.SpanId),
+		parent,
+		span.Name,
+		duration.Round(time.Microsecond),
+		status,
+	)
+	if len(attrs) > 0 {
+		line += "\n  " + strings.Join(attrs, " ")
+	}
+	log.Print(line)
+}
+
+func anyValue(v *commonpb.AnyValue) string {
+	switch v := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return strconv.Quote(v.StringValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+	default:
+		return "?"
+	}
+}
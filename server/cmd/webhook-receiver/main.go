@@ -0,0 +1,58 @@
+// Command webhook-receiver is a local HTTP receiver for testing webhooks. It
+// verifies each delivery's signature and logs the event.
+//
+//	go run ./cmd/webhook-receiver -secret whsec_... [-addr :4000] [-status 500]
+//
+// Register http://localhost:4000/ as an endpoint in development. Use -status
+// to answer with a non-2xx code and exercise retries and dead-lettering.
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/webhooks"
+)
+
+func main() {
+	addr := flag.String("addr", ":4000", "listen address")
+	secret := flag.String("secret", os.Getenv("WEBHOOK_SECRET"), "endpoint signing secret (default $WEBHOOK_SECRET)")
+	status := flag.Int("status", http.StatusNoContent, "status code to respond with")
+	flag.Parse()
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "read failed", http.StatusBadRequest)
+			return
+		}
+
+		verified := "unverified (no -secret)"
+		if *secret != "" {
+			if err := webhooks.Verify(r.Header.Get(webhooks.HeaderSignature), *secret, body, webhooks.DefaultTolerance, time.Now()); err != nil {
+				log.Printf("REJECTED %s %s: %v", r.Header.Get(webhooks.HeaderEventType), r.Header.Get(webhooks.HeaderDelivery), err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			verified = "signature ok"
+		}
+
+		log.Printf("%s event=%s id=%s delivery=%s attempt=%s (%s)\n%s",
+			r.Method,
+			r.Header.Get(webhooks.HeaderEventType),
+			r.Header.Get(webhooks.HeaderEventID),
+			r.Header.Get(webhooks.HeaderDelivery),
+			r.Header.Get(webhooks.HeaderAttempt),
+			verified,
+			body,
+		)
+		w.WriteHeader(*status)
+	})
+
+	log.Printf("Webhook receiver listening on %s (responding %d)", *addr, *status)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+)
+
+func runConfig(cfg *config.Config, args []string) int {
+	return runSubcommand(cfg, "config", args, map[string]func(*config.Config, []string) int{
+		"print": runConfigPrint,
+	})
+}
+
+// runConfigPrint prints every setting with where its value came from, then
+// any problems that would stop the server from starting. Secrets are redacted
+// unless asked for, so the output is safe to paste into an issue or log;
+// --redacted is accepted for scripts that ask for that explicitly.
+func runConfigPrint(cfg *config.Config, args []string) int {
+	fs := newFlagSet("config print [--redacted | --show-secrets]")
+	redacted := fs.Bool("redacted", false, "redact secrets and database passwords (the default)")
+	showSecrets := fs.Bool("show-secrets", false, "print secrets and database passwords in full")
+	if positional, err := parseFlags(fs, args); err != nil || len(positional) > 0 {
+		return usageError(fs, err)
+	}
+	if *redacted && *showSecrets {
+		return fail("--redacted and --show-secrets cannot be used together")
+	}
+
+	if err := cfg.Write(os.Stdout, !*showSecrets); err != nil {
+		return fail("failed to print configuration: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return invalidConfig(err)
+	}
+	return 0
+}
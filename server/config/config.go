@@ -1,43 +1,149 @@
 package config
 
 import (
-	"os"
+	"net/url"
+	"slices"
+	"time"
 )
 
+// DefaultJWTSecret is the development fallback for JWT_SECRET; never deploy it
+const DefaultJWTSecret = "your-secret-key-change-in-production"
+
+// Config is the server configuration. Each setting is named by its config
+// tag in config files, and read from its default, the config file, its
+// environment variable and its flag, each overriding the one before. The
+// dev tag replaces the default in development.
 type Config struct {
-	Environment string
-	Port        string
-	DatabaseURL string
-	FrontendURL string
-	JWTSecret   string
-}
+	Environment string `config:"environment" env:"ENVIRONMENT" default:"development"`
+	Port        string `config:"port" env:"PORT" default:"3000"`
+	DatabaseURL string `config:"database.url" env:"DATABASE_URL" secret:"true"`
+	FrontendURL string `config:"frontend_url" env:"FRONTEND_URL" default:"http://localhost:5173"`
 
-func Load() *Config {
-	return &Config{
-		Environment: getEnv("ENVIRONMENT", "development"),
-		Port:        getEnv("PORT", "3000"),
-		DatabaseURL: getEnv("DATABASE_URL", ""),
-		FrontendURL: getEnv("FRONTEND_URL", "http://localhost:5173"),
-		JWTSecret:   getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
-	}
+	// APIURL is the public base URL of this server, from which links handed
+	// to clients (such as calendar feed URLs) are built instead of trusting
+	// the request's Host header
+	APIURL string `config:"api_url" env:"API_URL" default:"http://localhost:3000"`
+
+	// Connection pool settings, applied to the primary and each replica
+	DatabaseMaxConns          int           `config:"database.max_conns" env:"DATABASE_MAX_CONNS" default:"25"`
+	DatabaseMinConns          int           `config:"database.min_conns" env:"DATABASE_MIN_CONNS" default:"5"`
+	DatabaseMaxConnLifetime   time.Duration `config:"database.max_conn_lifetime" env:"DATABASE_MAX_CONN_LIFETIME" default:"1h"`
+	DatabaseMaxConnIdleTime   time.Duration `config:"database.max_conn_idle_time" env:"DATABASE_MAX_CONN_IDLE_TIME" default:"30m"`
+	DatabaseHealthCheckPeriod time.Duration `config:"database.health_check_period" env:"DATABASE_HEALTH_CHECK_PERIOD" default:"1m"`
+
+	// DatabaseStatementTimeout is enforced by Postgres on every statement;
+	// DatabaseReadTimeout and DatabaseWriteTimeout cancel list/get queries and
+	// other queries sooner. Zero disables a timeout.
+	DatabaseStatementTimeout time.Duration `config:"database.statement_timeout" env:"DATABASE_STATEMENT_TIMEOUT" default:"30s"`
+	DatabaseReadTimeout      time.Duration `config:"database.read_timeout" env:"DATABASE_READ_TIMEOUT" default:"5s"`
+	DatabaseWriteTimeout     time.Duration `config:"database.write_timeout" env:"DATABASE_WRITE_TIMEOUT" default:"10s"`
+
+	// DatabaseSlowQueryThreshold logs queries taking at least this long (zero disables)
+	DatabaseSlowQueryThreshold time.Duration `config:"database.slow_query_threshold" env:"DATABASE_SLOW_QUERY_THRESHOLD" default:"200ms"`
+
+	// DatabaseReplicaURLs are read replicas for GET requests; reads fall back
+	// to the primary when none is healthy
+	DatabaseReplicaURLs []string `config:"database.replica_urls" env:"DATABASE_REPLICA_URLS" secret:"true"`
+
+	// DatabaseReplicaMaxLag evicts replicas trailing the primary by more than
+	// this, and pins a client's reads to the primary for as long after it writes
+	DatabaseReplicaMaxLag time.Duration `config:"database.replica_max_lag" env:"DATABASE_REPLICA_MAX_LAG" default:"5s"`
+
+	JWTSecret string `config:"jwt.secret" env:"JWT_SECRET" secret:"true"`
+
+	// JWTPreviousSecrets still verify tokens after JWT_SECRET is rotated
+	JWTPreviousSecrets []string `config:"jwt.previous_secrets" env:"JWT_PREVIOUS_SECRETS" secret:"true"`
+
+	// PresenceBackend selects the presence pub/sub: "postgres" shares presence
+	// across instances, "memory" is for a single instance
+	PresenceBackend string `config:"presence.backend" env:"PRESENCE_BACKEND" default:"postgres"`
+
+	// OutboxSinks lists the sinks the outbox relay delivers domain events to:
+	// stream, webhooks, inprocess and pubsub
+	OutboxSinks []string `config:"outbox.sinks" env:"OUTBOX_SINKS" default:"stream,webhooks,inprocess"`
+
+	// JobConcurrency is how many background jobs this instance runs at once
+	JobConcurrency int `config:"jobs.concurrency" env:"JOB_CONCURRENCY" default:"4"`
+
+	// AutoMigrate applies pending migrations when the server starts
+	AutoMigrate bool `config:"database.auto_migrate" env:"AUTO_MIGRATE" default:"true"`
+
+	// MetricsPort serves /metrics on a separate internal port instead of the
+	// API port when set
+	MetricsPort string `config:"metrics.port" env:"METRICS_PORT"`
+
+	// OTelEndpoint is the OTLP/HTTP collector traces are exported to; tracing
+	// is off without one. TraceSampleRatio is the fraction of new traces kept.
+	OTelEndpoint     string  `config:"tracing.endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	OTelServiceName  string  `config:"tracing.service_name" env:"OTEL_SERVICE_NAME" default:"server"`
+	TraceSampleRatio float64 `config:"tracing.sample_ratio" env:"TRACE_SAMPLE_RATIO" default:"1"`
+
+	// TrustRequestID adopts well-formed X-Request-Id headers instead of always
+	// generating request IDs. Enable it only behind a proxy that sets or strips
+	// the header, since clients could otherwise choose the IDs in logs and traces.
+	TrustRequestID bool `config:"trust_request_id" env:"TRUST_REQUEST_ID" default:"false"`
+
+	// LogLevel is debug, info, warn or error; LogFormat is json or text
+	// (json by default outside development)
+	LogLevel  string `config:"log.level" env:"LOG_LEVEL" default:"info"`
+	LogFormat string `config:"log.format" env:"LOG_FORMAT"`
+
+	// ShutdownDelay keeps serving after SIGTERM while readiness reports
+	// failing, so load balancers stop routing here first. ShutdownTimeout
+	// bounds how long in-flight requests get to finish afterwards.
+	ShutdownDelay   time.Duration `config:"shutdown.delay" env:"SHUTDOWN_DELAY" default:"0s"`
+	ShutdownTimeout time.Duration `config:"shutdown.timeout" env:"SHUTDOWN_TIMEOUT" default:"20s"`
+
+	// CORSAllowedOrigins are allowed in addition to FrontendURL; "https://*.example.com"
+	// allows every subdomain. CORSMaxAge is how long browsers cache preflights.
+	CORSAllowedOrigins []string      `config:"cors.allowed_origins" env:"CORS_ALLOWED_ORIGINS" dev:"http://localhost:5173,http://localhost:3000"`
+	CORSAllowedMethods []string      `config:"cors.allowed_methods" env:"CORS_ALLOWED_METHODS" default:"GET,POST,PUT,DELETE,PATCH,OPTIONS"`
+	CORSAllowedHeaders []string      `config:"cors.allowed_headers" env:"CORS_ALLOWED_HEADERS" default:"Content-Type,Authorization,Last-Event-ID,X-Request-Id,traceparent,tracestate"`
+	CORSExposedHeaders []string      `config:"cors.exposed_headers" env:"CORS_EXPOSED_HEADERS" default:"Content-Length,X-Request-Id"`
+	CORSMaxAge         time.Duration `config:"cors.max_age" env:"CORS_MAX_AGE" default:"10m" dev:"0s"`
+
+	// Security headers sent with every response; empty omits a header and a
+	// zero HSTSMaxAge omits Strict-Transport-Security
+	HSTSMaxAge            time.Duration `config:"security.hsts_max_age" env:"HSTS_MAX_AGE" default:"8760h" dev:"0s"`
+	HSTSIncludeSubdomains bool          `config:"security.hsts_include_subdomains" env:"HSTS_INCLUDE_SUBDOMAINS" default:"true"`
+	HSTSPreload           bool          `config:"security.hsts_preload" env:"HSTS_PRELOAD" default:"false"`
+	ContentSecurityPolicy string        `config:"security.content_security_policy" env:"CONTENT_SECURITY_POLICY" default:"default-src 'none'; frame-ancestors 'none'"`
+	ReferrerPolicy        string        `config:"security.referrer_policy" env:"REFERRER_POLICY" default:"no-referrer"`
+	PermissionsPolicy     string        `config:"security.permissions_policy" env:"PERMISSIONS_POLICY" default:"camera=(), geolocation=(), microphone=(), payment=(), usb=()"`
+
+	// sources records where each setting's value came from, and problems
+	// the values that could not be parsed
+	sources  map[string]string
+	problems []error
 }
 
 func (c *Config) IsDevelopment() bool {
 	return c.Environment == "development"
 }
 
-func (c *Config) GetAllowedOrigins() []string {
-	origins := []string{
-		c.FrontendURL,
-		"http://localhost:5173",
-		"http://localhost:3000",
+func (c *Config) IsProduction() bool {
+	return c.Environment == "production"
+}
+
+// AppHost is the FrontendURL hostname, used where identifiers must stay the
+// same whichever address a request came in on
+func (c *Config) AppHost() string {
+	if u, err := url.Parse(c.FrontendURL); err == nil && u.Hostname() != "" {
+		return u.Hostname()
 	}
-	return origins
+	return "localhost"
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// GetAllowedOrigins returns the frontend origin and the configured CORS origins
+func (c *Config) GetAllowedOrigins() []string {
+	var origins []string
+	if u, err := url.Parse(c.FrontendURL); err == nil {
+		origins = append(origins, u.Scheme+"://"+u.Host)
+	}
+	for _, origin := range c.CORSAllowedOrigins {
+		if !slices.Contains(origins, origin) {
+			origins = append(origins, origin)
+		}
 	}
-	return defaultValue
+	return origins
 }
@@ -0,0 +1,238 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"go.yaml.in/yaml/v3"
+)
+
+// setting is a Config field described by its tags
+type setting struct {
+	key    string
+	env    string
+	def    string
+	dev    *string
+	secret bool
+	isBool bool
+	index  int
+}
+
+// flagName is the command line flag for the setting: its key with dots and
+// underscores as dashes, e.g. -database-max-conns
+func (s setting) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+var settings = func() []setting {
+	t := reflect.TypeFor[Config]()
+	var list []setting
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if key := field.Tag.Get("config"); key != "" {
+			s := setting{
+				key:    key,
+				env:    field.Tag.Get("env"),
+				def:    field.Tag.Get("default"),
+				secret: field.Tag.Get("secret") == "true",
+				isBool: field.Type.Kind() == reflect.Bool,
+				index:  i,
+			}
+			if dev, ok := field.Tag.Lookup("dev"); ok {
+				s.dev = &dev
+			}
+			list = append(list, s)
+		}
+	}
+	return list
+}()
+
+// Load reads the configuration and returns the arguments after the global
+// flags. The config file is named by -config or CONFIG_FILE. Values that do
+// not parse are reported by Validate; the error is for unusable flags or
+// config files, and is flag.ErrHelp for -h.
+func Load(args []string) (*Config, []string, error) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "")
+	flags := map[string]string{}
+	for _, s := range settings {
+		record := func(value string) error {
+			flags[s.key] = value
+			return nil
+		}
+		if s.isBool {
+			fs.BoolFunc(s.flagName(), s.key, record) // -auto-migrate alone means true
+		} else {
+			fs.Func(s.flagName(), s.key, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	var file map[string]string
+	if *configFile != "" {
+		var err error
+		if file, err = readFile(*configFile); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	cfg := &Config{sources: map[string]string{}}
+	fields := reflect.ValueOf(cfg).Elem()
+	set := func(s setting, value, source string) {
+		if err := parseValue(fields.Field(s.index), value); err != nil {
+			cfg.problems = append(cfg.problems, fmt.Errorf("%s (%s): %w", s.key, source, err))
+			return
+		}
+		cfg.sources[s.key] = source
+	}
+	// Environment comes first, so it is known before the other defaults
+	for _, s := range settings {
+		if s.dev != nil && cfg.IsDevelopment() {
+			set(s, *s.dev, "development default")
+		} else {
+			set(s, s.def, "default")
+		}
+		if value, ok := file[s.key]; ok {
+			set(s, value, "file "+*configFile)
+			delete(file, s.key)
+		}
+		value, source, err := lookupEnv(s.env)
+		if err != nil {
+			cfg.problems = append(cfg.problems, fmt.Errorf("%s (%s): %w", s.key, source, err))
+		} else if value != "" {
+			set(s, value, source)
+		}
+		if value, ok := flags[s.key]; ok {
+			set(s, value, "flag -"+s.flagName())
+		}
+	}
+	for key := range file {
+		cfg.problems = append(cfg.problems, fmt.Errorf("%s (file %s): unknown setting", key, *configFile))
+	}
+
+	// Development runs without a secret configured; nothing else may
+	if cfg.JWTSecret == "" && cfg.IsDevelopment() {
+		cfg.JWTSecret = DefaultJWTSecret
+		cfg.sources["jwt.secret"] = "development fallback"
+	}
+	return cfg, fs.Args(), nil
+}
+
+// lookupEnv reads an environment variable, or the file named by its _FILE
+// variant as mounted from a Kubernetes or Docker secret. Empty is unset.
+func lookupEnv(name string) (value, source string, err error) {
+	value, source = os.Getenv(name), "env "+name
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return value, source, nil
+	}
+	source = "env " + name + "_FILE"
+	if value != "" {
+		return "", source, fmt.Errorf("%s is also set", name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", source, err
+	}
+	return strings.TrimRight(string(data), "\r\n"), source, nil
+}
+
+// readFile reads a YAML or TOML config file into values keyed by setting,
+// joining nested keys with dots
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	raw := map[string]any{}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		_, err = toml.Decode(string(data), &raw)
+	default:
+		return nil, fmt.Errorf("config file %s: want a .yaml, .yml or .toml file", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	flatten("", raw, values)
+	return values, nil
+}
+
+func flatten(prefix string, raw map[string]any, values map[string]string) {
+	for key, value := range raw {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch value := value.(type) {
+		case map[string]any:
+			flatten(key, value, values)
+		case []any:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(value)
+		}
+	}
+}
+
+// parseValue sets a field from its string form; lists are comma-separated
+func parseValue(field reflect.Value, value string) error {
+	switch p := field.Addr().Interface().(type) {
+	case *string:
+		*p = value
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q (e.g. 30s or 5m)", value)
+		}
+		*p = d
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*p = n
+	case *float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*p = f
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*p = b
+	case *[]string:
+		*p = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*p = append(*p, item)
+			}
+		}
+	default:
+		panic(fmt.Sprintf("config: unsupported setting type %s", field.Type()))
+	}
+	return nil
+}
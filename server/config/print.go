@@ -0,0 +1,66 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"text/tabwriter"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/logging"
+)
+
+// Write prints every setting as YAML that a config file accepts, commented
+// with where its value came from. Redacting hides secrets, keeping the
+// username and host of database URLs.
+func (c *Config) Write(w io.Writer, redact bool) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fields := reflect.ValueOf(c).Elem()
+	for _, s := range settings {
+		value := fields.Field(s.index).Interface()
+		if redact && s.secret {
+			value = redactValue(value)
+		}
+		switch v := value.(type) {
+		case time.Duration:
+			value = v.String()
+		case []string:
+			if v == nil {
+				value = []string{}
+			}
+		}
+		encoded, err := json.Marshal(value) // JSON scalars and arrays are YAML
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(tw, "%s: %s\t# %s\n", s.key, encoded, c.sources[s.key])
+	}
+	return tw.Flush()
+}
+
+func redactValue(value any) any {
+	switch value := value.(type) {
+	case string:
+		return redactString(value)
+	case []string:
+		redacted := make([]string, len(value))
+		for i, s := range value {
+			redacted[i] = redactString(s)
+		}
+		return redacted
+	}
+	return logging.Redacted
+}
+
+func redactString(s string) string {
+	if s == "" {
+		return ""
+	}
+	if u, err := url.Parse(s); err == nil && u.Scheme != "" && u.Host != "" {
+		u.RawQuery = "" // Query parameters can carry passwords too
+		return u.Redacted()
+	}
+	return logging.Redacted
+}
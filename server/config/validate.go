@@ -0,0 +1,146 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// minSecretLength is the shortest JWT secret accepted in production, in bytes
+const minSecretLength = 32
+
+// placeholderSecrets are fragments of the example secrets shipped in this
+// repository's docs and templates
+var placeholderSecrets = []string{"change-in-production", "changeme", "your-secret", "your-jwt-secret"}
+
+// Validate reports every invalid setting at once, including values that did
+// not parse. In production it also refuses default or weak secrets.
+func (c *Config) Validate() error {
+	errs := slices.Clone(c.problems)
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s (%s): %s", key, c.sources[key], fmt.Sprintf(format, args...)))
+		}
+	}
+
+	check(slices.Contains([]string{"development", "test", "staging", "production"}, c.Environment),
+		"environment", "must be development, test, staging or production")
+	check(validPort(c.Port), "port", "invalid port %q", c.Port)
+	if c.MetricsPort != "" {
+		check(validPort(c.MetricsPort), "metrics.port", "invalid port %q", c.MetricsPort)
+		check(c.MetricsPort != c.Port, "metrics.port", "must differ from port")
+	}
+	check(validHTTPURL(c.FrontendURL), "frontend_url", "must be an http or https URL")
+	check(validHTTPURL(c.APIURL), "api_url", "must be an http or https URL")
+
+	if c.DatabaseURL != "" {
+		_, err := pgx.ParseConfig(c.DatabaseURL)
+		check(err == nil, "database.url", "%v", err)
+	}
+	for _, replica := range c.DatabaseReplicaURLs {
+		_, err := pgx.ParseConfig(replica)
+		check(err == nil, "database.replica_urls", "%v", err)
+	}
+	check(c.DatabaseMaxConns >= 1, "database.max_conns", "must be at least 1")
+	check(c.DatabaseMinConns >= 0 && c.DatabaseMinConns <= c.DatabaseMaxConns,
+		"database.min_conns", "must be between 0 and database.max_conns")
+	for _, d := range []struct {
+		key     string
+		invalid bool
+	}{
+		{"database.max_conn_lifetime", c.DatabaseMaxConnLifetime < 0},
+		{"database.max_conn_idle_time", c.DatabaseMaxConnIdleTime < 0},
+		{"database.health_check_period", c.DatabaseHealthCheckPeriod <= 0},
+		{"database.statement_timeout", c.DatabaseStatementTimeout < 0},
+		{"database.read_timeout", c.DatabaseReadTimeout < 0},
+		{"database.write_timeout", c.DatabaseWriteTimeout < 0},
+		{"database.slow_query_threshold", c.DatabaseSlowQueryThreshold < 0},
+		{"database.replica_max_lag", c.DatabaseReplicaMaxLag <= 0},
+		{"shutdown.delay", c.ShutdownDelay < 0},
+		{"shutdown.timeout", c.ShutdownTimeout <= 0},
+		{"cors.max_age", c.CORSMaxAge < 0},
+		{"security.hsts_max_age", c.HSTSMaxAge < 0},
+	} {
+		check(!d.invalid, d.key, "out of range")
+	}
+
+	check(c.PresenceBackend == "postgres" || c.PresenceBackend == "memory",
+		"presence.backend", "must be postgres or memory")
+	check(c.JobConcurrency >= 1, "jobs.concurrency", "must be at least 1")
+	if c.OTelEndpoint != "" {
+		check(validHTTPURL(c.OTelEndpoint), "tracing.endpoint", "must be an http or https URL")
+	}
+	check(c.TraceSampleRatio >= 0 && c.TraceSampleRatio <= 1, "tracing.sample_ratio", "must be between 0 and 1")
+	check(slices.Contains([]string{"debug", "info", "warn", "error"}, strings.ToLower(c.LogLevel)),
+		"log.level", "must be debug, info, warn or error")
+	check(slices.Contains([]string{"", "json", "text"}, c.LogFormat), "log.format", "must be json or text")
+
+	for _, origin := range c.CORSAllowedOrigins {
+		check(validOrigin(origin), "cors.allowed_origins",
+			"invalid origin %q (want scheme://host[:port] or scheme://*.domain)", origin)
+	}
+	for _, method := range c.CORSAllowedMethods {
+		check(method != "" && strings.ToUpper(method) == method && !strings.ContainsAny(method, " ,"),
+			"cors.allowed_methods", "invalid method %q", method)
+	}
+
+	check(c.JWTSecret != "", "jwt.secret", "required outside development")
+	if c.IsProduction() {
+		if c.JWTSecret != "" {
+			check(!weakSecret(c.JWTSecret), "jwt.secret",
+				"refusing a default or weak secret in production; use at least %d random bytes (server keys rotate)", minSecretLength)
+		}
+		check(!slices.Contains(c.JWTPreviousSecrets, DefaultJWTSecret), "jwt.previous_secrets",
+			"must not contain the default development secret")
+		check(c.DatabaseURL != "", "database.url", "required in production; the in-memory store loses data on restart")
+		if u, err := url.Parse(c.DatabaseURL); err == nil && u.User != nil {
+			password, _ := u.User.Password()
+			check(password != "postgres" && password != "password", "database.url",
+				"refusing the default database password in production")
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// weakSecret reports secrets that are short, copied from an example or
+// made of few distinct characters
+func weakSecret(secret string) bool {
+	if len(secret) < minSecretLength {
+		return true
+	}
+	lower := strings.ToLower(secret)
+	for _, placeholder := range placeholderSecrets {
+		if strings.Contains(lower, placeholder) {
+			return true
+		}
+	}
+	distinct := map[rune]bool{}
+	for _, r := range secret {
+		distinct[r] = true
+	}
+	return len(distinct) < 10
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 1 && n <= 65535
+}
+
+func validHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validOrigin accepts what the CORS middleware matches: a bare origin,
+// optionally with a wildcard subdomain. "*" is refused since credentials are
+// allowed.
+func validOrigin(origin string) bool {
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://", 1))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.User == nil && u.Path == "" && u.RawQuery == "" && u.Fragment == ""
+}
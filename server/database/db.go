@@ -3,31 +3,74 @@ package database
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type DB struct {
 	Pool *pgxpool.Pool
+
+	applicationName string
+
+	readTimeout    time.Duration
+	writeTimeout   time.Duration
+	queryObservers atomic.Pointer[[]func(QueryStat)]
+
+	replicas     *replicaSet
+	stopReplicas context.CancelFunc
 }
 
-func Connect(databaseURL string) (*DB, error) {
-	config, err := pgxpool.ParseConfig(databaseURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse database URL: %w", err)
-	}
+// Options configures the connection pools and query limits. Zero values
+// keep the pgx defaults and disable the corresponding timeout.
+type Options struct {
+	// ApplicationName identifies the server's connections in pg_stat_activity
+	// and server logs. Transactions begun for a request append its ID.
+	ApplicationName string
+
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+
+	// StatementTimeout is enforced by Postgres on every statement, as a backstop
+	StatementTimeout time.Duration
+
+	// ReadTimeout and WriteTimeout cancel list/get queries and all other
+	// queries made through DB methods
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// SlowQueryThreshold logs queries taking at least this long
+	SlowQueryThreshold time.Duration
 
-	// Connection pool settings
-	config.MaxConns = 25
-	config.MinConns = 5
-	config.MaxConnLifetime = time.Hour
-	config.MaxConnIdleTime = 30 * time.Minute
-	config.HealthCheckPeriod = time.Minute
+	// ReplicaURLs are read replicas serving list and get queries for
+	// contexts prepared with ReadFromReplicas
+	ReplicaURLs []string
 
-	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	// ReplicaMaxLag evicts replicas trailing the primary by more than this,
+	// and is how long a session's reads stay on the primary after it writes
+	ReplicaMaxLag time.Duration
+}
+
+func Connect(databaseURL string, opts Options) (*DB, error) {
+	db := &DB{
+		applicationName: opts.ApplicationName,
+		readTimeout:     opts.ReadTimeout,
+		writeTimeout:    opts.WriteTimeout,
+	}
+	tracer := &queryTracer{db: db, slowThreshold: opts.SlowQueryThreshold}
+
+	config, err := poolConfig(databaseURL, opts, tracer)
 	if err != nil {
+		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	if db.Pool, err = pgxpool.NewWithConfig(context.Background(), config); err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
 
@@ -35,21 +78,103 @@ func Connect(databaseURL string) (*DB, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := pool.Ping(ctx); err != nil {
+	if err := db.Pool.Ping(ctx); err != nil {
+		db.Pool.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Println("✅ Database connected successfully")
+	slog.Info("Database connected")
+
+	if len(opts.ReplicaURLs) > 0 {
+		db.replicas = &replicaSet{maxLag: opts.ReplicaMaxLag}
+		for _, url := range opts.ReplicaURLs {
+			// An unreachable replica is evicted, not fatal: reads fall back to the primary
+			config, err := poolConfig(url, opts, tracer)
+			if err != nil {
+				db.Close()
+				return nil, fmt.Errorf("failed to parse replica URL: %w", err)
+			}
+			replicaPool, err := pgxpool.NewWithConfig(context.Background(), config)
+			if err != nil {
+				db.Close()
+				return nil, fmt.Errorf("failed to create replica pool: %w", err)
+			}
+			name := fmt.Sprintf("%s:%d", config.ConnConfig.Host, config.ConnConfig.Port)
+			db.replicas.replicas = append(db.replicas.replicas, &replica{name: name, pool: replicaPool})
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		db.stopReplicas = cancel
+		db.replicas.check(ctx)
+		go db.replicas.monitor(ctx)
+	}
 
-	return &DB{Pool: pool}, nil
+	return db, nil
+}
+
+// poolConfig parses url and applies the pool settings from opts
+func poolConfig(url string, opts Options, tracer pgx.QueryTracer) (*pgxpool.Config, error) {
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.MaxConns > 0 {
+		config.MaxConns = opts.MaxConns
+	}
+	if opts.MinConns > 0 {
+		config.MinConns = min(opts.MinConns, config.MaxConns)
+	}
+	if opts.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = opts.MaxConnLifetime
+	}
+	if opts.MaxConnIdleTime > 0 {
+		config.MaxConnIdleTime = opts.MaxConnIdleTime
+	}
+	if opts.HealthCheckPeriod > 0 {
+		config.HealthCheckPeriod = opts.HealthCheckPeriod
+	}
+
+	// Settings given in the URL take precedence
+	params := config.ConnConfig.RuntimeParams
+	if _, set := params["application_name"]; !set && opts.ApplicationName != "" {
+		params["application_name"] = opts.ApplicationName
+	}
+	if _, set := params["statement_timeout"]; !set && opts.StatementTimeout > 0 {
+		params["statement_timeout"] = strconv.FormatInt(opts.StatementTimeout.Milliseconds(), 10)
+	}
+
+	config.ConnConfig.Tracer = tracer
+	return config, nil
 }
 
 func (db *DB) Close() {
+	if db.replicas != nil {
+		if db.stopReplicas != nil {
+			db.stopReplicas()
+		}
+		for _, r := range db.replicas.replicas {
+			r.pool.Close()
+		}
+	}
 	db.Pool.Close()
 }
 
-func (db *DB) Health() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+// PoolStats returns the connection pool statistics of the primary, named
+// "primary", and of each replica, named by host and port
+func (db *DB) PoolStats() map[string]*pgxpool.Stat {
+	stats := map[string]*pgxpool.Stat{"primary": db.Pool.Stat()}
+	if db.replicas != nil {
+		for _, r := range db.replicas.replicas {
+			stats[r.name] = r.pool.Stat()
+		}
+	}
+	return stats
+}
+
+// Health pings the primary
+func (db *DB) Health(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 	return db.Pool.Ping(ctx)
 }
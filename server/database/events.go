@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ItemEventsChannel is the NOTIFY channel carrying {"id", "userId"} for each new item event
+const ItemEventsChannel = "item_events"
+
+// RecordItemEvent appends an event to the item event log. The insert trigger
+// notifies every replica listening on ItemEventsChannel.
+func (db *DB) RecordItemEvent(ctx context.Context, userID string, eventType models.ItemEventType, itemID *string, payload any) (*models.ItemEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &models.ItemEvent{
+		UserID:  userID,
+		Type:    eventType,
+		ItemID:  itemID,
+		Payload: data,
+	}
+	query := `
+		INSERT INTO item_events (user_id, type, item_id, payload)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	err = db.conn(ctx).QueryRow(ctx, query, userID, eventType, itemID, data).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (db *DB) GetItemEvent(ctx context.Context, id int64) (*models.ItemEvent, error) {
+	var event models.ItemEvent
+	query := `SELECT id, user_id, type, item_id, payload, created_at FROM item_events WHERE id = $1`
+	err := db.conn(ctx).QueryRow(ctx, query, id).Scan(
+		&event.ID, &event.UserID, &event.Type, &event.ItemID, &event.Payload, &event.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// GetItemEventsAfter returns up to limit of the user's events with an ID above afterID, oldest first
+func (db *DB) GetItemEventsAfter(ctx context.Context, userID string, afterID int64, limit int) ([]*models.ItemEvent, error) {
+	query := `
+		SELECT id, user_id, type, item_id, payload, created_at
+		FROM item_events
+		WHERE user_id = $1 AND id > $2
+		ORDER BY id ASC
+		LIMIT $3
+	`
+	rows, err := db.conn(ctx).Query(ctx, query, userID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*models.ItemEvent
+	for rows.Next() {
+		var event models.ItemEvent
+		if err := rows.Scan(
+			&event.ID, &event.UserID, &event.Type, &event.ItemID, &event.Payload, &event.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+	return events, rows.Err()
+}
+
+// GetOldestItemEventID returns the lowest retained event ID for a user, or 0 if none
+func (db *DB) GetOldestItemEventID(ctx context.Context, userID string) (int64, error) {
+	var id int64
+	err := db.conn(ctx).QueryRow(ctx, `SELECT COALESCE(MIN(id), 0) FROM item_events WHERE user_id = $1`, userID).Scan(&id)
+	return id, err
+}
+
+// PruneItemEvents deletes events older than retention and returns how many were removed
+func (db *DB) PruneItemEvents(ctx context.Context, retention time.Duration) (int64, error) {
+	result, err := db.conn(ctx).Exec(ctx, `DELETE FROM item_events WHERE created_at < $1`, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// Listen acquires a dedicated connection and LISTENs on channel. The caller
+// should close the underlying connection before releasing it so that it does
+// not return to the pool still listening.
+func (db *DB) Listen(ctx context.Context, channel string) (*pgxpool.Conn, error) {
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		conn.Release()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Notify sends a NOTIFY with payload on channel
+func (db *DB) Notify(ctx context.Context, channel, payload string) error {
+	_, err := db.conn(ctx).Exec(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	return err
+}
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// JobsChannel is notified (without payload) when jobs are inserted
+const JobsChannel = "jobs"
+
+// ErrDuplicateJob is returned when a retried job's unique key is taken by
+// another pending or running job
+var ErrDuplicateJob = errors.New("a job with this unique key is pending or running")
+
+const jobColumns = `id, kind, args, priority, run_at, unique_key, status, attempts, max_attempts,
+	locked_until, last_error, created_at, updated_at, finished_at`
+
+func scanJob(row pgx.Row, j *models.Job) error {
+	return row.Scan(
+		&j.ID, &j.Kind, &j.Args, &j.Priority, &j.RunAt, &j.UniqueKey, &j.Status, &j.Attempts, &j.MaxAttempts,
+		&j.LockedUntil, &j.LastError, &j.CreatedAt, &j.UpdatedAt, &j.FinishedAt,
+	)
+}
+
+func collectJobs(rows pgx.Rows) ([]*models.Job, error) {
+	defer rows.Close()
+
+	jobs := []*models.Job{}
+	for rows.Next() {
+		var j models.Job
+		if err := scanJob(rows, &j); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, &j)
+	}
+	return jobs, rows.Err()
+}
+
+// InsertJob queues a job. If the job has a unique key and a pending or
+// running job with that key exists, nothing is inserted, job is filled from
+// the existing one and false is returned.
+func (db *DB) InsertJob(ctx context.Context, job *models.Job) (bool, error) {
+	query := `
+		INSERT INTO jobs (id, kind, args, priority, run_at, unique_key, max_attempts)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (unique_key) WHERE unique_key IS NOT NULL AND status IN ('pending', 'running') DO NOTHING
+		RETURNING ` + jobColumns
+
+	err := scanJob(db.conn(ctx).QueryRow(ctx, query,
+		job.ID, job.Kind, job.Args, job.Priority, job.RunAt, job.UniqueKey, job.MaxAttempts,
+	), job)
+	if err != pgx.ErrNoRows {
+		return err == nil, err
+	}
+
+	query = `SELECT ` + jobColumns + ` FROM jobs WHERE unique_key = $1 AND status IN ('pending', 'running')`
+	err = scanJob(db.conn(ctx).QueryRow(ctx, query, job.UniqueKey), job)
+	if err == pgx.ErrNoRows {
+		// The existing job finished in the meantime
+		return false, nil
+	}
+	return false, err
+}
+
+// ClaimJobs marks up to limit due jobs of the given kinds as running, highest
+// priority first, and leases them for the given duration. Running jobs whose
+// lease expired (their worker died) are claimed again.
+func (db *DB) ClaimJobs(ctx context.Context, kinds []string, limit int, lease time.Duration) ([]*models.Job, error) {
+	query := `
+		WITH due AS (
+			SELECT id FROM jobs
+			WHERE kind = ANY($1) AND (
+				(status = 'pending' AND run_at <= CURRENT_TIMESTAMP) OR
+				(status = 'running' AND locked_until < CURRENT_TIMESTAMP)
+			)
+			ORDER BY priority DESC, run_at, id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE jobs j
+		SET status = 'running',
+			attempts = j.attempts + 1,
+			locked_until = CURRENT_TIMESTAMP + make_interval(secs => $3)
+		FROM due WHERE j.id = due.id
+		RETURNING ` + prefixColumns("j", jobColumns)
+
+	rows, err := db.conn(ctx).Query(ctx, query, kinds, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	return collectJobs(rows)
+}
+
+// CompleteJob finishes a running job. The attempt acts as a fencing token:
+// it returns false when the job was cancelled or claimed again meanwhile.
+func (db *DB) CompleteJob(ctx context.Context, id string, attempt int) (bool, error) {
+	query := `
+		UPDATE jobs
+		SET status = 'completed', locked_until = NULL, last_error = NULL, finished_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'running' AND attempts = $2
+	`
+	result, err := db.conn(ctx).Exec(ctx, query, id, attempt)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// FailJob records a failed attempt. A nil runAt fails the job permanently;
+// otherwise it is retried at runAt. Fenced like CompleteJob.
+func (db *DB) FailJob(ctx context.Context, id string, attempt int, lastError string, runAt *time.Time) (bool, error) {
+	query := `
+		UPDATE jobs
+		SET status = CASE WHEN $4::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+			run_at = COALESCE($4, run_at),
+			last_error = $3,
+			locked_until = NULL,
+			finished_at = CASE WHEN $4::timestamptz IS NULL THEN CURRENT_TIMESTAMP END
+		WHERE id = $1 AND status = 'running' AND attempts = $2
+	`
+	result, err := db.conn(ctx).Exec(ctx, query, id, attempt, lastError, runAt)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (db *DB) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	var j models.Job
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE id = $1`
+	if err := scanJob(db.read(ctx).QueryRow(ctx, query, id), &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// ListJobs returns the most recent jobs, optionally filtered by status and kind
+func (db *DB) ListJobs(ctx context.Context, status *models.JobStatus, kind *string, limit int) ([]*models.Job, error) {
+	query := `
+		SELECT ` + jobColumns + ` FROM jobs
+		WHERE ($1::text IS NULL OR status = $1) AND ($2::text IS NULL OR kind = $2)
+		ORDER BY created_at DESC
+		LIMIT $3
+	`
+	rows, err := db.read(ctx).Query(ctx, query, status, kind, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectJobs(rows)
+}
+
+func (db *DB) GetJobStats(ctx context.Context) ([]models.JobStats, error) {
+	query := `SELECT kind, status, COUNT(*) FROM jobs GROUP BY kind, status ORDER BY kind, status`
+	rows, err := db.read(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.JobStats])
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		stats = []models.JobStats{}
+	}
+	return stats, nil
+}
+
+// RetryJob makes a failed or cancelled job pending again, or runs a pending
+// job now, granting at least one more attempt. Returns pgx.ErrNoRows if the
+// job does not exist or is running or completed.
+func (db *DB) RetryJob(ctx context.Context, id string) (*models.Job, error) {
+	var j models.Job
+	query := `
+		UPDATE jobs
+		SET status = 'pending',
+			run_at = CURRENT_TIMESTAMP,
+			max_attempts = GREATEST(max_attempts, attempts + 1),
+			finished_at = NULL
+		WHERE id = $1 AND status IN ('pending', 'failed', 'cancelled')
+		RETURNING ` + jobColumns
+	if err := scanJob(db.conn(ctx).QueryRow(ctx, query, id), &j); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return nil, ErrDuplicateJob
+		}
+		return nil, err
+	}
+	return &j, nil
+}
+
+// CancelJob cancels a pending or running job. A running job is not
+// interrupted, but its outcome is discarded. Returns pgx.ErrNoRows if the job
+// does not exist or has already finished.
+func (db *DB) CancelJob(ctx context.Context, id string) (*models.Job, error) {
+	var j models.Job
+	query := `
+		UPDATE jobs
+		SET status = 'cancelled', locked_until = NULL, finished_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status IN ('pending', 'running')
+		RETURNING ` + jobColumns
+	if err := scanJob(db.conn(ctx).QueryRow(ctx, query, id), &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// PruneJobs deletes jobs that finished longer than retention ago
+func (db *DB) PruneJobs(ctx context.Context, retention time.Duration) (int64, error) {
+	query := `DELETE FROM jobs WHERE finished_at < $1`
+	result, err := db.conn(ctx).Exec(ctx, query, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	listenRetryMin = time.Second
+	listenRetryMax = 30 * time.Second
+)
+
+// ListenLoop LISTENs on a Postgres channel and passes each notification to
+// handle until ctx is done, reconnecting with exponential backoff
+func (db *DB) ListenLoop(ctx context.Context, channel string, handle func(*pgconn.Notification)) {
+	retry := listenRetryMin
+	for ctx.Err() == nil {
+		err := db.listenOnce(ctx, channel, handle)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("Listener stopped, retrying", "channel", channel, "error", err, "retry_in", retry)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		retry = min(retry*2, listenRetryMax)
+	}
+}
+
+func (db *DB) listenOnce(ctx context.Context, channel string, handle func(*pgconn.Notification)) error {
+	conn, err := db.Listen(ctx, channel)
+	if err != nil {
+		return err
+	}
+	// Close rather than return a LISTENing connection to the pool
+	defer func() {
+		conn.Conn().Close(context.Background())
+		conn.Release()
+	}()
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		handle(notification)
+	}
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Advisory lock keys. Keep them unique across the application.
+const (
+	SchedulerLockKey int64 = 727_001
+	MigrationLockKey int64 = 727_002
+)
+
+// AdvisoryLock is a session-level advisory lock held on a dedicated pooled
+// connection. Postgres releases it if that connection is lost.
+type AdvisoryLock struct {
+	conn *pgxpool.Conn
+	key  int64
+}
+
+// TryAdvisoryLock takes the lock without waiting. It returns nil if another
+// session holds it.
+func (db *DB) TryAdvisoryLock(ctx context.Context, key int64) (*AdvisoryLock, error) {
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var acquired bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&acquired); err != nil {
+		conn.Release()
+		return nil, err
+	}
+	if !acquired {
+		conn.Release()
+		return nil, nil
+	}
+	return &AdvisoryLock{conn: conn, key: key}, nil
+}
+
+// WaitAdvisoryLock takes the lock, waiting until it is free or ctx is done.
+// The statement timeout is lifted on the lock's connection for as long as it
+// is held, since both the wait and the work done under the lock (such as
+// migrations) can legitimately take long.
+func (db *DB) WaitAdvisoryLock(ctx context.Context, key int64) (*AdvisoryLock, error) {
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Exec(ctx, `SET statement_timeout = 0`); err != nil {
+		conn.Release()
+		return nil, err
+	}
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, key); err != nil {
+		// The connection's timeout is lifted, so it must not go back to the pool as is
+		conn.Conn().Close(ctx)
+		conn.Release()
+		return nil, err
+	}
+	return &AdvisoryLock{conn: conn, key: key}, nil
+}
+
+// Check verifies the lock's connection is alive, and so the lock still held
+func (l *AdvisoryLock) Check(ctx context.Context) error {
+	return l.conn.Ping(ctx)
+}
+
+// Release unlocks and returns the connection. If unlocking fails the
+// connection is closed, which releases the lock too.
+func (l *AdvisoryLock) Release(ctx context.Context) {
+	if _, err := l.conn.Exec(ctx, `SELECT pg_advisory_unlock($1)`, l.key); err != nil {
+		l.conn.Conn().Close(ctx)
+	} else if _, err := l.conn.Exec(ctx, `RESET statement_timeout`); err != nil {
+		l.conn.Conn().Close(ctx)
+	}
+	l.conn.Release()
+}
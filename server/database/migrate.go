@@ -0,0 +1,349 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migrations are embedded from migrations/NNNN_name.up.sql, with an optional
+// NNNN_name.down.sql to revert each one
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// noTxDirective on the first line of a script runs it outside a transaction,
+// e.g. for CREATE INDEX CONCURRENTLY
+const noTxDirective = "-- migrate:no-transaction"
+
+// ErrMigrationDrift is returned when an applied migration's script has changed
+// since it was applied
+var ErrMigrationDrift = errors.New("applied migration has been modified")
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string // Empty if the migration cannot be reverted
+	Checksum string // SHA-256 of the up script
+}
+
+// MigrationStatus describes a migration known to this binary, the database
+// or both
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time // Nil while pending
+	Drifted   bool       // Applied from a different script than the embedded one
+	Unknown   bool       // Applied, but not embedded in this binary (e.g. by a newer version)
+}
+
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// LoadMigrations returns the embedded migrations ordered by version
+func LoadMigrations() ([]*Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		stem, direction, _ := strings.Cut(base, ".")
+		versionPart, name, ok := strings.Cut(stem, "_")
+		version, err := strconv.ParseInt(versionPart, 10, 64)
+		if !ok || err != nil || version <= 0 || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %q, want NNNN_name.up.sql or NNNN_name.down.sql", entry.Name())
+		}
+
+		data, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		script := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %04d has two names: %s and %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = script
+		} else {
+			m.Down = script
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", m.Version, m.Name)
+		}
+		sum := sha256.Sum256([]byte(m.Up))
+		m.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, m)
+	}
+	slices.SortFunc(migrations, func(a, b *Migration) int { return cmp.Compare(a.Version, b.Version) })
+	return migrations, nil
+}
+
+// MigrateUp applies every pending migration in version order, each in its own
+// transaction. Concurrent callers (e.g. instances starting together) wait on
+// an advisory lock, so each migration runs once.
+func (db *DB) MigrateUp(ctx context.Context) ([]*Migration, error) {
+	var done []*Migration
+	err := db.withMigrationLock(ctx, func(conn *pgxpool.Conn, migrations []*Migration, applied map[int64]appliedMigration) error {
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if err := applyMigration(ctx, conn, m); err != nil {
+				return err
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// MigrateDown reverts the most recently applied migrations, newest first
+func (db *DB) MigrateDown(ctx context.Context, steps int) ([]*Migration, error) {
+	var done []*Migration
+	err := db.withMigrationLock(ctx, func(conn *pgxpool.Conn, migrations []*Migration, applied map[int64]appliedMigration) error {
+		var err error
+		done, err = revertMigrations(ctx, conn, migrations, applied, steps)
+		return err
+	})
+	return done, err
+}
+
+// MigrateRedo reverts the latest applied migration and applies it again
+func (db *DB) MigrateRedo(ctx context.Context) (*Migration, error) {
+	var redone *Migration
+	err := db.withMigrationLock(ctx, func(conn *pgxpool.Conn, migrations []*Migration, applied map[int64]appliedMigration) error {
+		reverted, err := revertMigrations(ctx, conn, migrations, applied, 1)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			return errors.New("no migration has been applied")
+		}
+		redone = reverted[0]
+		return applyMigration(ctx, conn, redone)
+	})
+	return redone, err
+}
+
+// GetMigrationStatus lists the embedded and applied migrations by version
+func (db *DB) GetMigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	// Before the first migration the table does not exist yet
+	var exists bool
+	if err := db.Pool.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, err
+	}
+	applied := map[int64]appliedMigration{}
+	if exists {
+		if applied, err = loadAppliedMigrations(ctx, db.Pool); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if a, ok := applied[m.Version]; ok {
+			status.AppliedAt = &a.AppliedAt
+			status.Drifted = a.Checksum != m.Checksum
+			delete(applied, m.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, a := range applied {
+		statuses = append(statuses, MigrationStatus{Version: a.Version, Name: a.Name, AppliedAt: &a.AppliedAt, Unknown: true})
+	}
+	slices.SortFunc(statuses, func(a, b MigrationStatus) int { return cmp.Compare(a.Version, b.Version) })
+	return statuses, nil
+}
+
+// PendingMigrations counts the embedded migrations not yet applied
+func (db *DB) PendingMigrations(ctx context.Context) (int, error) {
+	statuses, err := db.GetMigrationStatus(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// withMigrationLock holds the migration lock while fn runs, after verifying
+// that no applied migration has drifted from its embedded script
+func (db *DB) withMigrationLock(ctx context.Context, fn func(conn *pgxpool.Conn, migrations []*Migration, applied map[int64]appliedMigration) error) error {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return err
+	}
+
+	lock, err := db.WaitAdvisoryLock(ctx, MigrationLockKey)
+	if err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		lock.Release(releaseCtx)
+	}()
+
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`
+	if _, err := lock.conn.Exec(ctx, query); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	applied, err := loadAppliedMigrations(ctx, lock.conn)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if a, ok := applied[m.Version]; ok && a.Checksum != m.Checksum {
+			return fmt.Errorf("%w: %04d_%s", ErrMigrationDrift, m.Version, m.Name)
+		}
+	}
+	for version, a := range applied {
+		if !slices.ContainsFunc(migrations, func(m *Migration) bool { return m.Version == version }) {
+			slog.Warn("Migration is applied but unknown to this version", "migration", fmt.Sprintf("%04d_%s", version, a.Name))
+		}
+	}
+
+	return fn(lock.conn, migrations, applied)
+}
+
+func loadAppliedMigrations(ctx context.Context, q querier) (map[int64]appliedMigration, error) {
+	rows, err := q.Query(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	list, err := pgx.CollectRows(rows, pgx.RowToStructByPos[appliedMigration])
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]appliedMigration, len(list))
+	for _, a := range list {
+		applied[a.Version] = a
+	}
+	return applied, nil
+}
+
+// revertMigrations runs the down scripts of the latest applied migrations
+func revertMigrations(ctx context.Context, conn *pgxpool.Conn, migrations []*Migration, applied map[int64]appliedMigration, steps int) ([]*Migration, error) {
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	slices.Sort(versions)
+	slices.Reverse(versions)
+
+	var done []*Migration
+	for _, version := range versions[:min(steps, len(versions))] {
+		i := slices.IndexFunc(migrations, func(m *Migration) bool { return m.Version == version })
+		if i < 0 {
+			return done, fmt.Errorf("migration %04d_%s is not embedded in this version", version, applied[version].Name)
+		}
+		m := migrations[i]
+		if m.Down == "" {
+			return done, fmt.Errorf("migration %04d_%s has no down script", m.Version, m.Name)
+		}
+
+		err := runMigrationScript(ctx, conn, m.Down, func(q querier) error {
+			_, err := q.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("revert %04d_%s: %w", m.Version, m.Name, err)
+		}
+		slog.Info("Reverted migration", "migration", fmt.Sprintf("%04d_%s", m.Version, m.Name))
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+func applyMigration(ctx context.Context, conn *pgxpool.Conn, m *Migration) error {
+	started := time.Now()
+	err := runMigrationScript(ctx, conn, m.Up, func(q querier) error {
+		_, err := q.Exec(ctx,
+			`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+			m.Version, m.Name, m.Checksum,
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("apply %04d_%s: %w", m.Version, m.Name, err)
+	}
+	slog.Info("Applied migration", "migration", fmt.Sprintf("%04d_%s", m.Version, m.Name), "duration", time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+// runMigrationScript runs script and then record in one transaction, unless
+// the script opts out with the no-transaction directive
+func runMigrationScript(ctx context.Context, conn *pgxpool.Conn, script string, record func(q querier) error) error {
+	if strings.HasPrefix(script, noTxDirective) {
+		// Without arguments pgx uses the simple protocol, which allows multiple statements
+		if _, err := conn.Exec(ctx, script); err != nil {
+			return err
+		}
+		return record(conn)
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, script); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+// connectTestSchema connects to TEST_DATABASE_URL with a new, empty schema
+// first on the search path, so the test cannot collide with other tables
+func connectTestSchema(t *testing.T) *DB {
+	t.Helper()
+	rawURL := os.Getenv("TEST_DATABASE_URL")
+	if rawURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	admin, err := Connect(rawURL, Options{})
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(admin.Close)
+
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	if _, err := admin.Pool.Exec(context.Background(), "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Pool.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+	})
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("TEST_DATABASE_URL: %v", err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+
+	db, err := Connect(u.String(), Options{})
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(db.Close)
+	return db
+}
+
+func TestMigrateUpFromBaselineSchema(t *testing.T) {
+	db := connectTestSchema(t)
+	ctx := context.Background()
+
+	// A database created from db/schema.sql before migrations existed
+	baseline, err := os.ReadFile("testdata/baseline_schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Pool.Exec(ctx, string(baseline)); err != nil {
+		t.Fatalf("apply baseline schema: %v", err)
+	}
+	if _, err := db.Pool.Exec(ctx, `
+		INSERT INTO users (id, email, name) VALUES ('user_01', 'old@example.com', 'Old');
+		INSERT INTO items (id, user_id, title) VALUES ('item_01', 'user_01', 'Existing');
+	`); err != nil {
+		t.Fatalf("seed baseline rows: %v", err)
+	}
+
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := db.MigrateUp(ctx)
+	if err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Errorf("MigrateUp applied %d migrations, want %d", len(applied), len(migrations))
+	}
+
+	item, err := db.GetItemByID(ctx, "item_01")
+	if err != nil {
+		t.Fatalf("GetItemByID after migrating: %v", err)
+	}
+	if item.Title != "Existing" || item.DueAt != nil || len(item.Tags) != 0 {
+		t.Errorf("migrated item = %+v, want the existing row with empty new columns", item)
+	}
+
+	if applied, err := db.MigrateUp(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("second MigrateUp applied %d migrations (%v), want none", len(applied), err)
+	}
+}
@@ -0,0 +1,23 @@
+-- Drops everything created by 0001_initial.up.sql
+
+DROP FUNCTION IF EXISTS cleanup_expired_sessions();
+
+DROP TABLE IF EXISTS scheduled_tasks;
+DROP TABLE IF EXISTS jobs;
+DROP TABLE IF EXISTS outbox_sink_deliveries;
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS webhook_delivery_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_endpoints;
+DROP TABLE IF EXISTS calendar_feeds;
+DROP TABLE IF EXISTS item_events;
+DROP TABLE IF EXISTS items;
+DROP TABLE IF EXISTS item_series;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS oauth_accounts;
+DROP TABLE IF EXISTS users;
+
+DROP FUNCTION IF EXISTS notify_job();
+DROP FUNCTION IF EXISTS notify_outbox_event();
+DROP FUNCTION IF EXISTS notify_item_event();
+DROP FUNCTION IF EXISTS update_updated_at_column();
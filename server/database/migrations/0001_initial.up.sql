@@ -0,0 +1,527 @@
+-- Database schema for the application with TypeID and Authentication
+-- TypeIDs are K-sortable, type-safe identifiers generated application-side
+-- Format: {prefix}_{26-char-base32-uuidv7} (e.g., user_01h2xcejqtf2nbrexx3vqjhp41)
+--
+-- Baseline migration. Databases created from the former db/schema.sql adopt
+-- the migration history: existing tables are kept, and columns that schema.sql
+-- lacked are added before anything depends on them.
+
+-- ============================================================================
+-- Users Table
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS users (
+  -- TypeID format: user_xxx... (max ~30 chars)
+  id VARCHAR(30) PRIMARY KEY,
+
+  -- Authentication
+  email VARCHAR(255) UNIQUE NOT NULL,
+  password_hash VARCHAR(60), -- bcrypt hash (null for OAuth-only users)
+
+  -- Profile
+  name VARCHAR(255) NOT NULL,
+  avatar_url TEXT,
+
+  -- Authorization
+  role VARCHAR(20) NOT NULL DEFAULT 'user' CHECK (role IN ('admin', 'user', 'moderator')),
+
+  -- Email verification
+  email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+
+  -- Timestamps
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ============================================================================
+-- OAuth Accounts Table - Links external OAuth providers to users
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS oauth_accounts (
+  -- TypeID format: oauth_xxx...
+  id VARCHAR(30) PRIMARY KEY,
+  user_id VARCHAR(30) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+  -- Provider info
+  provider VARCHAR(20) NOT NULL CHECK (provider IN ('google', 'facebook', 'twitter')),
+  provider_account_id VARCHAR(255) NOT NULL,
+
+  -- Tokens (stored encrypted in production)
+  access_token TEXT,
+  refresh_token TEXT,
+  expires_at TIMESTAMP WITH TIME ZONE,
+
+  -- Timestamps
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+  -- Prevent duplicate provider accounts per user
+  UNIQUE(provider, provider_account_id),
+  UNIQUE(user_id, provider)
+);
+
+-- ============================================================================
+-- Sessions Table - Tracks active user sessions for refresh tokens
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS sessions (
+  -- TypeID format: sess_xxx...
+  id VARCHAR(30) PRIMARY KEY,
+  user_id VARCHAR(30) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+  -- Session metadata
+  user_agent TEXT,
+  ip_address VARCHAR(45), -- IPv6 max length
+
+  -- Expiration
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+
+  -- Timestamps
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ============================================================================
+-- Item Series Table - Recurrence template shared by occurrences of an item
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS item_series (
+  -- TypeID format: series_xxx...
+  id VARCHAR(40) PRIMARY KEY,
+  user_id VARCHAR(30) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+  -- Template copied into each generated occurrence
+  title VARCHAR(255) NOT NULL,
+  description TEXT,
+
+  -- RFC 5545 recurrence (RRULE value without the "RRULE:" prefix)
+  rrule TEXT NOT NULL,
+  timezone VARCHAR(64) NOT NULL DEFAULT 'UTC', -- IANA name used to expand the rule
+  dtstart TIMESTAMP WITH TIME ZONE NOT NULL,
+  exdates TIMESTAMP WITH TIME ZONE[] NOT NULL DEFAULT '{}', -- skipped occurrences
+
+  -- Timestamps
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ============================================================================
+-- Items Table - Example resource owned by users
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS items (
+  -- TypeID format: item_xxx...
+  id VARCHAR(30) PRIMARY KEY,
+  user_id VARCHAR(30) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+  -- Content
+  title VARCHAR(255) NOT NULL,
+  description TEXT,
+  status VARCHAR(20) DEFAULT 'active' CHECK (status IN ('active', 'completed', 'archived')),
+
+  -- Caller-supplied identifier used to upsert items on import
+  external_id VARCHAR(255),
+
+  -- Manual ordering: fractional base-62 key, compared byte-wise within a user's list
+  rank VARCHAR(255) COLLATE "C" NOT NULL DEFAULT '',
+
+  -- Scheduling
+  due_at TIMESTAMP WITH TIME ZONE,
+
+  -- Hierarchy (null for top-level items; deleting a parent deletes its subtree)
+  parent_id VARCHAR(30) REFERENCES items(id) ON DELETE CASCADE CHECK (parent_id <> id),
+
+  -- Free-form labels (lowercase, deduplicated application-side)
+  tags TEXT[] NOT NULL DEFAULT '{}',
+
+  -- Recurrence (null for one-off items)
+  series_id VARCHAR(40) REFERENCES item_series(id) ON DELETE SET NULL,
+  recurrence_id TIMESTAMP WITH TIME ZONE, -- originally scheduled time of this occurrence
+
+  -- Timestamps
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ============================================================================
+-- Item Events Table - Bounded log of item changes for real-time streams
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS item_events (
+  -- Monotonic ID doubles as the SSE event ID for Last-Event-ID resume
+  id BIGSERIAL PRIMARY KEY,
+  user_id VARCHAR(30) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+  type VARCHAR(50) NOT NULL, -- item.created, item.updated, item.deleted, items.imported
+  item_id VARCHAR(30),
+  payload JSONB NOT NULL DEFAULT '{}',
+
+  -- Timestamps (rows older than the retention window are pruned)
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ============================================================================
+-- Calendar Feeds Table - Secret-token iCalendar subscriptions (one per user)
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+  user_id VARCHAR(30) PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+
+  -- SHA-256 of the feed token; the token itself is only shown once
+  token_hash CHAR(64) UNIQUE NOT NULL,
+
+  -- Timestamps
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ============================================================================
+-- Webhook Endpoints Table - User-registered HTTP receivers for events
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+  -- TypeID format: whk_xxx...
+  id VARCHAR(40) PRIMARY KEY,
+  user_id VARCHAR(30) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+  url TEXT NOT NULL,
+  description VARCHAR(255) NOT NULL DEFAULT '',
+  event_types TEXT[] NOT NULL DEFAULT '{}', -- '*' subscribes to every event type
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+
+  -- Signing secret; the previous secret stays valid until it expires after a rotation
+  secret VARCHAR(100) NOT NULL,
+  previous_secret VARCHAR(100),
+  previous_secret_expires_at TIMESTAMP WITH TIME ZONE,
+
+  -- Timestamps
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ============================================================================
+-- Webhook Deliveries Table - Durable delivery queue (one row per event per endpoint)
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  -- TypeID format: whd_xxx...
+  id VARCHAR(40) PRIMARY KEY,
+  endpoint_id VARCHAR(40) NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+
+  -- Event ID (evt_xxx...) is shared by every delivery of the same event, including redeliveries
+  event_id VARCHAR(40) NOT NULL,
+  event_type VARCHAR(50) NOT NULL,
+  payload JSONB NOT NULL,
+
+  status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'dead')),
+  attempts INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_attempt_at TIMESTAMP WITH TIME ZONE,
+  response_status INTEGER,
+  last_error TEXT,
+
+  -- Timestamps
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  completed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- ============================================================================
+-- Webhook Delivery Attempts Table - Per-attempt delivery log
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+  id BIGSERIAL PRIMARY KEY,
+  delivery_id VARCHAR(40) NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+
+  attempt INTEGER NOT NULL,
+  response_status INTEGER,
+  response_body TEXT, -- Truncated
+  error TEXT,
+  duration_ms INTEGER NOT NULL,
+
+  -- Timestamps
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ============================================================================
+-- Outbox Events Table - Domain events written in the same transaction as the change
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+  -- Sequence number; the relay publishes in this order
+  id BIGSERIAL PRIMARY KEY,
+
+  -- TypeID format: evt_xxx... (exposed to sinks for deduplication)
+  event_id VARCHAR(40) UNIQUE NOT NULL,
+  type VARCHAR(50) NOT NULL, -- item.created, user.registered, session.revoked, ...
+  aggregate_type VARCHAR(30) NOT NULL,
+  aggregate_id VARCHAR(40) NOT NULL,
+  user_id VARCHAR(30), -- No foreign key: events outlive deleted users
+  payload JSONB NOT NULL DEFAULT '{}',
+
+  -- Relay state
+  status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'published', 'failed')),
+  attempts INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  locked_until TIMESTAMP WITH TIME ZONE, -- Lease held by the relay delivering the event
+  last_error TEXT,
+
+  -- Timestamps
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  published_at TIMESTAMP WITH TIME ZONE
+);
+
+-- ============================================================================
+-- Outbox Sink Deliveries Table - Sinks that have received each event
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS outbox_sink_deliveries (
+  event_id BIGINT NOT NULL REFERENCES outbox_events(id) ON DELETE CASCADE,
+  sink VARCHAR(50) NOT NULL,
+  delivered_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (event_id, sink)
+);
+
+-- ============================================================================
+-- Jobs Table - Background job queue
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS jobs (
+  -- TypeID format: job_xxx...
+  id VARCHAR(40) PRIMARY KEY,
+  kind VARCHAR(100) NOT NULL, -- Selects the handler, e.g. sessions.cleanup
+  args JSONB NOT NULL DEFAULT '{}',
+
+  -- Higher priority runs first; run_at delays or schedules the job
+  priority SMALLINT NOT NULL DEFAULT 0,
+  run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  -- At most one pending or running job per unique key
+  unique_key VARCHAR(255),
+
+  status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed', 'cancelled')),
+  attempts INTEGER NOT NULL DEFAULT 0,
+  max_attempts INTEGER NOT NULL DEFAULT 10,
+  locked_until TIMESTAMP WITH TIME ZONE, -- Lease of the worker running the job
+  last_error TEXT,
+
+  -- Timestamps
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  finished_at TIMESTAMP WITH TIME ZONE
+);
+
+-- ============================================================================
+-- Scheduled Tasks Table - Cron task state shared by all replicas
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS scheduled_tasks (
+  name VARCHAR(100) PRIMARY KEY, -- e.g. sessions.cleanup
+  schedule VARCHAR(100) NOT NULL, -- Cron expression
+
+  -- The leader claims a tick by moving next_run_at forward, so each tick runs once
+  next_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+
+  -- Last run
+  last_run_at TIMESTAMP WITH TIME ZONE,
+  last_finished_at TIMESTAMP WITH TIME ZONE,
+  last_status VARCHAR(20) CHECK (last_status IN ('running', 'succeeded', 'failed')),
+  last_error TEXT,
+  last_duration_ms INTEGER,
+  last_run_by VARCHAR(255), -- Hostname of the instance that ran it
+  run_count INTEGER NOT NULL DEFAULT 0,
+  failure_count INTEGER NOT NULL DEFAULT 0,
+
+  -- Timestamps
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ============================================================================
+-- Columns missing from tables created by the former db/schema.sql
+-- ============================================================================
+
+ALTER TABLE items
+  ADD COLUMN IF NOT EXISTS external_id VARCHAR(255),
+  ADD COLUMN IF NOT EXISTS rank VARCHAR(255) COLLATE "C" NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS due_at TIMESTAMP WITH TIME ZONE,
+  ADD COLUMN IF NOT EXISTS parent_id VARCHAR(30) REFERENCES items(id) ON DELETE CASCADE CHECK (parent_id <> id),
+  ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}',
+  ADD COLUMN IF NOT EXISTS series_id VARCHAR(40) REFERENCES item_series(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS recurrence_id TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE outbox_events
+  ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE;
+
+-- ============================================================================
+-- Indexes for Performance
+-- ============================================================================
+
+-- Users
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
+
+-- OAuth Accounts
+CREATE INDEX IF NOT EXISTS idx_oauth_accounts_user_id ON oauth_accounts(user_id);
+CREATE INDEX IF NOT EXISTS idx_oauth_accounts_provider ON oauth_accounts(provider, provider_account_id);
+
+-- Sessions
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
+
+-- Items
+CREATE INDEX IF NOT EXISTS idx_items_user_id ON items(user_id);
+CREATE INDEX IF NOT EXISTS idx_items_status ON items(status);
+CREATE INDEX IF NOT EXISTS idx_items_created_at ON items(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_items_due_at ON items(due_at) WHERE due_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_items_series_id ON items(series_id) WHERE series_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_items_tags ON items USING GIN(tags);
+CREATE INDEX IF NOT EXISTS idx_items_user_rank ON items(user_id, rank);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_items_user_external_id ON items(user_id, external_id) WHERE external_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_items_parent_id ON items(parent_id) WHERE parent_id IS NOT NULL;
+
+-- Item Events
+CREATE INDEX IF NOT EXISTS idx_item_events_user_id ON item_events(user_id, id);
+CREATE INDEX IF NOT EXISTS idx_item_events_created_at ON item_events(created_at);
+
+-- Item Series
+CREATE INDEX IF NOT EXISTS idx_item_series_user_id ON item_series(user_id);
+
+-- Outbox
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(next_attempt_at, id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_outbox_events_published_at ON outbox_events(published_at) WHERE published_at IS NOT NULL;
+
+-- Jobs
+CREATE INDEX IF NOT EXISTS idx_jobs_pending ON jobs(priority DESC, run_at, id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_jobs_running ON jobs(locked_until) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_jobs_kind_status ON jobs(kind, status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_jobs_finished_at ON jobs(finished_at) WHERE finished_at IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_unique_key ON jobs(unique_key) WHERE unique_key IS NOT NULL AND status IN ('pending', 'running');
+
+-- Webhooks
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_user_id ON webhook_endpoints(user_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint_id ON webhook_deliveries(endpoint_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery_id ON webhook_delivery_attempts(delivery_id);
+
+-- ============================================================================
+-- Trigger: Auto-update updated_at timestamp
+-- ============================================================================
+
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at = CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+-- Apply trigger to tables with updated_at
+DROP TRIGGER IF EXISTS update_users_updated_at ON users;
+CREATE TRIGGER update_users_updated_at
+  BEFORE UPDATE ON users
+  FOR EACH ROW
+  EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_oauth_accounts_updated_at ON oauth_accounts;
+CREATE TRIGGER update_oauth_accounts_updated_at
+  BEFORE UPDATE ON oauth_accounts
+  FOR EACH ROW
+  EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_item_series_updated_at ON item_series;
+CREATE TRIGGER update_item_series_updated_at
+  BEFORE UPDATE ON item_series
+  FOR EACH ROW
+  EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_items_updated_at ON items;
+CREATE TRIGGER update_items_updated_at
+  BEFORE UPDATE ON items
+  FOR EACH ROW
+  EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_webhook_endpoints_updated_at ON webhook_endpoints;
+CREATE TRIGGER update_webhook_endpoints_updated_at
+  BEFORE UPDATE ON webhook_endpoints
+  FOR EACH ROW
+  EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_scheduled_tasks_updated_at ON scheduled_tasks;
+CREATE TRIGGER update_scheduled_tasks_updated_at
+  BEFORE UPDATE ON scheduled_tasks
+  FOR EACH ROW
+  EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_jobs_updated_at ON jobs;
+CREATE TRIGGER update_jobs_updated_at
+  BEFORE UPDATE ON jobs
+  FOR EACH ROW
+  EXECUTE FUNCTION update_updated_at_column();
+
+-- ============================================================================
+-- Trigger: Notify listeners of new item events (fan-out across replicas)
+-- ============================================================================
+
+CREATE OR REPLACE FUNCTION notify_item_event()
+RETURNS TRIGGER AS $$
+BEGIN
+  PERFORM pg_notify('item_events', json_build_object('id', NEW.id, 'userId', NEW.user_id)::text);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS notify_item_events_insert ON item_events;
+CREATE TRIGGER notify_item_events_insert
+  AFTER INSERT ON item_events
+  FOR EACH ROW
+  EXECUTE FUNCTION notify_item_event();
+
+-- ============================================================================
+-- Trigger: Wake outbox relays when events are committed
+-- ============================================================================
+
+CREATE OR REPLACE FUNCTION notify_outbox_event()
+RETURNS TRIGGER AS $$
+BEGIN
+  PERFORM pg_notify('outbox_events', '');
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS notify_outbox_events_insert ON outbox_events;
+CREATE TRIGGER notify_outbox_events_insert
+  AFTER INSERT ON outbox_events
+  FOR EACH STATEMENT
+  EXECUTE FUNCTION notify_outbox_event();
+
+-- ============================================================================
+-- Trigger: Wake job workers when jobs are inserted
+-- ============================================================================
+
+CREATE OR REPLACE FUNCTION notify_job()
+RETURNS TRIGGER AS $$
+BEGIN
+  PERFORM pg_notify('jobs', '');
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS notify_jobs_insert ON jobs;
+CREATE TRIGGER notify_jobs_insert
+  AFTER INSERT ON jobs
+  FOR EACH STATEMENT
+  EXECUTE FUNCTION notify_job();
+
+-- ============================================================================
+-- Helper: Clean up expired sessions (run by the sessions.cleanup job)
+-- ============================================================================
+
+CREATE OR REPLACE FUNCTION cleanup_expired_sessions()
+RETURNS INTEGER AS $$
+DECLARE
+  deleted_count INTEGER;
+BEGIN
+  DELETE FROM sessions WHERE expires_at < CURRENT_TIMESTAMP;
+  GET DIAGNOSTICS deleted_count = ROW_COUNT;
+  RETURN deleted_count;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,34 @@
+-- Fails if any stored value no longer fits the narrower columns
+
+ALTER TABLE outbox_events
+  ALTER COLUMN user_id TYPE VARCHAR(30);
+
+ALTER TABLE webhook_endpoints
+  ALTER COLUMN user_id TYPE VARCHAR(30);
+
+ALTER TABLE calendar_feeds
+  ALTER COLUMN user_id TYPE VARCHAR(30);
+
+ALTER TABLE item_events
+  ALTER COLUMN user_id TYPE VARCHAR(30),
+  ALTER COLUMN item_id TYPE VARCHAR(30);
+
+ALTER TABLE items
+  ALTER COLUMN id TYPE VARCHAR(30),
+  ALTER COLUMN user_id TYPE VARCHAR(30),
+  ALTER COLUMN parent_id TYPE VARCHAR(30);
+
+ALTER TABLE item_series
+  ALTER COLUMN user_id TYPE VARCHAR(30);
+
+ALTER TABLE sessions
+  ALTER COLUMN id TYPE VARCHAR(30),
+  ALTER COLUMN user_id TYPE VARCHAR(30);
+
+ALTER TABLE oauth_accounts
+  ALTER COLUMN id TYPE VARCHAR(30),
+  ALTER COLUMN user_id TYPE VARCHAR(30);
+
+ALTER TABLE users
+  ALTER COLUMN id TYPE VARCHAR(30),
+  ALTER COLUMN password_hash TYPE VARCHAR(60);
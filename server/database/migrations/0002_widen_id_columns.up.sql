@@ -0,0 +1,36 @@
+-- TypeIDs with prefixes longer than four characters (sess_, oauth_) exceed
+-- 30 characters; match the VARCHAR(40) used by newer tables. password_hash
+-- is widened so hashes other than bcrypt fit.
+
+ALTER TABLE users
+  ALTER COLUMN id TYPE VARCHAR(40),
+  ALTER COLUMN password_hash TYPE VARCHAR(255);
+
+ALTER TABLE oauth_accounts
+  ALTER COLUMN id TYPE VARCHAR(40),
+  ALTER COLUMN user_id TYPE VARCHAR(40);
+
+ALTER TABLE sessions
+  ALTER COLUMN id TYPE VARCHAR(40),
+  ALTER COLUMN user_id TYPE VARCHAR(40);
+
+ALTER TABLE item_series
+  ALTER COLUMN user_id TYPE VARCHAR(40);
+
+ALTER TABLE items
+  ALTER COLUMN id TYPE VARCHAR(40),
+  ALTER COLUMN user_id TYPE VARCHAR(40),
+  ALTER COLUMN parent_id TYPE VARCHAR(40);
+
+ALTER TABLE item_events
+  ALTER COLUMN user_id TYPE VARCHAR(40),
+  ALTER COLUMN item_id TYPE VARCHAR(40);
+
+ALTER TABLE calendar_feeds
+  ALTER COLUMN user_id TYPE VARCHAR(40);
+
+ALTER TABLE webhook_endpoints
+  ALTER COLUMN user_id TYPE VARCHAR(40);
+
+ALTER TABLE outbox_events
+  ALTER COLUMN user_id TYPE VARCHAR(40);
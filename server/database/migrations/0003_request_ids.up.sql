@@ -0,0 +1,8 @@
+-- ID of the API request that caused an event, so webhook deliveries can be
+-- traced back to it (NULL for events raised outside a request)
+
+ALTER TABLE outbox_events
+  ADD COLUMN IF NOT EXISTS request_id VARCHAR(40);
+
+ALTER TABLE webhook_deliveries
+  ADD COLUMN IF NOT EXISTS request_id VARCHAR(40);
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+// OutboxChannel is notified (without payload) when outbox events are committed
+const OutboxChannel = "outbox_events"
+
+const domainEventColumns = `id, event_id, type, aggregate_type, aggregate_id, user_id, request_id, payload, attempts, created_at`
+
+func scanDomainEvent(row pgx.Row, e *models.DomainEvent) error {
+	return row.Scan(&e.Seq, &e.ID, &e.Type, &e.AggregateType, &e.AggregateID, &e.UserID, &e.RequestID, &e.Payload, &e.Attempts, &e.CreatedAt)
+}
+
+// RecordDomainEvent writes an event to the outbox with data as its payload.
+// Call it with the context of the transaction making the change so the event
+// exists if and only if the change commits. The event records the context's
+// request ID unless it already has one.
+func (db *DB) RecordDomainEvent(ctx context.Context, event *models.DomainEvent, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if event.ID == "" {
+		event.ID = utils.NewEventID()
+	}
+	if event.RequestID == nil {
+		event.RequestID = requestID(ctx)
+	}
+	event.Payload = payload
+
+	query := `
+		INSERT INTO outbox_events (event_id, type, aggregate_type, aggregate_id, user_id, request_id, payload)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	return db.conn(ctx).QueryRow(ctx, query,
+		event.ID, event.Type, event.AggregateType, event.AggregateID, event.UserID, event.RequestID, payload,
+	).Scan(&event.Seq, &event.CreatedAt)
+}
+
+// ClaimDomainEvents leases up to limit due events, returned in sequence
+// order. Claimed events are skipped by other relays until the lease expires,
+// so an event whose relay died is claimed again.
+func (db *DB) ClaimDomainEvents(ctx context.Context, limit int, lease time.Duration) ([]*models.DomainEvent, error) {
+	query := `
+		WITH due AS (
+			SELECT id FROM outbox_events
+			WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+				AND (locked_until IS NULL OR locked_until < CURRENT_TIMESTAMP)
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE outbox_events e
+			SET locked_until = CURRENT_TIMESTAMP + make_interval(secs => $2)
+			FROM due WHERE e.id = due.id
+			RETURNING ` + prefixColumns("e", domainEventColumns) + `
+		)
+		SELECT ` + domainEventColumns + ` FROM claimed ORDER BY id
+	`
+	rows, err := db.conn(ctx).Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*models.DomainEvent
+	for rows.Next() {
+		var e models.DomainEvent
+		if err := scanDomainEvent(rows, &e); err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+// GetDeliveredSinks returns the sinks that already received an event
+func (db *DB) GetDeliveredSinks(ctx context.Context, seq int64) (map[string]bool, error) {
+	rows, err := db.conn(ctx).Query(ctx, `SELECT sink FROM outbox_sink_deliveries WHERE event_id = $1`, seq)
+	if err != nil {
+		return nil, err
+	}
+	sinks, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+
+	delivered := make(map[string]bool, len(sinks))
+	for _, sink := range sinks {
+		delivered[sink] = true
+	}
+	return delivered, nil
+}
+
+func (db *DB) MarkSinkDelivered(ctx context.Context, seq int64, sink string) error {
+	query := `INSERT INTO outbox_sink_deliveries (event_id, sink) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	_, err := db.conn(ctx).Exec(ctx, query, seq, sink)
+	return err
+}
+
+func (db *DB) MarkDomainEventPublished(ctx context.Context, seq int64) error {
+	query := `
+		UPDATE outbox_events
+		SET status = 'published', published_at = CURRENT_TIMESTAMP, last_error = NULL, locked_until = NULL
+		WHERE id = $1 AND status = 'pending'
+	`
+	_, err := db.conn(ctx).Exec(ctx, query, seq)
+	return err
+}
+
+// MarkDomainEventRetry records a failed relay attempt. A failed event stays
+// in the outbox with status 'failed' for inspection instead of being retried.
+func (db *DB) MarkDomainEventRetry(ctx context.Context, seq int64, lastError string, nextAttemptAt time.Time, failed bool) error {
+	query := `
+		UPDATE outbox_events
+		SET attempts = attempts + 1,
+			last_error = $2,
+			next_attempt_at = $3,
+			locked_until = NULL,
+			status = CASE WHEN $4 THEN 'failed' ELSE status END
+		WHERE id = $1 AND status = 'pending'
+	`
+	_, err := db.conn(ctx).Exec(ctx, query, seq, lastError, nextAttemptAt, failed)
+	return err
+}
+
+// PruneDomainEvents deletes published events older than retention
+func (db *DB) PruneDomainEvents(ctx context.Context, retention time.Duration) (int64, error) {
+	query := `DELETE FROM outbox_events WHERE status = 'published' AND published_at < $1`
+	result, err := db.conn(ctx).Exec(ctx, query, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -2,24 +2,43 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrEmailTaken is returned when creating a user whose email is registered
+	ErrEmailTaken = errors.New("email already registered")
+
+	// ErrOAuthAccountLinked is returned when linking a provider account that is
+	// already linked, or a second account of the same provider to a user
+	ErrOAuthAccountLinked = errors.New("oauth account already linked")
 )
 
 // ============================================================================
 // User Queries
 // ============================================================================
 
+// CreateUser inserts a user, returning ErrEmailTaken if the email is registered
 func (db *DB) CreateUser(ctx context.Context, user *models.User) error {
 	query := `
 		INSERT INTO users (id, email, password_hash, name, avatar_url, role, email_verified)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING created_at, updated_at
 	`
-	return db.Pool.QueryRow(ctx, query,
+	err := db.conn(ctx).QueryRow(ctx, query,
 		user.ID, user.Email, user.PasswordHash, user.Name, user.AvatarURL, user.Role, user.EmailVerified,
 	).Scan(&user.CreatedAt, &user.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrEmailTaken
+	}
+	return err
 }
 
 func (db *DB) GetUserByID(ctx context.Context, id string) (*models.User, error) {
@@ -28,7 +47,7 @@ func (db *DB) GetUserByID(ctx context.Context, id string) (*models.User, error)
 		SELECT id, email, password_hash, name, avatar_url, role, email_verified, created_at, updated_at
 		FROM users WHERE id = $1
 	`
-	err := db.Pool.QueryRow(ctx, query, id).Scan(
+	err := db.read(ctx).QueryRow(ctx, query, id).Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.AvatarURL,
 		&user.Role, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt,
 	)
@@ -44,7 +63,7 @@ func (db *DB) GetUserByEmail(ctx context.Context, email string) (*models.User, e
 		SELECT id, email, password_hash, name, avatar_url, role, email_verified, created_at, updated_at
 		FROM users WHERE email = $1
 	`
-	err := db.Pool.QueryRow(ctx, query, email).Scan(
+	err := db.conn(ctx).QueryRow(ctx, query, email).Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.AvatarURL,
 		&user.Role, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt,
 	)
@@ -54,6 +73,30 @@ func (db *DB) GetUserByEmail(ctx context.Context, email string) (*models.User, e
 	return &user, nil
 }
 
+func (db *DB) UpdateUserRole(ctx context.Context, id string, role models.UserRole) error {
+	query := `UPDATE users SET role = $2 WHERE id = $1`
+	result, err := db.conn(ctx).Exec(ctx, query, id, role)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+func (db *DB) UpdateUserPassword(ctx context.Context, id, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $2 WHERE id = $1`
+	result, err := db.conn(ctx).Exec(ctx, query, id, passwordHash)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
 // ============================================================================
 // Session Queries
 // ============================================================================
@@ -64,7 +107,7 @@ func (db *DB) CreateSession(ctx context.Context, session *models.Session) error
 		VALUES ($1, $2, $3, $4, $5)
 		RETURNING created_at
 	`
-	return db.Pool.QueryRow(ctx, query,
+	return db.conn(ctx).QueryRow(ctx, query,
 		session.ID, session.UserID, session.UserAgent, session.IPAddress, session.ExpiresAt,
 	).Scan(&session.CreatedAt)
 }
@@ -75,7 +118,7 @@ func (db *DB) GetSessionByID(ctx context.Context, id string) (*models.Session, e
 		SELECT id, user_id, user_agent, ip_address, expires_at, created_at
 		FROM sessions WHERE id = $1
 	`
-	err := db.Pool.QueryRow(ctx, query, id).Scan(
+	err := db.conn(ctx).QueryRow(ctx, query, id).Scan(
 		&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress,
 		&session.ExpiresAt, &session.CreatedAt,
 	)
@@ -91,7 +134,7 @@ func (db *DB) GetUserSessions(ctx context.Context, userID string) ([]*models.Ses
 		FROM sessions WHERE user_id = $1 AND expires_at > NOW()
 		ORDER BY created_at DESC
 	`
-	rows, err := db.Pool.Query(ctx, query, userID)
+	rows, err := db.read(ctx).Query(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -113,7 +156,7 @@ func (db *DB) GetUserSessions(ctx context.Context, userID string) ([]*models.Ses
 
 func (db *DB) DeleteSession(ctx context.Context, id string) error {
 	query := `DELETE FROM sessions WHERE id = $1`
-	result, err := db.Pool.Exec(ctx, query, id)
+	result, err := db.conn(ctx).Exec(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -123,32 +166,93 @@ func (db *DB) DeleteSession(ctx context.Context, id string) error {
 	return nil
 }
 
+// DeleteUserSessions deletes every session of a user and returns how many
+func (db *DB) DeleteUserSessions(ctx context.Context, userID string) (int64, error) {
+	result, err := db.conn(ctx).Exec(ctx, `DELETE FROM sessions WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// DeleteAllSessions deletes every session and returns how many
+func (db *DB) DeleteAllSessions(ctx context.Context) (int64, error) {
+	result, err := db.conn(ctx).Exec(ctx, `DELETE FROM sessions`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// CleanupExpiredSessions deletes expired sessions and returns how many
+func (db *DB) CleanupExpiredSessions(ctx context.Context) (int, error) {
+	var deleted int
+	err := db.conn(ctx).QueryRow(ctx, `SELECT cleanup_expired_sessions()`).Scan(&deleted)
+	return deleted, err
+}
+
 // ============================================================================
 // Item Queries
 // ============================================================================
 
+const itemColumns = `id, user_id, title, description, status, external_id, parent_id, rank, due_at, tags, series_id, recurrence_id, created_at, updated_at`
+
+// scanItem scans itemColumns into item, followed by any extra selected columns
+func scanItem(row pgx.Row, item *models.Item, extra ...any) error {
+	dest := []any{
+		&item.ID, &item.UserID, &item.Title, &item.Description, &item.Status, &item.ExternalID, &item.ParentID,
+		&item.Rank, &item.DueAt, &item.Tags, &item.SeriesID, &item.RecurrenceID, &item.CreatedAt, &item.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// CreateItem inserts an item. Items without a rank are placed at the top of the user's list.
 func (db *DB) CreateItem(ctx context.Context, item *models.Item) error {
+	if item.Tags == nil {
+		item.Tags = []string{}
+	}
+	if item.Rank != "" {
+		return db.insertItem(ctx, item)
+	}
+
+	// New items go on top. Like MoveItem, this holds the user's rank lock so
+	// concurrent creates never compute the same key, and rebalances when the
+	// top key is malformed or has grown too long.
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		q := db.conn(ctx)
+		if err := lockUserItems(ctx, q, item.UserID); err != nil {
+			return err
+		}
+		rank, err := topItemRank(ctx, q, item.UserID)
+		if err != nil || len(rank) > utils.MaxRankLength {
+			if err := rebalanceItemRanks(ctx, q, item.UserID); err != nil {
+				return err
+			}
+			if rank, err = topItemRank(ctx, q, item.UserID); err != nil {
+				return err
+			}
+		}
+		item.Rank = rank
+		return db.insertItem(ctx, item)
+	})
+}
+
+func (db *DB) insertItem(ctx context.Context, item *models.Item) error {
 	query := `
-		INSERT INTO items (id, user_id, title, description, status)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO items (id, user_id, title, description, status, external_id, parent_id, rank, due_at, tags, series_id, recurrence_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING created_at, updated_at
 	`
-	return db.Pool.QueryRow(ctx, query,
-		item.ID, item.UserID, item.Title, item.Description, item.Status,
+	return db.conn(ctx).QueryRow(ctx, query,
+		item.ID, item.UserID, item.Title, item.Description, item.Status, item.ExternalID, item.ParentID,
+		item.Rank, item.DueAt, item.Tags, item.SeriesID, item.RecurrenceID,
 	).Scan(&item.CreatedAt, &item.UpdatedAt)
 }
 
 func (db *DB) GetItemByID(ctx context.Context, id string) (*models.Item, error) {
 	var item models.Item
-	query := `
-		SELECT id, user_id, title, description, status, created_at, updated_at
-		FROM items WHERE id = $1
-	`
-	err := db.Pool.QueryRow(ctx, query, id).Scan(
-		&item.ID, &item.UserID, &item.Title, &item.Description,
-		&item.Status, &item.CreatedAt, &item.UpdatedAt,
-	)
-	if err != nil {
+	query := `SELECT ` + itemColumns + ` FROM items WHERE id = $1`
+	if err := scanItem(db.read(ctx).QueryRow(ctx, query, id), &item); err != nil {
 		return nil, err
 	}
 	return &item, nil
@@ -156,11 +260,11 @@ func (db *DB) GetItemByID(ctx context.Context, id string) (*models.Item, error)
 
 func (db *DB) GetUserItems(ctx context.Context, userID string) ([]*models.Item, error) {
 	query := `
-		SELECT id, user_id, title, description, status, created_at, updated_at
+		SELECT ` + itemColumns + `
 		FROM items WHERE user_id = $1
-		ORDER BY created_at DESC
+		ORDER BY rank ASC, created_at DESC
 	`
-	rows, err := db.Pool.Query(ctx, query, userID)
+	rows, err := db.read(ctx).Query(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -169,10 +273,44 @@ func (db *DB) GetUserItems(ctx context.Context, userID string) ([]*models.Item,
 	var items []*models.Item
 	for rows.Next() {
 		var item models.Item
-		if err := rows.Scan(
-			&item.ID, &item.UserID, &item.Title, &item.Description,
-			&item.Status, &item.CreatedAt, &item.UpdatedAt,
-		); err != nil {
+		if err := scanItem(rows, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+	return items, rows.Err()
+}
+
+// GetFilteredUserItems returns a user's items matching filter, soonest due first
+func (db *DB) GetFilteredUserItems(ctx context.Context, userID string, filter models.ItemFilter) ([]*models.Item, error) {
+	query := `
+		SELECT ` + itemColumns + `
+		FROM items
+		WHERE user_id = $1
+			AND (cardinality($2::text[]) = 0 OR status = ANY($2))
+			AND (cardinality($3::text[]) = 0 OR tags && $3)
+			AND (NOT $4 OR due_at IS NOT NULL)
+		ORDER BY due_at ASC NULLS LAST, rank ASC
+	`
+	statuses := make([]string, len(filter.Statuses))
+	for i, status := range filter.Statuses {
+		statuses[i] = string(status)
+	}
+	tags := filter.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	rows, err := db.read(ctx).Query(ctx, query, userID, statuses, tags, filter.HasDueDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*models.Item
+	for rows.Next() {
+		var item models.Item
+		if err := scanItem(rows, &item); err != nil {
 			return nil, err
 		}
 		items = append(items, &item)
@@ -183,18 +321,426 @@ func (db *DB) GetUserItems(ctx context.Context, userID string) ([]*models.Item,
 func (db *DB) UpdateItem(ctx context.Context, item *models.Item) error {
 	query := `
 		UPDATE items
-		SET title = $2, description = $3, status = $4, updated_at = CURRENT_TIMESTAMP
+		SET title = $2, description = $3, status = $4, due_at = $5, tags = $6, series_id = $7, recurrence_id = $8,
+			updated_at = CURRENT_TIMESTAMP
 		WHERE id = $1
 		RETURNING updated_at
 	`
-	return db.Pool.QueryRow(ctx, query,
-		item.ID, item.Title, item.Description, item.Status,
+	if item.Tags == nil {
+		item.Tags = []string{}
+	}
+	return db.conn(ctx).QueryRow(ctx, query,
+		item.ID, item.Title, item.Description, item.Status, item.DueAt, item.Tags, item.SeriesID, item.RecurrenceID,
 	).Scan(&item.UpdatedAt)
 }
 
+// ============================================================================
+// Item Import/Export Queries
+// ============================================================================
+
+// StreamUserItems calls fn for each of the user's items in list order without
+// loading them all into memory. Iteration stops at the first error from fn.
+func (db *DB) StreamUserItems(ctx context.Context, userID string, fn func(*models.Item) error) error {
+	query := `
+		SELECT ` + itemColumns + `
+		FROM items WHERE user_id = $1
+		ORDER BY rank ASC, created_at DESC
+	`
+	// Exports are paced by the client, so the read timeout does not apply
+	rows, err := timed(db.route(ctx), QueryRead, 0).Query(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var item models.Item
+	for rows.Next() {
+		item = models.Item{}
+		if err := scanItem(rows, &item); err != nil {
+			return err
+		}
+		if err := fn(&item); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ImportItems inserts items in a single transaction, upserting rows that carry
+// an external ID. New items are appended to the end of the user's list in
+// input order. It returns the number of created and updated items.
+func (db *DB) ImportItems(ctx context.Context, userID string, items []*models.Item) (created, updated int, err error) {
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := lockUserItems(ctx, tx, userID); err != nil {
+		return 0, 0, err
+	}
+
+	// All imported keys share a prefix sorting after the current last item
+	var last string
+	err = tx.QueryRow(ctx, `SELECT rank FROM items WHERE user_id = $1 ORDER BY rank DESC LIMIT 1`, userID).Scan(&last)
+	if err != nil && err != pgx.ErrNoRows {
+		return 0, 0, err
+	}
+	prefix, err := utils.RankBetween(last, "")
+	if err != nil {
+		prefix = "z"
+	}
+	ranks := utils.EvenRanks(len(items))
+
+	query := `
+		INSERT INTO items (id, user_id, title, description, status, external_id, rank, due_at, tags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (user_id, external_id) WHERE external_id IS NOT NULL DO UPDATE
+		SET title = EXCLUDED.title, description = EXCLUDED.description, status = EXCLUDED.status,
+			due_at = EXCLUDED.due_at, tags = EXCLUDED.tags, updated_at = CURRENT_TIMESTAMP
+		RETURNING (xmax = 0) AS inserted
+	`
+	for i, item := range items {
+		if item.Tags == nil {
+			item.Tags = []string{}
+		}
+		var inserted bool
+		err := tx.QueryRow(ctx, query,
+			item.ID, userID, item.Title, item.Description, item.Status, item.ExternalID,
+			prefix+ranks[i], item.DueAt, item.Tags,
+		).Scan(&inserted)
+		if err != nil {
+			return 0, 0, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		if inserted {
+			created++
+		} else {
+			updated++
+		}
+	}
+
+	return created, updated, tx.Commit(ctx)
+}
+
+// ============================================================================
+// Item Rank Queries
+// ============================================================================
+
+// LockUserItems serializes changes to the user's item order and hierarchy
+// until the context's transaction ends
+func (db *DB) LockUserItems(ctx context.Context, userID string) error {
+	return lockUserItems(ctx, db.conn(ctx), userID)
+}
+
+func lockUserItems(ctx context.Context, q querier, userID string) error {
+	_, err := q.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('item_rank:' || $1))`, userID)
+	return err
+}
+
+// topItemRank returns a rank placing a new item above all of the user's items
+func topItemRank(ctx context.Context, q querier, userID string) (string, error) {
+	var first string
+	err := q.QueryRow(ctx, `SELECT rank FROM items WHERE user_id = $1 AND rank <> '' ORDER BY rank ASC LIMIT 1`, userID).Scan(&first)
+	if err != nil && err != pgx.ErrNoRows {
+		return "", err
+	}
+	return utils.RankBetween("", first)
+}
+
+// MoveItem places itemID directly before or after anchorID in the user's list
+// and returns the item's new rank. Moves are serialized per user with an
+// advisory lock so concurrent drags never compute the same key; ties and keys
+// that grow past utils.MaxRankLength trigger a rebalance of the user's list.
+func (db *DB) MoveItem(ctx context.Context, userID, itemID, anchorID string, before bool) (string, error) {
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := lockUserItems(ctx, tx, userID); err != nil {
+		return "", err
+	}
+
+	rank, err := rankNextTo(ctx, tx, userID, itemID, anchorID, before)
+	if err == pgx.ErrNoRows {
+		return "", err
+	}
+	if err != nil || len(rank) > utils.MaxRankLength {
+		if err := rebalanceItemRanks(ctx, tx, userID); err != nil {
+			return "", err
+		}
+		if rank, err = rankNextTo(ctx, tx, userID, itemID, anchorID, before); err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE items SET rank = $2 WHERE id = $1`, itemID, rank); err != nil {
+		return "", err
+	}
+	return rank, tx.Commit(ctx)
+}
+
+// rankNextTo computes a key between anchorID and its neighbour (ignoring the moved item).
+// It fails when the anchor shares its rank with another item.
+func rankNextTo(ctx context.Context, tx querier, userID, itemID, anchorID string, before bool) (string, error) {
+	var anchorRank string
+	var ties int
+	err := tx.QueryRow(ctx, `
+		SELECT a.rank, (SELECT COUNT(*) FROM items WHERE user_id = $1 AND id NOT IN ($2, $3) AND rank = a.rank)
+		FROM items a WHERE a.id = $3 AND a.user_id = $1
+	`, userID, itemID, anchorID).Scan(&anchorRank, &ties)
+	if err != nil {
+		return "", err
+	}
+	if ties > 0 || anchorRank == "" {
+		return "", fmt.Errorf("rank of %s is not unique", anchorID)
+	}
+
+	var neighbour string
+	if before {
+		err = tx.QueryRow(ctx, `
+			SELECT rank FROM items WHERE user_id = $1 AND id <> $2 AND rank < $3
+			ORDER BY rank DESC LIMIT 1
+		`, userID, itemID, anchorRank).Scan(&neighbour)
+	} else {
+		err = tx.QueryRow(ctx, `
+			SELECT rank FROM items WHERE user_id = $1 AND id <> $2 AND rank > $3
+			ORDER BY rank ASC LIMIT 1
+		`, userID, itemID, anchorRank).Scan(&neighbour)
+	}
+	if err != nil && err != pgx.ErrNoRows {
+		return "", err
+	}
+
+	if before {
+		return utils.RankBetween(neighbour, anchorRank)
+	}
+	return utils.RankBetween(anchorRank, neighbour)
+}
+
+// rebalanceItemRanks rewrites a user's ranks as short, evenly spaced keys
+// preserving the current order
+func rebalanceItemRanks(ctx context.Context, tx querier, userID string) error {
+	rows, err := tx.Query(ctx, `SELECT id FROM items WHERE user_id = $1 ORDER BY rank ASC, created_at DESC FOR UPDATE`, userID)
+	if err != nil {
+		return err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE items SET rank = v.rank
+		FROM unnest($1::text[], $2::text[]) AS v(id, rank)
+		WHERE items.id = v.id
+	`, ids, utils.EvenRanks(len(ids)))
+	return err
+}
+
+// RebalanceLongItemRanks rebalances every user whose longest rank exceeds
+// utils.MaxRankLength and returns the number of users rebalanced
+func (db *DB) RebalanceLongItemRanks(ctx context.Context) (int, error) {
+	rows, err := db.conn(ctx).Query(ctx, `
+		SELECT user_id FROM items GROUP BY user_id HAVING MAX(length(rank)) > $1
+	`, utils.MaxRankLength)
+	if err != nil {
+		return 0, err
+	}
+	userIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, err
+	}
+
+	for _, userID := range userIDs {
+		tx, err := db.begin(ctx)
+		if err != nil {
+			return 0, err
+		}
+		err = lockUserItems(ctx, tx, userID)
+		if err == nil {
+			err = rebalanceItemRanks(ctx, tx, userID)
+		}
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return 0, err
+		}
+	}
+	return len(userIDs), nil
+}
+
+// ============================================================================
+// Item Hierarchy Queries
+// ============================================================================
+
+// maxTreeRecursion bounds recursive CTEs as a safety net against corrupted (cyclic) data
+const maxTreeRecursion = 64
+
+func (db *DB) GetItemChildren(ctx context.Context, parentID string) ([]*models.Item, error) {
+	query := `
+		SELECT ` + itemColumns + `
+		FROM items WHERE parent_id = $1
+		ORDER BY rank ASC, created_at ASC
+	`
+	rows, err := db.read(ctx).Query(ctx, query, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*models.Item
+	for rows.Next() {
+		var item models.Item
+		if err := scanItem(rows, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+	return items, rows.Err()
+}
+
+// GetItemSubtree returns rootID and all of its descendants as nodes ordered by
+// depth. Nodes are returned flat with empty Children.
+func (db *DB) GetItemSubtree(ctx context.Context, rootID string) ([]*models.ItemNode, error) {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id, 0 AS depth FROM items WHERE id = $1
+			UNION ALL
+			SELECT i.id, s.depth + 1
+			FROM items i JOIN subtree s ON i.parent_id = s.id
+			WHERE s.depth < $2
+		)
+		SELECT ` + prefixColumns("i", itemColumns) + `, s.depth
+		FROM subtree s JOIN items i ON i.id = s.id
+		ORDER BY s.depth ASC, i.rank ASC, i.created_at ASC
+	`
+	rows, err := db.read(ctx).Query(ctx, query, rootID, maxTreeRecursion)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes []*models.ItemNode
+	for rows.Next() {
+		node := &models.ItemNode{Item: &models.Item{}}
+		if err := scanItem(rows, node.Item, &node.Depth); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, rows.Err()
+}
+
+// GetItemDepth returns the number of ancestors of an item (0 for top-level items)
+func (db *DB) GetItemDepth(ctx context.Context, id string) (int, error) {
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT parent_id, 0 AS depth FROM items WHERE id = $1
+			UNION ALL
+			SELECT i.parent_id, a.depth + 1
+			FROM items i JOIN ancestors a ON i.id = a.parent_id
+			WHERE a.depth < $2
+		)
+		SELECT MAX(depth) FROM ancestors
+	`
+	var depth int
+	err := db.conn(ctx).QueryRow(ctx, query, id, maxTreeRecursion).Scan(&depth)
+	return depth, err
+}
+
+// GetItemSubtreeHeight returns the number of levels below an item (0 for leaves)
+func (db *DB) GetItemSubtreeHeight(ctx context.Context, id string) (int, error) {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id, 0 AS depth FROM items WHERE id = $1
+			UNION ALL
+			SELECT i.id, s.depth + 1
+			FROM items i JOIN subtree s ON i.parent_id = s.id
+			WHERE s.depth < $2
+		)
+		SELECT COALESCE(MAX(depth), 0) FROM subtree
+	`
+	var height int
+	err := db.conn(ctx).QueryRow(ctx, query, id, maxTreeRecursion).Scan(&height)
+	return height, err
+}
+
+// IsItemDescendant reports whether candidateID lies in the subtree rooted at ancestorID
+// (an item counts as its own descendant)
+func (db *DB) IsItemDescendant(ctx context.Context, ancestorID, candidateID string) (bool, error) {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id, 0 AS depth FROM items WHERE id = $1
+			UNION ALL
+			SELECT i.id, s.depth + 1
+			FROM items i JOIN subtree s ON i.parent_id = s.id
+			WHERE s.depth < $3
+		)
+		SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)
+	`
+	var exists bool
+	err := db.conn(ctx).QueryRow(ctx, query, ancestorID, candidateID, maxTreeRecursion).Scan(&exists)
+	return exists, err
+}
+
+// SetItemParent moves an item (and with it, its subtree) under a new parent
+func (db *DB) SetItemParent(ctx context.Context, id string, parentID *string) error {
+	query := `UPDATE items SET parent_id = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	result, err := db.conn(ctx).Exec(ctx, query, id, parentID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("item not found")
+	}
+	return nil
+}
+
+// GetItemsProgress rolls up descendant status counts for each of the given items.
+// Items without (non-archived) descendants are absent from the result.
+func (db *DB) GetItemsProgress(ctx context.Context, ids []string) (map[string]*models.ItemProgress, error) {
+	query := `
+		WITH RECURSIVE descendants AS (
+			SELECT parent_id AS root_id, id, status, 1 AS depth
+			FROM items WHERE parent_id = ANY($1)
+			UNION ALL
+			SELECT d.root_id, i.id, i.status, d.depth + 1
+			FROM items i JOIN descendants d ON i.parent_id = d.id
+			WHERE d.depth < $2
+		)
+		SELECT root_id, COUNT(*), COUNT(*) FILTER (WHERE status = 'completed')
+		FROM descendants
+		WHERE status <> 'archived'
+		GROUP BY root_id
+	`
+	rows, err := db.read(ctx).Query(ctx, query, ids, maxTreeRecursion)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	progress := make(map[string]*models.ItemProgress)
+	for rows.Next() {
+		var rootID string
+		var p models.ItemProgress
+		if err := rows.Scan(&rootID, &p.Total, &p.Completed); err != nil {
+			return nil, err
+		}
+		if p.Total > 0 {
+			p.Percent = p.Completed * 100 / p.Total
+		}
+		progress[rootID] = &p
+	}
+	return progress, rows.Err()
+}
+
 func (db *DB) DeleteItem(ctx context.Context, id string) error {
 	query := `DELETE FROM items WHERE id = $1`
-	result, err := db.Pool.Exec(ctx, query, id)
+	result, err := db.conn(ctx).Exec(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -204,20 +750,142 @@ func (db *DB) DeleteItem(ctx context.Context, id string) error {
 	return nil
 }
 
+// prefixColumns qualifies a comma-separated column list with a table alias
+func prefixColumns(alias, columns string) string {
+	parts := strings.Split(columns, ",")
+	for i, col := range parts {
+		parts[i] = alias + "." + strings.TrimSpace(col)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ============================================================================
+// Item Series Queries
+// ============================================================================
+
+func (db *DB) CreateItemSeries(ctx context.Context, series *models.ItemSeries) error {
+	query := `
+		INSERT INTO item_series (id, user_id, title, description, rrule, timezone, dtstart, exdates)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at, updated_at
+	`
+	if series.ExDates == nil {
+		series.ExDates = []time.Time{}
+	}
+	return db.conn(ctx).QueryRow(ctx, query,
+		series.ID, series.UserID, series.Title, series.Description,
+		series.RRule, series.Timezone, series.DTStart, series.ExDates,
+	).Scan(&series.CreatedAt, &series.UpdatedAt)
+}
+
+func (db *DB) GetItemSeriesByID(ctx context.Context, id string) (*models.ItemSeries, error) {
+	var series models.ItemSeries
+	query := `
+		SELECT id, user_id, title, description, rrule, timezone, dtstart, exdates, created_at, updated_at
+		FROM item_series WHERE id = $1
+	`
+	err := db.read(ctx).QueryRow(ctx, query, id).Scan(
+		&series.ID, &series.UserID, &series.Title, &series.Description,
+		&series.RRule, &series.Timezone, &series.DTStart, &series.ExDates,
+		&series.CreatedAt, &series.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// LockItemSeries locks a series row until the context's transaction ends,
+// serializing changes that derive occurrences from it
+func (db *DB) LockItemSeries(ctx context.Context, id string) error {
+	var locked string
+	return db.conn(ctx).QueryRow(ctx, `SELECT id FROM item_series WHERE id = $1 FOR UPDATE`, id).Scan(&locked)
+}
+
+func (db *DB) UpdateItemSeries(ctx context.Context, series *models.ItemSeries) error {
+	query := `
+		UPDATE item_series
+		SET title = $2, description = $3, rrule = $4, timezone = $5, dtstart = $6, exdates = $7,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at
+	`
+	if series.ExDates == nil {
+		series.ExDates = []time.Time{}
+	}
+	return db.conn(ctx).QueryRow(ctx, query,
+		series.ID, series.Title, series.Description, series.RRule,
+		series.Timezone, series.DTStart, series.ExDates,
+	).Scan(&series.UpdatedAt)
+}
+
+// ============================================================================
+// Calendar Feed Queries
+// ============================================================================
+
+// UpsertCalendarFeed stores a new token hash for the user, invalidating any previous token
+func (db *DB) UpsertCalendarFeed(ctx context.Context, feed *models.CalendarFeed) error {
+	query := `
+		INSERT INTO calendar_feeds (user_id, token_hash)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = CURRENT_TIMESTAMP
+		RETURNING created_at
+	`
+	return db.conn(ctx).QueryRow(ctx, query, feed.UserID, feed.TokenHash).Scan(&feed.CreatedAt)
+}
+
+func (db *DB) GetCalendarFeed(ctx context.Context, userID string) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	query := `SELECT user_id, token_hash, created_at FROM calendar_feeds WHERE user_id = $1`
+	err := db.read(ctx).QueryRow(ctx, query, userID).Scan(&feed.UserID, &feed.TokenHash, &feed.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (db *DB) GetCalendarFeedByTokenHash(ctx context.Context, tokenHash string) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	query := `SELECT user_id, token_hash, created_at FROM calendar_feeds WHERE token_hash = $1`
+	err := db.conn(ctx).QueryRow(ctx, query, tokenHash).Scan(&feed.UserID, &feed.TokenHash, &feed.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (db *DB) DeleteCalendarFeed(ctx context.Context, userID string) error {
+	query := `DELETE FROM calendar_feeds WHERE user_id = $1`
+	result, err := db.conn(ctx).Exec(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
 // ============================================================================
 // OAuth Queries
 // ============================================================================
 
+// CreateOAuthAccount links a provider account to a user, returning
+// ErrOAuthAccountLinked if either is already linked
 func (db *DB) CreateOAuthAccount(ctx context.Context, account *models.OAuthAccount) error {
 	query := `
 		INSERT INTO oauth_accounts (id, user_id, provider, provider_account_id, access_token, refresh_token, expires_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING created_at, updated_at
 	`
-	return db.Pool.QueryRow(ctx, query,
+	err := db.conn(ctx).QueryRow(ctx, query,
 		account.ID, account.UserID, account.Provider, account.ProviderAccountID,
 		account.AccessToken, account.RefreshToken, account.ExpiresAt,
 	).Scan(&account.CreatedAt, &account.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrOAuthAccountLinked
+	}
+	return err
 }
 
 func (db *DB) GetOAuthAccount(ctx context.Context, provider models.OAuthProvider, providerAccountID string) (*models.OAuthAccount, error) {
@@ -226,7 +894,7 @@ func (db *DB) GetOAuthAccount(ctx context.Context, provider models.OAuthProvider
 		SELECT id, user_id, provider, provider_account_id, access_token, refresh_token, expires_at, created_at, updated_at
 		FROM oauth_accounts WHERE provider = $1 AND provider_account_id = $2
 	`
-	err := db.Pool.QueryRow(ctx, query, provider, providerAccountID).Scan(
+	err := db.conn(ctx).QueryRow(ctx, query, provider, providerAccountID).Scan(
 		&account.ID, &account.UserID, &account.Provider, &account.ProviderAccountID,
 		&account.AccessToken, &account.RefreshToken, &account.ExpiresAt,
 		&account.CreatedAt, &account.UpdatedAt,
@@ -0,0 +1,190 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// replicaCheckInterval is how often replica health and lag are measured
+const replicaCheckInterval = 5 * time.Second
+
+// replicaLagQuery returns how far a replica's replay trails the primary, in
+// seconds, and whether its WAL receiver is streaming from the primary. A
+// streaming replica that has replayed everything it received is current even
+// if the primary has been idle since. A replica that stopped receiving WAL
+// only knows how old its last replayed transaction is, and is not current
+// however small that age. Without pg_read_all_stats (e.g. via pg_monitor) the
+// receiver's status is hidden, and a running receiver counts as streaming.
+const replicaLagQuery = `
+	WITH receiver AS (
+		SELECT EXISTS (
+			SELECT 1 FROM pg_stat_wal_receiver WHERE status IS NULL OR status = 'streaming'
+		) AS streaming
+	)
+	SELECT
+		NOT pg_is_in_recovery() OR streaming,
+		CASE
+			WHEN NOT pg_is_in_recovery() THEN 0
+			WHEN streaming AND pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		END
+	FROM receiver
+`
+
+type replica struct {
+	name    string // host:port, safe to log
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+	lag     atomic.Int64 // nanoseconds
+}
+
+// ReplicaStatus describes a read replica for health reporting
+type ReplicaStatus struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	LagMs   int64  `json:"lagMs"`
+}
+
+// replicaSet routes reads across the healthy replicas and remembers which
+// sessions wrote recently
+type replicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+	maxLag   time.Duration
+	writes   sync.Map // session key -> time.Time of its last write
+}
+
+type replicaReadsKey struct{}
+
+// ReadFromReplicas returns a context whose list and get queries may be served
+// by a replica. Reads stay on the primary while session has written within the
+// replica lag limit, so a client sees its own writes.
+func (db *DB) ReadFromReplicas(ctx context.Context, session string) context.Context {
+	if db.replicas == nil || db.replicas.wroteRecently(session) {
+		return ctx
+	}
+	return context.WithValue(ctx, replicaReadsKey{}, true)
+}
+
+// PinToPrimary records that session is writing, keeping its reads on the
+// primary until replicas have caught up
+func (db *DB) PinToPrimary(session string) {
+	if db.replicas != nil && session != "" {
+		db.replicas.writes.Store(session, time.Now())
+	}
+}
+
+// HasReplicas reports whether read replicas are configured
+func (db *DB) HasReplicas() bool {
+	return db.replicas != nil
+}
+
+// ReplicaStatus reports the health and lag of each replica
+func (db *DB) ReplicaStatus() []ReplicaStatus {
+	if db.replicas == nil {
+		return nil
+	}
+	statuses := make([]ReplicaStatus, len(db.replicas.replicas))
+	for i, r := range db.replicas.replicas {
+		statuses[i] = ReplicaStatus{
+			Name:    r.name,
+			Healthy: r.healthy.Load(),
+			LagMs:   time.Duration(r.lag.Load()).Milliseconds(),
+		}
+	}
+	return statuses
+}
+
+// read returns the querier for a read-only query: the context's transaction,
+// a healthy replica if the context allows one, or the primary
+func (db *DB) read(ctx context.Context) querier {
+	return timed(db.route(ctx), QueryRead, db.readTimeout)
+}
+
+func (db *DB) route(ctx context.Context) querier {
+	_, inTx := ctx.Value(txKey{}).(pgx.Tx)
+	if inTx || db.replicas == nil || ctx.Value(replicaReadsKey{}) == nil {
+		return db.primary(ctx)
+	}
+	if r := db.replicas.pick(); r != nil {
+		return r.pool
+	}
+	return db.Pool
+}
+
+// pick returns the next healthy replica in round-robin order, or nil
+func (s *replicaSet) pick() *replica {
+	n := uint64(len(s.replicas))
+	start := s.next.Add(1)
+	for i := range n {
+		if r := s.replicas[(start+i)%n]; r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+func (s *replicaSet) wroteRecently(session string) bool {
+	if session == "" {
+		return false
+	}
+	at, ok := s.writes.Load(session)
+	return ok && time.Since(at.(time.Time)) < s.maxLag
+}
+
+// monitor measures every replica until ctx is cancelled, evicting replicas
+// that are unreachable, not receiving WAL or lag by more than maxLag
+func (s *replicaSet) monitor(ctx context.Context) {
+	ticker := time.NewTicker(replicaCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.check(ctx)
+
+			// Forget sessions whose pin has expired
+			s.writes.Range(func(session, at any) bool {
+				if time.Since(at.(time.Time)) >= s.maxLag {
+					s.writes.Delete(session)
+				}
+				return true
+			})
+		}
+	}
+}
+
+func (s *replicaSet) check(ctx context.Context) {
+	for _, r := range s.replicas {
+		checkCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		var streaming bool
+		var lagSeconds float64
+		err := r.pool.QueryRow(checkCtx, replicaLagQuery).Scan(&streaming, &lagSeconds)
+		cancel()
+
+		lag := time.Duration(lagSeconds * float64(time.Second))
+		healthy := err == nil && streaming && lag <= s.maxLag
+		r.lag.Store(int64(lag))
+
+		if was := r.healthy.Swap(healthy); was != healthy {
+			switch {
+			case healthy:
+				slog.Info("Replica in rotation", "replica", r.name, "lag", lag.Round(time.Millisecond))
+			case err != nil:
+				slog.Warn("Replica evicted", "replica", r.name, "error", err)
+			case !streaming:
+				slog.Warn("Replica evicted", "replica", r.name, "reason", "WAL receiver is not streaming")
+			default:
+				slog.Warn("Replica evicted", "replica", r.name, "lag", lag.Round(time.Millisecond), "max_lag", s.maxLag)
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const scheduledTaskColumns = `name, schedule, next_run_at, last_run_at, last_finished_at, last_status,
+	last_error, last_duration_ms, last_run_by, run_count, failure_count, created_at, updated_at`
+
+func scanScheduledTask(row pgx.Row, t *models.ScheduledTask) error {
+	return row.Scan(
+		&t.Name, &t.Schedule, &t.NextRunAt, &t.LastRunAt, &t.LastFinishedAt, &t.LastStatus,
+		&t.LastError, &t.LastDurationMs, &t.LastRunBy, &t.RunCount, &t.FailureCount, &t.CreatedAt, &t.UpdatedAt,
+	)
+}
+
+// SyncScheduledTask registers a task, or updates its schedule. nextRunAt only
+// applies to new tasks and to tasks whose schedule changed.
+func (db *DB) SyncScheduledTask(ctx context.Context, name, schedule string, nextRunAt time.Time) error {
+	query := `
+		INSERT INTO scheduled_tasks (name, schedule, next_run_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET
+			schedule = EXCLUDED.schedule,
+			next_run_at = CASE
+				WHEN scheduled_tasks.schedule = EXCLUDED.schedule THEN scheduled_tasks.next_run_at
+				ELSE EXCLUDED.next_run_at
+			END
+	`
+	_, err := db.conn(ctx).Exec(ctx, query, name, schedule, nextRunAt)
+	return err
+}
+
+func (db *DB) GetScheduledTasks(ctx context.Context) ([]*models.ScheduledTask, error) {
+	query := `SELECT ` + scheduledTaskColumns + ` FROM scheduled_tasks ORDER BY name`
+	rows, err := db.read(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []*models.ScheduledTask{}
+	for rows.Next() {
+		var t models.ScheduledTask
+		if err := scanScheduledTask(rows, &t); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, &t)
+	}
+	return tasks, rows.Err()
+}
+
+// ClaimScheduledTask starts the run due at dueAt by moving next_run_at to
+// nextRunAt. It returns false if the tick was already claimed, so each tick
+// runs once even if two instances briefly both act as leader.
+func (db *DB) ClaimScheduledTask(ctx context.Context, name string, dueAt, nextRunAt time.Time, runBy string) (bool, error) {
+	query := `
+		UPDATE scheduled_tasks
+		SET next_run_at = $3,
+			last_run_at = CURRENT_TIMESTAMP,
+			last_status = 'running',
+			last_run_by = $4,
+			run_count = run_count + 1
+		WHERE name = $1 AND next_run_at = $2
+	`
+	result, err := db.conn(ctx).Exec(ctx, query, name, dueAt, nextRunAt, runBy)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// FinishScheduledTask records the outcome of a run; a nil error message means success
+func (db *DB) FinishScheduledTask(ctx context.Context, name string, lastError *string, duration time.Duration) error {
+	query := `
+		UPDATE scheduled_tasks
+		SET last_status = CASE WHEN $2::text IS NULL THEN 'succeeded' ELSE 'failed' END,
+			last_error = $2,
+			last_finished_at = CURRENT_TIMESTAMP,
+			last_duration_ms = $3,
+			failure_count = failure_count + CASE WHEN $2::text IS NULL THEN 0 ELSE 1 END
+		WHERE name = $1
+	`
+	_, err := db.conn(ctx).Exec(ctx, query, name, lastError, duration.Milliseconds())
+	return err
+}
+
+// TriggerScheduledTask makes a task due now. Returns pgx.ErrNoRows if it does not exist.
+func (db *DB) TriggerScheduledTask(ctx context.Context, name string) (*models.ScheduledTask, error) {
+	var t models.ScheduledTask
+	query := `
+		UPDATE scheduled_tasks SET next_run_at = CURRENT_TIMESTAMP
+		WHERE name = $1
+		RETURNING ` + scheduledTaskColumns
+	if err := scanScheduledTask(db.conn(ctx).QueryRow(ctx, query, name), &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
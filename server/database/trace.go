@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/logging"
+	"github.com/binduni/bun-golang-react-monorepo/server/tracing"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryKind classifies queries for timeouts and reporting
+type QueryKind string
+
+const (
+	QueryRead        QueryKind = "read"        // List and get queries, possibly served by a replica
+	QueryWrite       QueryKind = "write"       // Everything else on the primary
+	QueryMaintenance QueryKind = "maintenance" // Queries of scheduled tasks, see Maintenance
+)
+
+// QueryStat describes one finished query
+type QueryStat struct {
+	Name     string // DB method that ran the query, such as "GetUserItems"
+	Kind     QueryKind
+	Duration time.Duration
+	Rows     int64 // Rows returned or affected
+	Err      error
+}
+
+// queryTracer is a pgx.QueryTracer that times every query, records a span
+// for it, logs slow ones and reports each to the DB's observers
+type queryTracer struct {
+	db            *DB
+	slowThreshold time.Duration
+}
+
+type queryTraceKey struct{}
+
+type queryTrace struct {
+	name    string
+	sql     string
+	started time.Time
+	span    trace.Span
+}
+
+var tracer = tracing.Tracer("database")
+
+type queryKindKey struct{}
+
+func (t *queryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	name := callerMethod()
+	config := conn.Config()
+	ctx, span := tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBNamespace(config.Database),
+			semconv.DBQueryText(compactSQL(data.SQL)),
+			semconv.ServerAddress(config.Host),
+			semconv.ServerPort(int(config.Port)),
+		),
+	)
+	return context.WithValue(ctx, queryTraceKey{}, &queryTrace{
+		name:    name,
+		sql:     data.SQL,
+		started: time.Now(),
+		span:    span,
+	})
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	qt, ok := ctx.Value(queryTraceKey{}).(*queryTrace)
+	if !ok {
+		return
+	}
+
+	kind, ok := ctx.Value(queryKindKey{}).(QueryKind)
+	if !ok {
+		kind = QueryWrite
+	}
+	stat := QueryStat{
+		Name:     qt.name,
+		Kind:     kind,
+		Duration: time.Since(qt.started),
+		Rows:     data.CommandTag.RowsAffected(),
+		Err:      data.Err,
+	}
+
+	qt.span.SetAttributes(attribute.String("db.query.kind", string(kind)), attribute.Int64("db.response.rows", stat.Rows))
+	if stat.Err != nil && !errors.Is(stat.Err, pgx.ErrNoRows) {
+		qt.span.RecordError(stat.Err)
+		qt.span.SetStatus(codes.Error, "query failed")
+	}
+	qt.span.End()
+
+	if t.slowThreshold > 0 && stat.Duration >= t.slowThreshold {
+		logging.FromContext(ctx).Warn("Slow query",
+			"query", stat.Name,
+			"kind", stat.Kind,
+			"duration", stat.Duration.Round(time.Millisecond),
+			"rows", stat.Rows,
+			"sql", compactSQL(qt.sql),
+		)
+	}
+	if observers := t.db.queryObservers.Load(); observers != nil {
+		for _, observe := range *observers {
+			observe(stat)
+		}
+	}
+}
+
+// OnQuery registers fn to be called after every query, for metrics. fn must
+// be fast and safe for concurrent use.
+func (db *DB) OnQuery(fn func(QueryStat)) {
+	for {
+		old := db.queryObservers.Load()
+		var observers []func(QueryStat)
+		if old != nil {
+			observers = append(observers, *old...)
+		}
+		observers = append(observers, fn)
+		if db.queryObservers.CompareAndSwap(old, &observers) {
+			return
+		}
+	}
+}
+
+// callerMethod returns the name of the DB method on the stack. Statements
+// issued by WithTx itself (BEGIN, COMMIT) are named "transaction", and
+// queries made outside of a DB method "unknown".
+func callerMethod() string {
+	const prefix = "server/database.(*DB)."
+
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	inTx := false
+	for {
+		frame, more := frames.Next()
+		if i := strings.Index(frame.Function, prefix); i >= 0 {
+			// Closures are named Method.func1
+			name, _, _ := strings.Cut(frame.Function[i+len(prefix):], ".")
+			switch name {
+			case "WithTx", "runTx", "begin":
+				inTx = true
+			default:
+				return name
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	if inTx {
+		return "transaction"
+	}
+	return "unknown"
+}
+
+// compactSQL collapses whitespace so a query fits on one log line
+func compactSQL(sql string) string {
+	sql = strings.Join(strings.Fields(sql), " ")
+	if len(sql) > 200 {
+		sql = sql[:200] + "…"
+	}
+	return sql
+}
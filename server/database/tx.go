@@ -0,0 +1,227 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// maxTxAttempts bounds how often WithTx runs a transaction that keeps hitting
+// serialization failures or deadlocks
+const maxTxAttempts = 3
+
+type txKey struct{}
+
+type serializableKey struct{}
+
+// Serializable returns a context whose transactions run at the SERIALIZABLE
+// isolation level. Use it for a transaction that writes based on rows it read
+// without locking them: a concurrent change to those rows then aborts it with
+// a serialization failure, and WithTx runs it again on the committed state.
+func Serializable(ctx context.Context) context.Context {
+	return context.WithValue(ctx, serializableKey{}, true)
+}
+
+// querier is satisfied by both the pool and a transaction
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// WithTx runs fn in a transaction carried by the context it receives, so every
+// query made with that context joins it. The transaction commits when fn
+// returns nil and rolls back otherwise. Nested calls use savepoints.
+//
+// Transactions run at READ COMMITTED, where concurrent writers block on row
+// locks instead of failing, unless ctx is marked Serializable. A top-level
+// transaction that fails with a deadlock, or with a serialization failure at
+// the serializable level, is retried from the start, so fn must be safe to
+// run more than once: it should read what it changes inside the transaction
+// and rebuild any values it modifies, rather than carry over state from a
+// rolled-back attempt.
+func (db *DB) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, nested := ctx.Value(txKey{}).(pgx.Tx); nested {
+		// The enclosing transaction is aborted as a whole, so only it can retry
+		return db.runTx(ctx, fn)
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = db.runTx(ctx, fn)
+		if err == nil || attempt == maxTxAttempts || !isRetryable(err) {
+			return err
+		}
+
+		// Back off 10-20ms, then 20-40ms, so conflicting transactions drift apart
+		backoff := time.Duration(attempt) * 10 * time.Millisecond
+		select {
+		case <-time.After(backoff + rand.N(backoff)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+func (db *DB) runTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// isRetryable reports whether err aborted a transaction that may succeed if run again
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	switch pgErr.Code {
+	case "40001", "40P01": // serialization_failure, deadlock_detected
+		return true
+	}
+	return false
+}
+
+// isUniqueViolation reports whether err violated a unique constraint
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// conn returns the querier for a query that must run on the primary: the
+// context's transaction, or the pool outside of one
+func (db *DB) conn(ctx context.Context) querier {
+	return timed(db.primary(ctx), QueryWrite, db.writeTimeout)
+}
+
+func (db *DB) primary(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return db.Pool
+}
+
+// timedQuerier tags queries with their kind for the tracer and cancels any
+// that run longer than timeout (if set)
+type timedQuerier struct {
+	q       querier
+	kind    QueryKind
+	timeout time.Duration
+}
+
+func timed(q querier, kind QueryKind, timeout time.Duration) querier {
+	return timedQuerier{q: q, kind: kind, timeout: timeout}
+}
+
+type maintenanceKey struct{}
+
+// Maintenance returns a context for bulk work such as pruning, whose queries
+// are not subject to the read and write timeouts
+func Maintenance(ctx context.Context) context.Context {
+	return context.WithValue(ctx, maintenanceKey{}, true)
+}
+
+func (t timedQuerier) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx.Value(maintenanceKey{}) != nil {
+		t.kind, t.timeout = QueryMaintenance, 0
+	}
+	ctx = context.WithValue(ctx, queryKindKey{}, t.kind)
+	if t.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, t.timeout)
+}
+
+func (t timedQuerier) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	ctx, cancel := t.context(ctx)
+	defer cancel()
+	return t.q.Exec(ctx, sql, arguments...)
+}
+
+func (t timedQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	ctx, cancel := t.context(ctx)
+	rows, err := t.q.Query(ctx, sql, args...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &timedRows{Rows: rows, cancel: cancel}, nil
+}
+
+func (t timedQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	ctx, cancel := t.context(ctx)
+	return timedRow{row: t.q.QueryRow(ctx, sql, args...), cancel: cancel}
+}
+
+// timedRows keeps the query's deadline until the rows are read or closed
+type timedRows struct {
+	pgx.Rows
+	cancel context.CancelFunc
+}
+
+func (r *timedRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.cancel()
+	return false
+}
+
+func (r *timedRows) Close() {
+	r.Rows.Close()
+	r.cancel()
+}
+
+// timedRow keeps the query's deadline until the row is scanned
+type timedRow struct {
+	row    pgx.Row
+	cancel context.CancelFunc
+}
+
+func (r timedRow) Scan(dest ...any) error {
+	defer r.cancel()
+	return r.row.Scan(dest...)
+}
+
+// begin starts a transaction, or a savepoint inside the context's transaction.
+// A transaction begun for a request carries the request ID in its
+// application_name, so it can be found in pg_stat_activity and server logs.
+func (db *DB) begin(ctx context.Context) (pgx.Tx, error) {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx.Begin(ctx)
+	}
+	var opts pgx.TxOptions
+	begin := "BEGIN"
+	if ctx.Value(serializableKey{}) != nil {
+		opts.IsoLevel = pgx.Serializable
+		begin = "BEGIN ISOLATION LEVEL SERIALIZABLE"
+	}
+	if id := utils.RequestIDFromContext(ctx); db.applicationName != "" && utils.ValidRequestID(id) {
+		// Sent with BEGIN in one round trip; SET LOCAL ends with the transaction.
+		// BeginQuery replaces IsoLevel, so it repeats the isolation level.
+		name := strings.ReplaceAll(db.applicationName+" "+id, "'", "''")
+		opts.BeginQuery = begin + "; SET LOCAL application_name = '" + name + "'"
+	}
+	return db.Pool.BeginTx(ctx, opts)
+}
+
+// requestID returns the context's request ID for storing with a row, or nil
+func requestID(ctx context.Context) *string {
+	if id := utils.RequestIDFromContext(ctx); id != "" {
+		return &id
+	}
+	return nil
+}
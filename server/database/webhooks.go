@@ -0,0 +1,315 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+// ============================================================================
+// Webhook Endpoint Queries
+// ============================================================================
+
+const webhookEndpointColumns = `id, user_id, url, description, event_types, enabled,
+	secret, previous_secret, previous_secret_expires_at, created_at, updated_at`
+
+func scanWebhookEndpoint(row pgx.Row, e *models.WebhookEndpoint) error {
+	return row.Scan(
+		&e.ID, &e.UserID, &e.URL, &e.Description, &e.EventTypes, &e.Enabled,
+		&e.Secret, &e.PreviousSecret, &e.PreviousSecretExpiresAt, &e.CreatedAt, &e.UpdatedAt,
+	)
+}
+
+func (db *DB) CreateWebhookEndpoint(ctx context.Context, e *models.WebhookEndpoint) error {
+	query := `
+		INSERT INTO webhook_endpoints (id, user_id, url, description, event_types, enabled, secret)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at, updated_at
+	`
+	return db.conn(ctx).QueryRow(ctx, query,
+		e.ID, e.UserID, e.URL, e.Description, e.EventTypes, e.Enabled, e.Secret,
+	).Scan(&e.CreatedAt, &e.UpdatedAt)
+}
+
+func (db *DB) GetWebhookEndpoint(ctx context.Context, id string) (*models.WebhookEndpoint, error) {
+	var e models.WebhookEndpoint
+	query := `SELECT ` + webhookEndpointColumns + ` FROM webhook_endpoints WHERE id = $1`
+	if err := scanWebhookEndpoint(db.read(ctx).QueryRow(ctx, query, id), &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (db *DB) GetUserWebhookEndpoints(ctx context.Context, userID string) ([]*models.WebhookEndpoint, error) {
+	query := `SELECT ` + webhookEndpointColumns + ` FROM webhook_endpoints WHERE user_id = $1 ORDER BY created_at DESC`
+	rows, err := db.read(ctx).Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := []*models.WebhookEndpoint{}
+	for rows.Next() {
+		var e models.WebhookEndpoint
+		if err := scanWebhookEndpoint(rows, &e); err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, &e)
+	}
+	return endpoints, rows.Err()
+}
+
+func (db *DB) UpdateWebhookEndpoint(ctx context.Context, e *models.WebhookEndpoint) error {
+	query := `
+		UPDATE webhook_endpoints
+		SET url = $2, description = $3, event_types = $4, enabled = $5
+		WHERE id = $1
+		RETURNING updated_at
+	`
+	return db.conn(ctx).QueryRow(ctx, query, e.ID, e.URL, e.Description, e.EventTypes, e.Enabled).Scan(&e.UpdatedAt)
+}
+
+// RotateWebhookSecret replaces the signing secret. The old secret keeps
+// signing deliveries alongside the new one for the grace period so receivers
+// can switch over without dropping events.
+func (db *DB) RotateWebhookSecret(ctx context.Context, id, secret string, grace time.Duration) (*models.WebhookEndpoint, error) {
+	var e models.WebhookEndpoint
+	query := `
+		UPDATE webhook_endpoints
+		SET previous_secret = secret,
+			previous_secret_expires_at = CURRENT_TIMESTAMP + make_interval(secs => $3),
+			secret = $2
+		WHERE id = $1
+		RETURNING ` + webhookEndpointColumns
+	if err := scanWebhookEndpoint(db.conn(ctx).QueryRow(ctx, query, id, secret, grace.Seconds()), &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (db *DB) DeleteWebhookEndpoint(ctx context.Context, id string) error {
+	result, err := db.conn(ctx).Exec(ctx, `DELETE FROM webhook_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("webhook endpoint not found")
+	}
+	return nil
+}
+
+// ============================================================================
+// Webhook Delivery Queries
+// ============================================================================
+
+const webhookDeliveryColumns = `id, endpoint_id, event_id, event_type, request_id, payload, status, attempts,
+	next_attempt_at, last_attempt_at, response_status, last_error, created_at, completed_at`
+
+func scanWebhookDelivery(row pgx.Row, d *models.WebhookDelivery) error {
+	return row.Scan(
+		&d.ID, &d.EndpointID, &d.EventID, &d.EventType, &d.RequestID, &d.Payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastAttemptAt, &d.ResponseStatus, &d.LastError, &d.CreatedAt, &d.CompletedAt,
+	)
+}
+
+// EnqueueWebhookEvent queues an event for every enabled endpoint subscribed
+// to its type, recording the context's request ID on each delivery. Endpoints owned by ownerID receive it; a nil ownerID targets
+// endpoints owned by admins (for events such as user.registered).
+func (db *DB) EnqueueWebhookEvent(ctx context.Context, ownerID *string, event *models.WebhookEvent) (int, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		SELECT e.id FROM webhook_endpoints e
+		JOIN users u ON u.id = e.user_id
+		WHERE e.enabled
+			AND ($2 = ANY(e.event_types) OR '*' = ANY(e.event_types))
+			AND (($1::text IS NOT NULL AND e.user_id = $1) OR ($1::text IS NULL AND u.role = 'admin'))
+	`
+	rows, err := db.conn(ctx).Query(ctx, query, ownerID, event.Type)
+	if err != nil {
+		return 0, err
+	}
+	endpointIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil || len(endpointIDs) == 0 {
+		return 0, err
+	}
+
+	deliveryIDs := make([]string, len(endpointIDs))
+	for i := range deliveryIDs {
+		deliveryIDs[i] = utils.NewDeliveryID()
+	}
+	insert := `
+		INSERT INTO webhook_deliveries (id, endpoint_id, event_id, event_type, request_id, payload)
+		SELECT d.id, d.endpoint_id, $3, $4, $5, $6
+		FROM unnest($1::text[], $2::text[]) AS d(id, endpoint_id)
+	`
+	if _, err := db.conn(ctx).Exec(ctx, insert, deliveryIDs, endpointIDs, event.ID, event.Type, requestID(ctx), payload); err != nil {
+		return 0, err
+	}
+	return len(endpointIDs), nil
+}
+
+// EnqueueWebhookDelivery queues an event for a single endpoint regardless of its subscriptions
+func (db *DB) EnqueueWebhookDelivery(ctx context.Context, endpointID string, event *models.WebhookEvent) (*models.WebhookDelivery, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	var d models.WebhookDelivery
+	query := `
+		INSERT INTO webhook_deliveries (id, endpoint_id, event_id, event_type, request_id, payload)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + webhookDeliveryColumns
+	err = scanWebhookDelivery(db.conn(ctx).QueryRow(ctx, query, utils.NewDeliveryID(), endpointID, event.ID, event.Type, requestID(ctx), payload), &d)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// RedeliverWebhook queues a fresh delivery of an earlier delivery's event.
+// The event ID is kept so receivers can deduplicate; the request ID becomes
+// that of the redelivery request, if any.
+func (db *DB) RedeliverWebhook(ctx context.Context, deliveryID string) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	query := `
+		INSERT INTO webhook_deliveries (id, endpoint_id, event_id, event_type, request_id, payload)
+		SELECT $1, endpoint_id, event_id, event_type, COALESCE($3, request_id), payload FROM webhook_deliveries WHERE id = $2
+		RETURNING ` + webhookDeliveryColumns
+	if err := scanWebhookDelivery(db.conn(ctx).QueryRow(ctx, query, utils.NewDeliveryID(), deliveryID, requestID(ctx)), &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ClaimWebhookDeliveries leases up to limit due deliveries of enabled
+// endpoints. Each claim counts as an attempt and pushes next_attempt_at out
+// by the lease, so a delivery abandoned by a crashed worker is retried.
+func (db *DB) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	query := `
+		WITH due AS (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhook_endpoints e ON e.id = d.endpoint_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= CURRENT_TIMESTAMP AND e.enabled
+			ORDER BY d.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1,
+			last_attempt_at = CURRENT_TIMESTAMP,
+			next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+		FROM due WHERE d.id = due.id
+		RETURNING ` + prefixColumns("d", webhookDeliveryColumns)
+
+	rows, err := db.conn(ctx).Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RecordWebhookAttempt logs an attempt and moves the delivery to its next
+// state: succeeded, dead, or pending again at nextAttemptAt.
+func (db *DB) RecordWebhookAttempt(ctx context.Context, d *models.WebhookDelivery, attempt *models.WebhookDeliveryAttempt, nextAttemptAt time.Time) error {
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempt, response_status, response_body, error, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, d.ID, attempt.Attempt, attempt.ResponseStatus, attempt.ResponseBody, attempt.Error, attempt.DurationMs,
+	).Scan(&attempt.ID, &attempt.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, next_attempt_at = $3, response_status = $4, last_error = $5,
+			completed_at = CASE WHEN $2 = 'pending' THEN NULL ELSE CURRENT_TIMESTAMP END
+		WHERE id = $1
+	`, d.ID, d.Status, nextAttemptAt, attempt.ResponseStatus, attempt.Error)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetWebhookDeliveries returns an endpoint's most recent deliveries, optionally filtered by status
+func (db *DB) GetWebhookDeliveries(ctx context.Context, endpointID string, status *models.WebhookDeliveryStatus, limit int) ([]*models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries
+		WHERE endpoint_id = $1 AND ($2::text IS NULL OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3
+	`
+	rows, err := db.read(ctx).Query(ctx, query, endpointID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetWebhookDelivery returns a delivery with its attempt log
+func (db *DB) GetWebhookDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	q := db.read(ctx)
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+	if err := scanWebhookDelivery(q.QueryRow(ctx, query, id), &d); err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT id, delivery_id, attempt, response_status, response_body, error, duration_ms, created_at
+		FROM webhook_delivery_attempts WHERE delivery_id = $1 ORDER BY id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	d.AttemptLog = []*models.WebhookDeliveryAttempt{}
+	for rows.Next() {
+		var a models.WebhookDeliveryAttempt
+		if err := rows.Scan(&a.ID, &a.DeliveryID, &a.Attempt, &a.ResponseStatus, &a.ResponseBody, &a.Error, &a.DurationMs, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		d.AttemptLog = append(d.AttemptLog, &a)
+	}
+	return &d, rows.Err()
+}
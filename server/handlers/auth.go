@@ -1,23 +1,26 @@
 package handlers
 
 import (
+	"context"
 	"time"
 
 	"github.com/binduni/bun-golang-react-monorepo/server/config"
-	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/metrics"
 	"github.com/binduni/bun-golang-react-monorepo/server/middleware"
 	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/outbox"
+	"github.com/binduni/bun-golang-react-monorepo/server/store"
 	"github.com/binduni/bun-golang-react-monorepo/server/utils"
 	"github.com/gofiber/fiber/v3"
 	"github.com/jackc/pgx/v5"
 )
 
 type AuthHandler struct {
-	db     *database.DB
+	db     store.Store
 	config *config.Config
 }
 
-func NewAuthHandler(db *database.DB, cfg *config.Config) *AuthHandler {
+func NewAuthHandler(db store.Store, cfg *config.Config) *AuthHandler {
 	return &AuthHandler{
 		db:     db,
 		config: cfg,
@@ -67,27 +70,23 @@ func (h *AuthHandler) Register(c fiber.Ctx) error {
 		EmailVerified: false,
 	}
 
-	if err := h.db.CreateUser(c.Context(), user); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to create user"))
+	// Create session
+	session := &models.Session{
+		ID:        utils.NewSessionID(),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(utils.RefreshTokenExpiry),
 	}
 
 	// Generate tokens
-	accessToken, err := utils.GenerateAccessToken(user, h.config.JWTSecret)
+	accessToken, err := utils.GenerateAccessToken(user, session.ID, h.config.JWTSecret)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to generate access token"))
 	}
 
-	refreshToken, err := utils.GenerateRefreshToken(user, h.config.JWTSecret)
+	refreshToken, err := utils.GenerateRefreshToken(user, session.ID, h.config.JWTSecret)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to generate refresh token"))
 	}
-
-	// Create session
-	session := &models.Session{
-		ID:        utils.NewSessionID(),
-		UserID:    user.ID,
-		ExpiresAt: time.Now().Add(utils.RefreshTokenExpiry),
-	}
 	userAgent := c.Get("User-Agent")
 	if userAgent != "" {
 		session.UserAgent = &userAgent
@@ -97,8 +96,21 @@ func (h *AuthHandler) Register(c fiber.Ctx) error {
 		session.IPAddress = &ipAddress
 	}
 
-	if err := h.db.CreateSession(c.Context(), session); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to create session"))
+	// The user, their first session and the registration event commit together
+	if err := h.db.WithTx(c.Context(), func(ctx context.Context) error {
+		if err := h.db.CreateUser(ctx, user); err != nil {
+			return err
+		}
+		if err := h.db.CreateSession(ctx, session); err != nil {
+			return err
+		}
+		event := outbox.NewEvent(models.EventUserRegistered, models.AggregateUser, user.ID, &user.ID)
+		return h.db.RecordDomainEvent(ctx, event, user)
+	}); err == store.ErrEmailTaken {
+		// Lost a race with a concurrent registration; nothing was written
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse("Email already registered"))
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to create user"))
 	}
 
 	// Return response
@@ -126,33 +138,35 @@ func (h *AuthHandler) Login(c fiber.Ctx) error {
 	user, err := h.db.GetUserByEmail(c.Context(), req.Email)
 	if err != nil {
 		if err == pgx.ErrNoRows {
+			metrics.Login(metrics.Failure)
 			return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Invalid email or password"))
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Database error"))
 	}
 
 	// Verify password
-	if user.PasswordHash == nil || !utils.VerifyPassword(*user.PasswordHash, req.Password) {
+	if user.PasswordHash == nil || !utils.VerifyPassword(c.Context(), *user.PasswordHash, req.Password) {
+		metrics.Login(metrics.Failure)
 		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Invalid email or password"))
 	}
 
+	// Create session
+	session := &models.Session{
+		ID:        utils.NewSessionID(),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(utils.RefreshTokenExpiry),
+	}
+
 	// Generate tokens
-	accessToken, err := utils.GenerateAccessToken(user, h.config.JWTSecret)
+	accessToken, err := utils.GenerateAccessToken(user, session.ID, h.config.JWTSecret)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to generate access token"))
 	}
 
-	refreshToken, err := utils.GenerateRefreshToken(user, h.config.JWTSecret)
+	refreshToken, err := utils.GenerateRefreshToken(user, session.ID, h.config.JWTSecret)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to generate refresh token"))
 	}
-
-	// Create session
-	session := &models.Session{
-		ID:        utils.NewSessionID(),
-		UserID:    user.ID,
-		ExpiresAt: time.Now().Add(utils.RefreshTokenExpiry),
-	}
 	userAgent := c.Get("User-Agent")
 	if userAgent != "" {
 		session.UserAgent = &userAgent
@@ -162,9 +176,16 @@ func (h *AuthHandler) Login(c fiber.Ctx) error {
 		session.IPAddress = &ipAddress
 	}
 
-	if err := h.db.CreateSession(c.Context(), session); err != nil {
+	if err := h.db.WithTx(c.Context(), func(ctx context.Context) error {
+		if err := h.db.CreateSession(ctx, session); err != nil {
+			return err
+		}
+		event := outbox.NewEvent(models.EventUserLogin, models.AggregateSession, session.ID, &user.ID)
+		return h.db.RecordDomainEvent(ctx, event, session)
+	}); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to create session"))
 	}
+	metrics.Login(metrics.Success)
 
 	// Return response
 	response := models.AuthResponse{
@@ -185,28 +206,43 @@ func (h *AuthHandler) RefreshToken(c fiber.Ctx) error {
 	}
 
 	// Validate refresh token
-	claims, err := utils.ValidateToken(req.RefreshToken, h.config.JWTSecret)
+	claims, err := utils.ValidateToken(req.RefreshToken, h.config.JWTSecret, h.config.JWTPreviousSecrets...)
 	if err != nil {
+		metrics.TokenRefresh(metrics.Failure)
 		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Invalid or expired refresh token"))
 	}
 
 	// Verify it's a refresh token
 	if claims.Type != models.TokenTypeRefresh {
+		metrics.TokenRefresh(metrics.Failure)
 		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Invalid token type"))
 	}
 
+	// Revoked and purged sessions end the token's use before it expires
+	session, err := h.db.GetSessionByID(c.Context(), claims.SessionID)
+	if err != nil && err != pgx.ErrNoRows {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve session"))
+	}
+	if err == pgx.ErrNoRows || session.UserID != claims.UserID || !session.ExpiresAt.After(time.Now()) {
+		metrics.TokenRefresh(metrics.Failure)
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Session has expired or been revoked"))
+	}
+
 	// Get user
 	user, err := h.db.GetUserByID(c.Context(), claims.UserID)
 	if err != nil {
+		metrics.TokenRefresh(metrics.Failure)
 		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("User not found"))
 	}
 
 	// Generate new access token
-	accessToken, err := utils.GenerateAccessToken(user, h.config.JWTSecret)
+	accessToken, err := utils.GenerateAccessToken(user, claims.SessionID, h.config.JWTSecret)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to generate access token"))
 	}
 
+	metrics.TokenRefresh(metrics.Success)
+
 	// Return response
 	response := models.RefreshTokenResponse{
 		AccessToken: accessToken,
@@ -261,3 +297,37 @@ func (h *AuthHandler) GetSessions(c fiber.Ctx) error {
 
 	return c.JSON(models.SuccessResponse(sessions))
 }
+
+// RevokeSession deletes one of the authenticated user's sessions
+func (h *AuthHandler) RevokeSession(c fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Unauthorized"))
+	}
+
+	session, err := h.db.GetSessionByID(c.Context(), c.Params("id"))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse("Session not found"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve session"))
+	}
+	// Other users' sessions are reported as missing rather than forbidden
+	if session.UserID != userID {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse("Session not found"))
+	}
+
+	if err := h.db.WithTx(c.Context(), func(ctx context.Context) error {
+		if err := h.db.DeleteSession(ctx, session.ID); err != nil {
+			return err
+		}
+		event := outbox.NewEvent(models.EventSessionRevoked, models.AggregateSession, session.ID, &userID)
+		return h.db.RecordDomainEvent(ctx, event, session)
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to revoke session"))
+	}
+
+	return c.JSON(models.SuccessResponse(fiber.Map{
+		"message": "Session revoked successfully",
+	}))
+}
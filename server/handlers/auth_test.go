@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/store"
+	"github.com/gofiber/fiber/v3"
+)
+
+func newAuthApp(t *testing.T) (*fiber.App, *store.Memory) {
+	t.Helper()
+	st := store.NewMemory()
+	h := NewAuthHandler(st, &config.Config{JWTSecret: "test-secret"})
+
+	app := fiber.New()
+	app.Post("/auth/register", h.Register)
+	app.Post("/auth/refresh", h.RefreshToken)
+	return app, st
+}
+
+func TestRefreshTokenAfterSessionPurge(t *testing.T) {
+	app, st := newAuthApp(t)
+
+	var auth models.AuthResponse
+	body := `{"email":"alice@example.com","password":"correct horse","name":"Alice"}`
+	if status := doJSON(t, app, "POST", "/auth/register", "", body, &auth); status != fiber.StatusCreated {
+		t.Fatalf("register returned %d", status)
+	}
+
+	refresh := `{"refreshToken":"` + auth.RefreshToken + `"}`
+	if status := doJSON(t, app, "POST", "/auth/refresh", "", refresh, nil); status != fiber.StatusOK {
+		t.Fatalf("refresh with a live session returned %d", status)
+	}
+
+	// What `sessions purge --user` does
+	if _, err := st.DeleteUserSessions(context.Background(), auth.User.ID); err != nil {
+		t.Fatalf("DeleteUserSessions: %v", err)
+	}
+	if status := doJSON(t, app, "POST", "/auth/refresh", "", refresh, nil); status != fiber.StatusUnauthorized {
+		t.Errorf("refresh after purging the session returned %d, want 401", status)
+	}
+}
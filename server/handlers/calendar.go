@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/middleware"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+	"github.com/gofiber/fiber/v3"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	calendarTokenBytes = 32
+	calendarProdID     = "-//Monorepo//Items Calendar//EN"
+
+	// Calendar apps poll feeds; let them cache briefly and revalidate with ETag
+	calendarCacheControl = "private, max-age=300, must-revalidate"
+)
+
+type CalendarHandler struct {
+	db     *database.DB
+	config *config.Config
+}
+
+func NewCalendarHandler(db *database.DB, cfg *config.Config) *CalendarHandler {
+	return &CalendarHandler{
+		db:     db,
+		config: cfg,
+	}
+}
+
+// GetFeedStatus reports whether the authenticated user has an active feed token
+func (h *CalendarHandler) GetFeedStatus(c fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Unauthorized"))
+	}
+
+	feed, err := h.db.GetCalendarFeed(c.Context(), userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return c.JSON(models.SuccessResponse(fiber.Map{"enabled": false}))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve calendar feed"))
+	}
+
+	return c.JSON(models.SuccessResponse(fiber.Map{
+		"enabled":   true,
+		"createdAt": feed.CreatedAt,
+	}))
+}
+
+// RegenerateFeedToken issues a new secret feed token, invalidating the previous one.
+// The feed URL is only returned here since the server stores just a hash of the token.
+func (h *CalendarHandler) RegenerateFeedToken(c fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Unauthorized"))
+	}
+
+	token, err := utils.GenerateSecureToken(calendarTokenBytes)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to generate token"))
+	}
+
+	feed := &models.CalendarFeed{
+		UserID:    userID,
+		TokenHash: utils.HashToken(token),
+	}
+	if err := h.db.UpsertCalendarFeed(c.Context(), feed); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to save calendar feed"))
+	}
+
+	url := strings.TrimSuffix(h.config.APIURL, "/") + "/api/calendar/feed/" + token + ".ics"
+	response := models.CalendarFeedResponse{
+		URL:       url,
+		WebcalURL: "webcal://" + strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://"),
+		CreatedAt: feed.CreatedAt,
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.SuccessResponse(response))
+}
+
+// RevokeFeedToken disables the authenticated user's calendar feed
+func (h *CalendarHandler) RevokeFeedToken(c fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Unauthorized"))
+	}
+
+	if err := h.db.DeleteCalendarFeed(c.Context(), userID); err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse("Calendar feed not found"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to revoke calendar feed"))
+	}
+
+	return c.JSON(models.SuccessResponse(fiber.Map{
+		"message": "Calendar feed revoked",
+	}))
+}
+
+// Feed serves the iCalendar document for a feed token. Items with a due date
+// are exported as VTODO entries, or as VEVENT entries with ?type=event.
+// Filters: ?status=active,completed and ?tag=work (repeatable or comma-separated).
+func (h *CalendarHandler) Feed(c fiber.Ctx) error {
+	token := c.Params("token")
+	if token == "" {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse("Calendar feed not found"))
+	}
+
+	feed, err := h.db.GetCalendarFeedByTokenHash(c.Context(), utils.HashToken(token))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse("Calendar feed not found"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve calendar feed"))
+	}
+
+	filter := models.ItemFilter{
+		Tags:       utils.NormalizeTags(queryList(c, "tag")),
+		HasDueDate: true,
+	}
+	for _, status := range queryList(c, "status") {
+		switch s := models.ItemStatus(strings.ToLower(status)); s {
+		case models.ItemStatusActive, models.ItemStatusCompleted, models.ItemStatusArchived:
+			filter.Statuses = append(filter.Statuses, s)
+		default:
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Invalid status filter: " + status))
+		}
+	}
+
+	asEvents := c.Query("type") == "event"
+
+	items, err := h.db.GetFilteredUserItems(c.Context(), feed.UserID, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve items"))
+	}
+
+	var lastModified time.Time
+	for _, item := range items {
+		if item.UpdatedAt.After(lastModified) {
+			lastModified = item.UpdatedAt
+		}
+	}
+
+	body := buildItemsCalendar(items, asEvents, h.config.AppHost())
+	sum := sha256.Sum256([]byte(body))
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Set(fiber.HeaderCacheControl, calendarCacheControl)
+	c.Set(fiber.HeaderETag, etag)
+	if !lastModified.IsZero() {
+		c.Set(fiber.HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="items.ics"`)
+	return c.SendString(body)
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, using the
+// weak comparison RFC 9110 requires for GET: "*", or any entry equal to etag
+// once W/ prefixes are ignored
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// buildItemsCalendar renders items as a VCALENDAR document. host qualifies
+// item UIDs and must not vary between requests, or clients see duplicates.
+func buildItemsCalendar(items []*models.Item, asEvents bool, host string) string {
+	cal := utils.NewICalendar(calendarProdID, "Items")
+	for _, item := range items {
+		if item.DueAt == nil {
+			continue
+		}
+
+		component := "VTODO"
+		if asEvents {
+			component = "VEVENT"
+		}
+
+		cal.Line("BEGIN", component)
+		cal.Line("UID", item.ID+"@"+host)
+		// DTSTAMP must be stable between requests so the ETag only changes with the data
+		cal.Time("DTSTAMP", item.UpdatedAt)
+		cal.Time("CREATED", item.CreatedAt)
+		cal.Time("LAST-MODIFIED", item.UpdatedAt)
+		cal.Line("SUMMARY", utils.EscapeICalText(item.Title))
+		if item.Description != "" {
+			cal.Line("DESCRIPTION", utils.EscapeICalText(item.Description))
+		}
+		if len(item.Tags) > 0 {
+			escaped := make([]string, len(item.Tags))
+			for i, tag := range item.Tags {
+				escaped[i] = utils.EscapeICalText(tag)
+			}
+			cal.Line("CATEGORIES", strings.Join(escaped, ","))
+		}
+
+		if asEvents {
+			// Without DTEND or DURATION the event takes no time (RFC 5545
+			// section 3.6.1); a DTEND equal to DTSTART is invalid
+			cal.Time("DTSTART", *item.DueAt)
+			if item.Status == models.ItemStatusArchived {
+				cal.Line("STATUS", "CANCELLED")
+			} else {
+				cal.Line("STATUS", "CONFIRMED")
+			}
+		} else {
+			cal.Time("DUE", *item.DueAt)
+			switch item.Status {
+			case models.ItemStatusCompleted:
+				cal.Line("STATUS", "COMPLETED")
+				cal.Time("COMPLETED", item.UpdatedAt)
+			case models.ItemStatusArchived:
+				cal.Line("STATUS", "CANCELLED")
+			default:
+				cal.Line("STATUS", "NEEDS-ACTION")
+			}
+		}
+
+		cal.Line("END", component)
+	}
+	return cal.String()
+}
+
+// queryList collects a query parameter given repeatedly and/or comma-separated
+func queryList(c fiber.Ctx, key string) []string {
+	var values []string
+	for _, raw := range c.RequestCtx().QueryArgs().PeekMulti(key) {
+		for _, v := range strings.Split(string(raw), ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/middleware"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/realtime"
+	"github.com/gofiber/fiber/v3"
+)
+
+const (
+	sseHeartbeatInterval = 15 * time.Second
+	sseRetryMillis       = 3000
+	sseReplayPageSize    = 500
+)
+
+type EventsHandler struct {
+	db     *database.DB
+	config *config.Config
+	broker *realtime.Broker
+}
+
+func NewEventsHandler(db *database.DB, cfg *config.Config, broker *realtime.Broker) *EventsHandler {
+	return &EventsHandler{
+		db:     db,
+		config: cfg,
+		broker: broker,
+	}
+}
+
+// StreamItemEvents streams the authenticated user's item changes as
+// Server-Sent Events. Clients resume with the Last-Event-ID header (or the
+// lastEventId query parameter); if the requested position is no longer in
+// the event log a "reset" event tells the client to refetch its items.
+func (h *EventsHandler) StreamItemEvents(c fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Unauthorized"))
+	}
+
+	lastID := int64(0)
+	if raw := c.Get("Last-Event-ID", c.Query("lastEventId")); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Invalid Last-Event-ID"))
+		}
+		lastID = id
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+
+	// Subscribe before replaying so no event falls between replay and live delivery
+	sub := h.broker.Subscribe(userID)
+
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		defer h.broker.Unsubscribe(sub)
+
+		// The writer outlives the handler, so it uses its own context
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		fmt.Fprintf(w, "retry: %d\n\n", sseRetryMillis)
+
+		if lastID > 0 {
+			var err error
+			if lastID, err = h.replay(ctx, w, userID, lastID); err != nil {
+				return
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(sseHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case event, ok := <-sub.Events:
+				if !ok {
+					// Dropped for falling behind or server shutdown; the client reconnects and resumes
+					return
+				}
+				if event.ID <= lastID {
+					continue
+				}
+				if err := writeSSEEvent(w, event); err != nil {
+					return
+				}
+				lastID = event.ID
+			case <-heartbeat.C:
+				if _, err := w.WriteString(": heartbeat\n\n"); err != nil {
+					return
+				}
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+}
+
+// replay writes logged events after lastID and returns the last ID written
+func (h *EventsHandler) replay(ctx context.Context, w *bufio.Writer, userID string, lastID int64) (int64, error) {
+	// Pruning is by age, so if the client's last event is still retained
+	// everything after it is too; otherwise events may have been lost
+	oldest, err := h.db.GetOldestItemEventID(ctx, userID)
+	if err != nil {
+		return lastID, err
+	}
+	if oldest == 0 || oldest > lastID {
+		_, err := fmt.Fprintf(w, "event: reset\ndata: {}\n\n")
+		return lastID, err
+	}
+
+	for {
+		events, err := h.db.GetItemEventsAfter(ctx, userID, lastID, sseReplayPageSize)
+		if err != nil {
+			return lastID, err
+		}
+		for _, event := range events {
+			if err := writeSSEEvent(w, event); err != nil {
+				return lastID, err
+			}
+			lastID = event.ID
+		}
+		if len(events) < sseReplayPageSize {
+			return lastID, nil
+		}
+	}
+}
+
+func writeSSEEvent(w *bufio.Writer, event *models.ItemEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
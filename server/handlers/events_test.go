@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+	"github.com/binduni/bun-golang-react-monorepo/server/realtime"
+	"github.com/gofiber/fiber/v3"
+)
+
+// The server ends streams before draining, so a stream that subscribes after
+// that must end by itself rather than hold the drain until its timeout
+func TestStreamItemEventsEndsAfterBrokerShutdown(t *testing.T) {
+	broker := realtime.NewBroker(nil)
+	h := NewEventsHandler(nil, &config.Config{}, broker)
+
+	app := fiber.New()
+	app.Use(func(c fiber.Ctx) error {
+		c.Locals("userID", "user_1")
+		return c.Next()
+	})
+	app.Get("/events/items", h.StreamItemEvents)
+
+	broker.Shutdown()
+	resp, err := app.Test(httptest.NewRequest("GET", "/events/items", nil), fiber.TestConfig{Timeout: time.Second, FailOnTimeout: true})
+	if err != nil {
+		t.Fatalf("stream did not end after shutdown: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusOK || !strings.HasPrefix(string(body), "retry: ") {
+		t.Errorf("stream = %d %q, want a clean end of stream", resp.StatusCode, body)
+	}
+}
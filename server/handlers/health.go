@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"github.com/binduni/bun-golang-react-monorepo/server/health"
+	"github.com/gofiber/fiber/v3"
+)
+
+// HealthHandler serves the Kubernetes probes. Each answers 200 or 503 with
+// {"status": "ok" | "unavailable"}; add ?verbose to list every check.
+type HealthHandler struct {
+	probes *health.Registry
+}
+
+func NewHealthHandler(probes *health.Registry) *HealthHandler {
+	return &HealthHandler{probes: probes}
+}
+
+// Livez reports that the process is serving requests. It checks no
+// dependencies: restarting the server cannot fix an unreachable database.
+func (h *HealthHandler) Livez(c fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// Readyz reports whether the server should receive traffic: it has started,
+// is not draining and every registered check passes
+func (h *HealthHandler) Readyz(c fiber.Ctx) error {
+	ready := h.probes.Started() && !h.probes.Draining()
+
+	// Skip the checks when the answer is already no, unless asked for them
+	var results []health.Result
+	if ready || verbose(c) {
+		var healthy bool
+		results, healthy = h.probes.Run(c.Context())
+		ready = ready && healthy
+	}
+
+	response := fiber.Map{"status": probeStatus(ready)}
+	if verbose(c) {
+		response["started"] = h.probes.Started()
+		response["draining"] = h.probes.Draining()
+		response["checks"] = results
+	}
+	if !ready {
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+	return c.JSON(response)
+}
+
+// Startupz reports whether startup has completed, so liveness and
+// readiness probes only begin once migrations and workers are up
+func (h *HealthHandler) Startupz(c fiber.Ctx) error {
+	started := h.probes.Started()
+	if !started {
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+	return c.JSON(fiber.Map{"status": probeStatus(started)})
+}
+
+func verbose(c fiber.Ctx) bool {
+	_, ok := c.Queries()["verbose"]
+	return ok
+}
+
+func probeStatus(ok bool) string {
+	if ok {
+		return "ok"
+	}
+	return "unavailable"
+}
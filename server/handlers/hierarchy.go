@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/gofiber/fiber/v3"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxItemDepth is the maximum nesting of subtasks (a top-level item has depth 0)
+const maxItemDepth = 8
+
+// errInvalidParent aborts a transaction after validateParent rejected the
+// parent; the caller responds with the status it returned
+var errInvalidParent = errors.New("invalid parent item")
+
+// ListChildren returns the direct subtasks of an item
+func (h *ItemsHandler) ListChildren(c fiber.Ctx) error {
+	item, ok := h.getOwnedItem(c)
+	if !ok {
+		return nil
+	}
+
+	children, err := h.db.GetItemChildren(c.Context(), item.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve children"))
+	}
+
+	if err := h.attachProgress(c.Context(), children); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to compute progress"))
+	}
+
+	return c.JSON(models.SuccessResponse(children))
+}
+
+// GetSubtree returns an item with all of its descendants nested as a tree
+func (h *ItemsHandler) GetSubtree(c fiber.Ctx) error {
+	item, ok := h.getOwnedItem(c)
+	if !ok {
+		return nil
+	}
+
+	nodes, err := h.db.GetItemSubtree(c.Context(), item.ID)
+	if err != nil || len(nodes) == 0 {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve subtree"))
+	}
+
+	items := make([]*models.Item, len(nodes))
+	for i, node := range nodes {
+		items[i] = node.Item
+	}
+	if err := h.attachProgress(c.Context(), items); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to compute progress"))
+	}
+
+	return c.JSON(models.SuccessResponse(buildItemTree(nodes)))
+}
+
+// MoveSubtree re-parents an item together with its subtree. A null parentId
+// makes the item top-level.
+func (h *ItemsHandler) MoveSubtree(c fiber.Ctx) error {
+	item, ok := h.getOwnedItem(c)
+	if !ok {
+		return nil
+	}
+
+	var req struct {
+		ParentID *string `json:"parentId"`
+	}
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Invalid request body"))
+	}
+	if req.ParentID != nil && *req.ParentID == "" {
+		req.ParentID = nil
+	}
+
+	// Validate and re-parent under the user's item lock so a concurrent move
+	// cannot create a cycle or exceed the depth limit in between. The moved
+	// item is read back in the transaction, so the event and the response
+	// carry the row as committed.
+	var moved *models.Item
+	var status int
+	var message string
+	err := h.db.WithTx(c.Context(), func(ctx context.Context) error {
+		status = 0
+		if err := h.db.LockUserItems(ctx, item.UserID); err != nil {
+			return err
+		}
+		if req.ParentID != nil {
+			if status, message = h.validateParent(ctx, item.UserID, *req.ParentID, item.ID); status != 0 {
+				return errInvalidParent
+			}
+		}
+		if err := h.db.SetItemParent(ctx, item.ID, req.ParentID); err != nil {
+			return err
+		}
+		var err error
+		if moved, err = h.db.GetItemByID(ctx, item.ID); err != nil {
+			return err
+		}
+		return h.recordItemEvent(ctx, models.EventItemUpdated, moved)
+	})
+	if status != 0 {
+		return c.Status(status).JSON(models.ErrorResponse(message))
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to move item"))
+	}
+
+	return c.JSON(models.SuccessResponse(moved))
+}
+
+// validateParent checks that parentID can hold itemID (empty for a new item):
+// same owner, no cycle, and the moved subtree stays within maxItemDepth.
+// It returns a zero status when the parent is acceptable. Callers hold the
+// user's item lock so the checks stay true until they commit.
+func (h *ItemsHandler) validateParent(ctx context.Context, userID, parentID, itemID string) (int, string) {
+	parent, err := h.db.GetItemByID(ctx, parentID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fiber.StatusBadRequest, "Parent item not found"
+		}
+		return fiber.StatusInternalServerError, "Failed to retrieve parent item"
+	}
+	if parent.UserID != userID {
+		return fiber.StatusBadRequest, "Parent item not found"
+	}
+
+	height := 0
+	if itemID != "" {
+		cycle, err := h.db.IsItemDescendant(ctx, itemID, parentID)
+		if err != nil {
+			return fiber.StatusInternalServerError, "Failed to validate parent item"
+		}
+		if cycle {
+			return fiber.StatusBadRequest, "An item cannot be moved under itself or its subtasks"
+		}
+		if height, err = h.db.GetItemSubtreeHeight(ctx, itemID); err != nil {
+			return fiber.StatusInternalServerError, "Failed to validate parent item"
+		}
+	}
+
+	depth, err := h.db.GetItemDepth(ctx, parentID)
+	if err != nil {
+		return fiber.StatusInternalServerError, "Failed to validate parent item"
+	}
+	if depth+1+height > maxItemDepth {
+		return fiber.StatusBadRequest, "Maximum subtask depth exceeded"
+	}
+
+	return 0, ""
+}
+
+// attachProgress fills Progress for items that have subtasks
+func (h *ItemsHandler) attachProgress(ctx context.Context, items []*models.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+
+	progress, err := h.db.GetItemsProgress(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		item.Progress = progress[item.ID]
+	}
+	return nil
+}
+
+// buildItemTree nests depth-ordered subtree nodes under their parents and returns the root
+func buildItemTree(nodes []*models.ItemNode) *models.ItemNode {
+	byID := make(map[string]*models.ItemNode, len(nodes))
+	for _, node := range nodes {
+		node.Children = []*models.ItemNode{}
+		byID[node.ID] = node
+	}
+	for _, node := range nodes[1:] {
+		if parent, ok := byID[*node.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	return nodes[0]
+}
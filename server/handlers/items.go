@@ -1,21 +1,30 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/binduni/bun-golang-react-monorepo/server/config"
-	"github.com/binduni/bun-golang-react-monorepo/server/database"
 	"github.com/binduni/bun-golang-react-monorepo/server/middleware"
 	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/outbox"
+	"github.com/binduni/bun-golang-react-monorepo/server/store"
 	"github.com/binduni/bun-golang-react-monorepo/server/utils"
 	"github.com/gofiber/fiber/v3"
 	"github.com/jackc/pgx/v5"
 )
 
+// errUpdateRejected aborts an update transaction after a check on the item
+// failed; the caller responds with the status the check set
+var errUpdateRejected = errors.New("item update rejected")
+
 type ItemsHandler struct {
-	db     *database.DB
+	db     store.Store
 	config *config.Config
 }
 
-func NewItemsHandler(db *database.DB, cfg *config.Config) *ItemsHandler {
+func NewItemsHandler(db store.Store, cfg *config.Config) *ItemsHandler {
 	return &ItemsHandler{
 		db:     db,
 		config: cfg,
@@ -34,6 +43,10 @@ func (h *ItemsHandler) ListItems(c fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve items"))
 	}
 
+	if err := h.attachProgress(c.Context(), items); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to compute progress"))
+	}
+
 	return c.JSON(models.SuccessResponse(items))
 }
 
@@ -62,6 +75,10 @@ func (h *ItemsHandler) GetItem(c fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse("Access denied"))
 	}
 
+	if err := h.attachProgress(c.Context(), []*models.Item{item}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to compute progress"))
+	}
+
 	return c.JSON(models.SuccessResponse(item))
 }
 
@@ -76,6 +93,11 @@ func (h *ItemsHandler) CreateItem(c fiber.Ctx) error {
 		Title       string            `json:"title"`
 		Description string            `json:"description"`
 		Status      models.ItemStatus `json:"status"`
+		ParentID    string            `json:"parentId"`
+		DueAt       *time.Time        `json:"dueAt"`
+		Tags        []string          `json:"tags"`
+		RRule       string            `json:"rrule"`
+		Timezone    string            `json:"timezone"`
 	}
 
 	if err := c.Bind().Body(&req); err != nil {
@@ -93,15 +115,64 @@ func (h *ItemsHandler) CreateItem(c fiber.Ctx) error {
 	}
 
 	// Create item
-	item := &models.Item{
+	draft := models.Item{
 		ID:          utils.NewItemID(),
 		UserID:      userID,
 		Title:       req.Title,
 		Description: req.Description,
 		Status:      req.Status,
+		DueAt:       req.DueAt,
+		Tags:        utils.NormalizeTags(req.Tags),
+	}
+	if req.ParentID != "" {
+		draft.ParentID = &req.ParentID
+	}
+
+	// Recurring items start a series at their due date
+	var rule *utils.RRule
+	if req.RRule != "" {
+		if req.DueAt == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Due date is required for recurring items"))
+		}
+		var err error
+		if rule, _, err = validateRecurrence(req.RRule, req.Timezone); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse(err.Error()))
+		}
 	}
 
-	if err := h.db.CreateItem(c.Context(), item); err != nil {
+	var item *models.Item
+	var status int
+	var message string
+	err := h.db.WithTx(c.Context(), func(ctx context.Context) error {
+		// Start from the request on every attempt, since a retried
+		// transaction must not reuse the series or rank of a rolled-back one
+		created := draft
+		item = &created
+
+		// Subtasks must reference an owned parent within the depth limit,
+		// checked under the user's item lock so a concurrent move can't void it
+		if req.ParentID != "" {
+			if err := h.db.LockUserItems(ctx, userID); err != nil {
+				return err
+			}
+			if status, message = h.validateParent(ctx, userID, req.ParentID, ""); status != 0 {
+				return errInvalidParent
+			}
+		}
+		if rule != nil {
+			if err := h.startSeries(ctx, item, rule, req.Timezone); err != nil {
+				return err
+			}
+		}
+		return h.createItem(ctx, item)
+	})
+	if status != 0 {
+		return c.Status(status).JSON(models.ErrorResponse(message))
+	}
+	if err != nil {
+		if rule != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to create series"))
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to create item"))
 	}
 
@@ -120,43 +191,162 @@ func (h *ItemsHandler) UpdateItem(c fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Item ID is required"))
 	}
 
-	// Get existing item
-	item, err := h.db.GetItemByID(c.Context(), itemID)
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse("Item not found"))
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve item"))
-	}
-
-	// Verify ownership
-	if item.UserID != userID {
-		return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse("Access denied"))
-	}
-
 	var req struct {
 		Title       string            `json:"title"`
 		Description string            `json:"description"`
 		Status      models.ItemStatus `json:"status"`
+		DueAt       *time.Time        `json:"dueAt"`
+		Tags        []string          `json:"tags"`     // [] clears, omitted keeps
+		RRule       *string           `json:"rrule"`    // "" ends the series (scope=future)
+		Timezone    *string           `json:"timezone"` // IANA timezone for the series
 	}
 
 	if err := c.Bind().Body(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Invalid request body"))
 	}
 
-	// Update fields
-	if req.Title != "" {
-		item.Title = req.Title
+	// Recurring items can be edited for this occurrence only or for this and all future ones
+	scope := models.ItemEditScope(c.Query("scope", string(models.ItemEditScopeThis)))
+	if scope != models.ItemEditScopeThis && scope != models.ItemEditScopeFuture {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Scope must be 'this' or 'future'"))
 	}
-	if req.Description != "" {
-		item.Description = req.Description
+	if req.Timezone != nil {
+		if _, err := utils.ValidateTimezone(*req.Timezone); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse(err.Error()))
+		}
 	}
-	if req.Status != "" {
-		item.Status = req.Status
+	var newRule *utils.RRule
+	if req.RRule != nil && *req.RRule != "" {
+		var err error
+		if newRule, err = utils.ParseRRule(*req.RRule); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Invalid recurrence rule: " + err.Error()))
+		}
 	}
 
-	if err := h.db.UpdateItem(c.Context(), item); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to update item"))
+	// The item is read inside the transaction, which is serializable so an
+	// edit that commits in between makes it retry from the committed row
+	// instead of overwriting that edit. Series changes, the item update and
+	// the next occurrence commit together; failMessage names the step that
+	// failed.
+	var item *models.Item
+	var status int
+	var message, failMessage string
+	err := h.db.WithTx(store.Serializable(c.Context()), func(ctx context.Context) error {
+		status = 0
+		failMessage = "Failed to retrieve item"
+		var err error
+		if item, err = h.db.GetItemByID(ctx, itemID); err != nil {
+			if err == pgx.ErrNoRows {
+				status, message = fiber.StatusNotFound, "Item not found"
+			}
+			return err
+		}
+
+		// Verify ownership
+		if item.UserID != userID {
+			status, message = fiber.StatusForbidden, "Access denied"
+			return errUpdateRejected
+		}
+
+		wasCompleted := item.Status == models.ItemStatusCompleted
+
+		// Update fields
+		if req.Title != "" {
+			item.Title = req.Title
+		}
+		if req.Description != "" {
+			item.Description = req.Description
+		}
+		if req.Status != "" {
+			item.Status = req.Status
+		}
+		if req.DueAt != nil {
+			item.DueAt = req.DueAt
+		}
+		if req.Tags != nil {
+			item.Tags = utils.NormalizeTags(req.Tags)
+		}
+
+		var startRule *utils.RRule
+		var timezone string
+		if item.SeriesID != nil {
+			// Only a "this and all future" edit may change an existing recurrence
+			if scope != models.ItemEditScopeFuture && (req.RRule != nil || req.Timezone != nil) {
+				status, message = fiber.StatusBadRequest, "Changing the recurrence requires scope=future"
+				return errUpdateRejected
+			}
+		} else if newRule != nil {
+			// Turn a one-off item into the first occurrence of a new series
+			if item.DueAt == nil {
+				status, message = fiber.StatusBadRequest, "Due date is required for recurring items"
+				return errUpdateRejected
+			}
+			startRule = newRule
+			if req.Timezone != nil {
+				timezone = *req.Timezone
+			}
+		}
+
+		// Completing an occurrence schedules the next one. Concurrent
+		// completions queue on the series row, and only the first still finds
+		// the occurrence incomplete.
+		completing := !wasCompleted && item.Status == models.ItemStatusCompleted && item.SeriesID != nil
+		if completing {
+			failMessage = "Failed to update item"
+			if err := h.db.LockItemSeries(ctx, *item.SeriesID); err != nil {
+				return err
+			}
+			current, err := h.db.GetItemByID(ctx, item.ID)
+			if err != nil {
+				return err
+			}
+			completing = current.Status != models.ItemStatusCompleted
+		}
+
+		if item.SeriesID != nil && scope == models.ItemEditScopeFuture {
+			edit := seriesEdit{
+				title:       req.Title,
+				description: req.Description,
+				rrule:       req.RRule,
+				timezone:    req.Timezone,
+			}
+			failMessage = "Failed to update series"
+			if err := h.editFutureOccurrences(ctx, item, edit); err != nil {
+				return err
+			}
+		}
+		if startRule != nil {
+			failMessage = "Failed to create series"
+			if err := h.startSeries(ctx, item, startRule, timezone); err != nil {
+				return err
+			}
+		}
+
+		failMessage = "Failed to update item"
+		if err := h.db.UpdateItem(ctx, item); err != nil {
+			return err
+		}
+		if err := h.recordItemEvent(ctx, models.EventItemUpdated, item); err != nil {
+			return err
+		}
+
+		if completing {
+			failMessage = "Failed to create next occurrence"
+			next, err := h.createNextOccurrence(ctx, item)
+			if err != nil {
+				return err
+			}
+			if next != nil {
+				return h.recordItemEvent(ctx, models.EventItemCreated, next)
+			}
+		}
+		return nil
+	})
+	if status != 0 {
+		return c.Status(status).JSON(models.ErrorResponse(message))
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse(failMessage))
 	}
 
 	return c.JSON(models.SuccessResponse(item))
@@ -188,7 +378,26 @@ func (h *ItemsHandler) DeleteItem(c fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse("Access denied"))
 	}
 
-	if err := h.db.DeleteItem(c.Context(), itemID); err != nil {
+	// Subtasks go with the item. Under the user's item lock the subtree can't
+	// change before it is deleted, so every removed item gets its own event.
+	if err := h.db.WithTx(c.Context(), func(ctx context.Context) error {
+		if err := h.db.LockUserItems(ctx, userID); err != nil {
+			return err
+		}
+		nodes, err := h.db.GetItemSubtree(ctx, itemID)
+		if err != nil {
+			return err
+		}
+		if err := h.db.DeleteItem(ctx, itemID); err != nil {
+			return err
+		}
+		for _, node := range nodes {
+			if err := h.recordItemEvent(ctx, models.EventItemDeleted, node.Item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to delete item"))
 	}
 
@@ -196,3 +405,67 @@ func (h *ItemsHandler) DeleteItem(c fiber.Ctx) error {
 		"message": "Item deleted successfully",
 	}))
 }
+
+// MoveItem reorders an item directly before or after another of the user's items
+func (h *ItemsHandler) MoveItem(c fiber.Ctx) error {
+	item, ok := h.getOwnedItem(c)
+	if !ok {
+		return nil
+	}
+
+	var req struct {
+		BeforeID string `json:"beforeId"`
+		AfterID  string `json:"afterId"`
+	}
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Invalid request body"))
+	}
+
+	if (req.BeforeID == "") == (req.AfterID == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Exactly one of beforeId or afterId is required"))
+	}
+	anchorID, before := req.AfterID, false
+	if req.BeforeID != "" {
+		anchorID, before = req.BeforeID, true
+	}
+	if anchorID == item.ID {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("An item cannot be moved relative to itself"))
+	}
+
+	// The moved item is read back in the transaction, so the event and the
+	// response carry the new rank and any change committed in between
+	var moved *models.Item
+	err := h.db.WithTx(c.Context(), func(ctx context.Context) error {
+		if _, err := h.db.MoveItem(ctx, item.UserID, item.ID, anchorID, before); err != nil {
+			return err
+		}
+		var err error
+		if moved, err = h.db.GetItemByID(ctx, item.ID); err != nil {
+			return err
+		}
+		return h.recordItemEvent(ctx, models.EventItemUpdated, moved)
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse("Target item not found"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to move item"))
+	}
+
+	return c.JSON(models.SuccessResponse(moved))
+}
+
+// createItem inserts an item and records its item.created event
+func (h *ItemsHandler) createItem(ctx context.Context, item *models.Item) error {
+	if err := h.db.CreateItem(ctx, item); err != nil {
+		return err
+	}
+	return h.recordItemEvent(ctx, models.EventItemCreated, item)
+}
+
+// recordItemEvent writes an item change to the outbox. Call it inside the
+// transaction making the change.
+func (h *ItemsHandler) recordItemEvent(ctx context.Context, eventType models.DomainEventType, item *models.Item) error {
+	userID := item.UserID
+	return h.db.RecordDomainEvent(ctx, outbox.NewEvent(eventType, models.AggregateItem, item.ID, &userID), item)
+}
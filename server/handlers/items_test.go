@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/store"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+	"github.com/gofiber/fiber/v3"
+)
+
+// newItemsApp serves the items handlers on a Memory store. Requests are
+// authenticated as the user named by the X-User-Id header.
+func newItemsApp(t *testing.T) (*fiber.App, *store.Memory) {
+	t.Helper()
+	st := store.NewMemory()
+	h := NewItemsHandler(st, &config.Config{})
+
+	app := fiber.New()
+	app.Use(func(c fiber.Ctx) error {
+		// Header values alias fasthttp's buffers, which are reused
+		if id := c.Get("X-User-Id"); id != "" {
+			c.Locals("userID", strings.Clone(id))
+		}
+		return c.Next()
+	})
+	app.Get("/items/:id", h.GetItem)
+	app.Post("/items", h.CreateItem)
+	app.Put("/items/:id", h.UpdateItem)
+	app.Delete("/items/:id", h.DeleteItem)
+	app.Post("/items/:id/move", h.MoveItem)
+	app.Put("/items/:id/parent", h.MoveSubtree)
+	return app, st
+}
+
+func newTestUser(t *testing.T, st store.Store) string {
+	t.Helper()
+	id := utils.NewUserID()
+	user := &models.User{ID: id, Email: id + "@example.com", Name: "Test", Role: models.RoleUser}
+	if err := st.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return id
+}
+
+// doJSON sends a request as userID and decodes the response's data into out
+func doJSON(t *testing.T, app *fiber.App, method, path, userID, body string, out any) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-Id", userID)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode < 300 {
+		raw, _ := io.ReadAll(resp.Body)
+		envelope := models.ApiResponse[json.RawMessage]{}
+		if err := json.Unmarshal(raw, &envelope); err != nil || envelope.Data == nil {
+			t.Fatalf("%s %s: unexpected body %s", method, path, raw)
+		}
+		if err := json.Unmarshal(*envelope.Data, out); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// lastItemEvent decodes the item in the latest event of eventType about id
+func lastItemEvent(t *testing.T, st *store.Memory, eventType models.DomainEventType, id string) *models.Item {
+	t.Helper()
+	events := st.DomainEvents()
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Type == eventType && events[i].AggregateID == id {
+			var item models.Item
+			if err := json.Unmarshal(events[i].Payload, &item); err != nil {
+				t.Fatalf("decode %s payload: %v", eventType, err)
+			}
+			return &item
+		}
+	}
+	t.Fatalf("no %s event for %s", eventType, id)
+	return nil
+}
+
+func TestItemOwnership(t *testing.T) {
+	app, st := newItemsApp(t)
+	alice, bob := newTestUser(t, st), newTestUser(t, st)
+
+	var item models.Item
+	if status := doJSON(t, app, "POST", "/items", alice, `{"title":"Groceries"}`, &item); status != fiber.StatusCreated {
+		t.Fatalf("create returned %d", status)
+	}
+	if item.UserID != alice || item.Status != models.ItemStatusActive {
+		t.Errorf("created item = %+v, want an active item owned by alice", item)
+	}
+
+	path := "/items/" + item.ID
+	if status := doJSON(t, app, "GET", path, bob, "", nil); status != fiber.StatusForbidden {
+		t.Errorf("GET as another user returned %d, want 403", status)
+	}
+	if status := doJSON(t, app, "PUT", path, bob, `{"title":"Mine now"}`, nil); status != fiber.StatusForbidden {
+		t.Errorf("PUT as another user returned %d, want 403", status)
+	}
+	if status := doJSON(t, app, "DELETE", path, bob, "", nil); status != fiber.StatusForbidden {
+		t.Errorf("DELETE as another user returned %d, want 403", status)
+	}
+
+	var updated models.Item
+	if status := doJSON(t, app, "PUT", path, alice, `{"title":"Weekly groceries"}`, &updated); status != fiber.StatusOK {
+		t.Fatalf("PUT as owner returned %d", status)
+	}
+	if updated.Title != "Weekly groceries" {
+		t.Errorf("updated title = %q", updated.Title)
+	}
+	if status := doJSON(t, app, "PUT", "/items/"+utils.NewItemID(), alice, `{"title":"x"}`, nil); status != fiber.StatusNotFound {
+		t.Errorf("PUT of a missing item returned %d, want 404", status)
+	}
+}
+
+func TestCreateSubtaskRequiresOwnedParent(t *testing.T) {
+	app, st := newItemsApp(t)
+	alice, bob := newTestUser(t, st), newTestUser(t, st)
+
+	var parent models.Item
+	if status := doJSON(t, app, "POST", "/items", alice, `{"title":"Trip"}`, &parent); status != fiber.StatusCreated {
+		t.Fatalf("create returned %d", status)
+	}
+
+	body := `{"title":"Pack","parentId":"` + parent.ID + `"}`
+	if status := doJSON(t, app, "POST", "/items", bob, body, nil); status < 400 {
+		t.Errorf("subtask of another user's item returned %d, want an error", status)
+	}
+
+	var child models.Item
+	if status := doJSON(t, app, "POST", "/items", alice, body, &child); status != fiber.StatusCreated {
+		t.Fatalf("subtask create returned %d", status)
+	}
+	if child.ParentID == nil || *child.ParentID != parent.ID {
+		t.Errorf("subtask parent = %v, want %s", child.ParentID, parent.ID)
+	}
+}
+
+func TestDeleteItemRecordsEventPerDescendant(t *testing.T) {
+	app, st := newItemsApp(t)
+	alice := newTestUser(t, st)
+
+	var root, child, grandchild, other models.Item
+	doJSON(t, app, "POST", "/items", alice, `{"title":"Trip"}`, &root)
+	doJSON(t, app, "POST", "/items", alice, `{"title":"Pack","parentId":"`+root.ID+`"}`, &child)
+	doJSON(t, app, "POST", "/items", alice, `{"title":"Socks","parentId":"`+child.ID+`"}`, &grandchild)
+	doJSON(t, app, "POST", "/items", alice, `{"title":"Groceries"}`, &other)
+
+	if status := doJSON(t, app, "DELETE", "/items/"+root.ID, alice, "", nil); status != fiber.StatusOK {
+		t.Fatalf("delete returned %d", status)
+	}
+
+	var deleted []string
+	for _, event := range st.DomainEvents() {
+		if event.Type == models.EventItemDeleted {
+			deleted = append(deleted, event.AggregateID)
+		}
+	}
+	slices.Sort(deleted)
+	want := []string{root.ID, child.ID, grandchild.ID}
+	slices.Sort(want)
+	if !slices.Equal(deleted, want) {
+		t.Errorf("item.deleted events for %v, want %v", deleted, want)
+	}
+}
+
+func TestMoveSubtreeReturnsCommittedItem(t *testing.T) {
+	app, st := newItemsApp(t)
+	alice := newTestUser(t, st)
+
+	var parent, child models.Item
+	doJSON(t, app, "POST", "/items", alice, `{"title":"Trip"}`, &parent)
+	doJSON(t, app, "POST", "/items", alice, `{"title":"Pack"}`, &child)
+
+	var moved models.Item
+	body := `{"parentId":"` + parent.ID + `"}`
+	if status := doJSON(t, app, "PUT", "/items/"+child.ID+"/parent", alice, body, &moved); status != fiber.StatusOK {
+		t.Fatalf("move returned %d", status)
+	}
+	if moved.ParentID == nil || *moved.ParentID != parent.ID || !moved.UpdatedAt.After(child.UpdatedAt) {
+		t.Errorf("moved item = %+v, want the committed row under %s", moved, parent.ID)
+	}
+
+	event := lastItemEvent(t, st, models.EventItemUpdated, child.ID)
+	if !event.UpdatedAt.Equal(moved.UpdatedAt) || event.ParentID == nil || *event.ParentID != parent.ID {
+		t.Errorf("item.updated payload = %+v, want the committed row", event)
+	}
+}
+
+func TestMoveItemRecordsCommittedItem(t *testing.T) {
+	app, st := newItemsApp(t)
+	alice := newTestUser(t, st)
+
+	var first, second models.Item
+	doJSON(t, app, "POST", "/items", alice, `{"title":"Groceries"}`, &first)
+	doJSON(t, app, "POST", "/items", alice, `{"title":"Laundry"}`, &second)
+
+	// New items go on top, so this moves the older item back above the newer one
+	var moved models.Item
+	body := `{"beforeId":"` + second.ID + `"}`
+	if status := doJSON(t, app, "POST", "/items/"+first.ID+"/move", alice, body, &moved); status != fiber.StatusOK {
+		t.Fatalf("move returned %d", status)
+	}
+	if moved.Rank >= second.Rank || !moved.UpdatedAt.After(first.UpdatedAt) {
+		t.Errorf("moved item = %+v, want the committed row ranked before %q", moved, second.Rank)
+	}
+
+	event := lastItemEvent(t, st, models.EventItemUpdated, first.ID)
+	if event.Rank != moved.Rank || !event.UpdatedAt.Equal(moved.UpdatedAt) {
+		t.Errorf("item.updated payload = %+v, want the committed row", event)
+	}
+}
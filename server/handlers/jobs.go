@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/jobs"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/gofiber/fiber/v3"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultJobListLimit = 50
+	maxJobListLimit     = 500
+)
+
+// JobsHandler serves the admin API for the background job queue
+type JobsHandler struct {
+	db     *database.DB
+	config *config.Config
+	queue  *jobs.Queue
+}
+
+func NewJobsHandler(db *database.DB, cfg *config.Config, queue *jobs.Queue) *JobsHandler {
+	return &JobsHandler{
+		db:     db,
+		config: cfg,
+		queue:  queue,
+	}
+}
+
+// ListJobs returns recent jobs, filtered by ?status= and ?kind=
+func (h *JobsHandler) ListJobs(c fiber.Ctx) error {
+	var status *models.JobStatus
+	if raw := c.Query("status"); raw != "" {
+		s := models.JobStatus(raw)
+		switch s {
+		case models.JobStatusPending, models.JobStatusRunning, models.JobStatusCompleted,
+			models.JobStatusFailed, models.JobStatusCancelled:
+			status = &s
+		default:
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Invalid status"))
+		}
+	}
+	var kind *string
+	if raw := c.Query("kind"); raw != "" {
+		kind = &raw
+	}
+
+	limit := defaultJobListLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxJobListLimit {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Limit must be between 1 and 500"))
+		}
+		limit = n
+	}
+
+	list, err := h.db.ListJobs(c.Context(), status, kind, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve jobs"))
+	}
+
+	return c.JSON(models.SuccessResponse(list))
+}
+
+// GetStats returns job counts per kind and status, plus the kinds this
+// instance runs
+func (h *JobsHandler) GetStats(c fiber.Ctx) error {
+	stats, err := h.db.GetJobStats(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve job stats"))
+	}
+
+	return c.JSON(models.SuccessResponse(fiber.Map{
+		"kinds":  h.queue.Kinds(),
+		"counts": stats,
+	}))
+}
+
+// GetJob returns a single job
+func (h *JobsHandler) GetJob(c fiber.Ctx) error {
+	job, err := h.db.GetJob(c.Context(), c.Params("id"))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse("Job not found"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve job"))
+	}
+
+	return c.JSON(models.SuccessResponse(job))
+}
+
+// EnqueueJob queues a job of a registered kind
+func (h *JobsHandler) EnqueueJob(c fiber.Ctx) error {
+	var req struct {
+		Kind        string          `json:"kind"`
+		Args        json.RawMessage `json:"args"`
+		Priority    int             `json:"priority"`
+		RunAt       *time.Time      `json:"runAt"`
+		UniqueKey   string          `json:"uniqueKey"`
+		MaxAttempts int             `json:"maxAttempts"`
+	}
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Invalid request body"))
+	}
+
+	if !slices.Contains(h.queue.Kinds(), req.Kind) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Unknown job kind"))
+	}
+	if len(req.Args) == 0 {
+		req.Args = json.RawMessage(`{}`)
+	}
+	if req.MaxAttempts < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("maxAttempts must not be negative"))
+	}
+
+	opts := &jobs.InsertOpts{
+		Priority:    req.Priority,
+		UniqueKey:   req.UniqueKey,
+		MaxAttempts: req.MaxAttempts,
+	}
+	if req.RunAt != nil {
+		opts.RunAt = *req.RunAt
+	}
+
+	job, inserted, err := jobs.InsertRaw(c.Context(), h.db, req.Kind, req.Args, opts)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to queue job"))
+	}
+	if !inserted {
+		// A job with this unique key is already pending or running
+		return c.JSON(models.SuccessResponse(job))
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.SuccessResponse(job))
+}
+
+// RetryJob re-queues a failed or cancelled job, or runs a pending one now
+func (h *JobsHandler) RetryJob(c fiber.Ctx) error {
+	job, err := h.db.RetryJob(c.Context(), c.Params("id"))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse("Job not found or not retryable"))
+		}
+		if err == database.ErrDuplicateJob {
+			return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse("Another job with this unique key is pending or running"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retry job"))
+	}
+
+	return c.JSON(models.SuccessResponse(job))
+}
+
+// CancelJob cancels a pending or running job
+func (h *JobsHandler) CancelJob(c fiber.Ctx) error {
+	job, err := h.db.CancelJob(c.Context(), c.Params("id"))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse("Job not found or already finished"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to cancel job"))
+	}
+
+	return c.JSON(models.SuccessResponse(job))
+}
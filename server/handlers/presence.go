@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/middleware"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/realtime"
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v3"
+	"github.com/jackc/pgx/v5"
+	"github.com/valyala/fasthttp"
+)
+
+type PresenceHandler struct {
+	db       *database.DB
+	config   *config.Config
+	hub      *realtime.PresenceHub
+	upgrader websocket.FastHTTPUpgrader
+}
+
+func NewPresenceHandler(db *database.DB, cfg *config.Config, hub *realtime.PresenceHub) *PresenceHandler {
+	allowedOrigins := cfg.GetAllowedOrigins()
+	return &PresenceHandler{
+		db:     db,
+		config: cfg,
+		hub:    hub,
+		upgrader: websocket.FastHTTPUpgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			// Browsers always send Origin; non-browser clients may omit it
+			CheckOrigin: func(ctx *fasthttp.RequestCtx) bool {
+				origin := string(ctx.Request.Header.Peek("Origin"))
+				return origin == "" || slices.Contains(allowedOrigins, origin)
+			},
+		},
+	}
+}
+
+// Connect upgrades the request to a presence WebSocket for the authenticated user
+func (h *PresenceHandler) Connect(c fiber.Ctx) error {
+	if !websocket.FastHTTPIsWebSocketUpgrade(c.RequestCtx()) {
+		return c.Status(fiber.StatusUpgradeRequired).JSON(models.ErrorResponse("WebSocket upgrade required"))
+	}
+
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Unauthorized"))
+	}
+
+	user, err := h.db.GetUserByID(c.Context(), userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("User not found"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve user"))
+	}
+
+	// The connection runs after this handler returns, so only plain values are captured
+	return h.upgrader.Upgrade(c.RequestCtx(), func(conn *websocket.Conn) {
+		h.hub.Serve(conn, user.ID, user.Name)
+	})
+}
+
+// ItemPresenceAuthorizer allows users to join presence only for items they own
+func ItemPresenceAuthorizer(db *database.DB) realtime.AuthorizeFunc {
+	return func(ctx context.Context, userID, itemID string) error {
+		item, err := db.GetItemByID(ctx, itemID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return realtime.ErrItemNotFound
+			}
+			return err
+		}
+		if item.UserID != userID {
+			return realtime.ErrItemNotFound
+		}
+		return nil
+	}
+}
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/middleware"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+	"github.com/gofiber/fiber/v3"
+	"github.com/jackc/pgx/v5"
+)
+
+// errOccurrenceClosed aborts a transaction after the occurrence turned out to
+// be completed, archived or detached from its series
+var errOccurrenceClosed = errors.New("occurrence is not open")
+
+// GetItemSeries returns the recurrence template of a recurring item
+func (h *ItemsHandler) GetItemSeries(c fiber.Ctx) error {
+	item, ok := h.getOwnedItem(c)
+	if !ok {
+		return nil
+	}
+
+	if item.SeriesID == nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse("Item is not recurring"))
+	}
+
+	series, err := h.db.GetItemSeriesByID(c.Context(), *item.SeriesID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse("Series not found"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve series"))
+	}
+
+	return c.JSON(models.SuccessResponse(series))
+}
+
+// SkipOccurrence skips the current occurrence of a recurring item and moves it
+// to the next scheduled date. The item is archived when the series has ended.
+func (h *ItemsHandler) SkipOccurrence(c fiber.Ctx) error {
+	item, ok := h.getOwnedItem(c)
+	if !ok {
+		return nil
+	}
+
+	if item.SeriesID == nil || item.RecurrenceID == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Item is not recurring"))
+	}
+
+	// The series and occurrence are re-read under the series lock, which
+	// completing an occurrence also takes, so neither can change in between
+	var status int
+	var message string
+	err := h.db.WithTx(c.Context(), func(ctx context.Context) error {
+		status = 0
+		if err := h.db.LockItemSeries(ctx, *item.SeriesID); err != nil {
+			return err
+		}
+		current, err := h.db.GetItemByID(ctx, item.ID)
+		if err != nil {
+			return err
+		}
+		if current.SeriesID == nil || *current.SeriesID != *item.SeriesID || current.RecurrenceID == nil {
+			status, message = fiber.StatusConflict, "Item is no longer part of this series"
+			return errOccurrenceClosed
+		}
+		if current.Status == models.ItemStatusCompleted || current.Status == models.ItemStatusArchived {
+			status, message = fiber.StatusConflict, "Only open occurrences can be skipped"
+			return errOccurrenceClosed
+		}
+
+		series, err := h.db.GetItemSeriesByID(ctx, *current.SeriesID)
+		if err != nil {
+			return err
+		}
+		rule, loc, err := parseSeriesRule(series)
+		if err != nil {
+			return err
+		}
+
+		series.ExDates = append(series.ExDates, *current.RecurrenceID)
+		next, ok := rule.Next(series.DTStart, loc, *current.RecurrenceID, series.ExDates)
+		if ok {
+			current.Title = series.Title
+			current.Description = series.Description
+			current.DueAt = &next.Time
+			current.RecurrenceID = &next.Time
+		} else {
+			current.Status = models.ItemStatusArchived
+		}
+
+		if err := h.db.UpdateItemSeries(ctx, series); err != nil {
+			return err
+		}
+		if err := h.db.UpdateItem(ctx, current); err != nil {
+			return err
+		}
+		item = current
+		return h.recordItemEvent(ctx, models.EventItemUpdated, current)
+	})
+	if status != 0 {
+		return c.Status(status).JSON(models.ErrorResponse(message))
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to update item"))
+	}
+
+	return c.JSON(models.SuccessResponse(item))
+}
+
+// getOwnedItem loads the item named by the :id param and verifies the
+// authenticated user owns it. When ok is false the error response has
+// already been written.
+func (h *ItemsHandler) getOwnedItem(c fiber.Ctx) (item *models.Item, ok bool) {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Unauthorized"))
+		return nil, false
+	}
+
+	itemID := c.Params("id")
+	if itemID == "" {
+		c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Item ID is required"))
+		return nil, false
+	}
+
+	item, err := h.db.GetItemByID(c.Context(), itemID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse("Item not found"))
+			return nil, false
+		}
+		c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve item"))
+		return nil, false
+	}
+
+	if item.UserID != userID {
+		c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse("Access denied"))
+		return nil, false
+	}
+
+	return item, true
+}
+
+// validateRecurrence parses an RRULE and timezone supplied by a client
+func validateRecurrence(rrule, timezone string) (*utils.RRule, *time.Location, error) {
+	rule, err := utils.ParseRRule(rrule)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid recurrence rule: %w", err)
+	}
+	loc, err := utils.ValidateTimezone(timezone)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rule, loc, nil
+}
+
+func parseSeriesRule(series *models.ItemSeries) (*utils.RRule, *time.Location, error) {
+	return validateRecurrence(series.RRule, series.Timezone)
+}
+
+// startSeries makes item the first occurrence of a new series starting at its due date
+func (h *ItemsHandler) startSeries(ctx context.Context, item *models.Item, rule *utils.RRule, timezone string) error {
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	series := &models.ItemSeries{
+		ID:          utils.NewItemSeriesID(),
+		UserID:      item.UserID,
+		Title:       item.Title,
+		Description: item.Description,
+		RRule:       rule.String(),
+		Timezone:    timezone,
+		DTStart:     *item.DueAt,
+	}
+	if err := h.db.CreateItemSeries(ctx, series); err != nil {
+		return err
+	}
+
+	item.SeriesID = &series.ID
+	item.RecurrenceID = item.DueAt
+	return nil
+}
+
+// createNextOccurrence generates the occurrence following item in its series.
+// It returns nil when the series has ended.
+func (h *ItemsHandler) createNextOccurrence(ctx context.Context, item *models.Item) (*models.Item, error) {
+	series, err := h.db.GetItemSeriesByID(ctx, *item.SeriesID)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, loc, err := parseSeriesRule(series)
+	if err != nil {
+		return nil, err
+	}
+
+	after := series.DTStart
+	if item.RecurrenceID != nil {
+		after = *item.RecurrenceID
+	}
+	next, ok := rule.Next(series.DTStart, loc, after, series.ExDates)
+	if !ok {
+		return nil, nil
+	}
+
+	nextItem := &models.Item{
+		ID:           utils.NewItemID(),
+		UserID:       item.UserID,
+		Title:        series.Title,
+		Description:  series.Description,
+		Status:       models.ItemStatusActive,
+		ParentID:     item.ParentID,
+		DueAt:        &next.Time,
+		Tags:         item.Tags,
+		SeriesID:     &series.ID,
+		RecurrenceID: &next.Time,
+	}
+	if err := h.db.CreateItem(ctx, nextItem); err != nil {
+		return nil, err
+	}
+	return nextItem, nil
+}
+
+// seriesEdit carries the template changes of a "this and all future" edit
+type seriesEdit struct {
+	title       string
+	description string
+	rrule       *string
+	timezone    *string
+}
+
+// editFutureOccurrences applies edit to item and every later occurrence. When
+// item is not the first occurrence the series is split: the original series is
+// ended just before item and a new series starts at item's due date.
+func (h *ItemsHandler) editFutureOccurrences(ctx context.Context, item *models.Item, edit seriesEdit) error {
+	series, err := h.db.GetItemSeriesByID(ctx, *item.SeriesID)
+	if err != nil {
+		return err
+	}
+
+	rule, loc, err := parseSeriesRule(series)
+	if err != nil {
+		return err
+	}
+
+	// Ending the series: this occurrence becomes the last one
+	if edit.rrule != nil && *edit.rrule == "" {
+		rule.SetUntil(*item.RecurrenceID)
+		series.RRule = rule.String()
+		return h.db.UpdateItemSeries(ctx, series)
+	}
+
+	newRule := *rule
+	if edit.rrule != nil {
+		parsed, err := utils.ParseRRule(*edit.rrule)
+		if err != nil {
+			return err
+		}
+		newRule = *parsed
+	} else if rule.Count > 0 {
+		// Keep the total number of occurrences when carrying COUNT into the new series
+		if index := rule.IndexOf(series.DTStart, loc, *item.RecurrenceID); index > 1 {
+			newRule.Count = rule.Count - index + 1
+		}
+	}
+
+	timezone := series.Timezone
+	if edit.timezone != nil {
+		timezone = *edit.timezone
+	}
+	title, description := series.Title, series.Description
+	if edit.title != "" {
+		title = edit.title
+	}
+	if edit.description != "" {
+		description = edit.description
+	}
+
+	// First occurrence: the whole series changes, no split needed
+	if item.RecurrenceID.Equal(series.DTStart) {
+		series.Title = title
+		series.Description = description
+		series.RRule = newRule.String()
+		series.Timezone = timezone
+		series.DTStart = *item.DueAt
+		item.RecurrenceID = item.DueAt
+		return h.db.UpdateItemSeries(ctx, series)
+	}
+
+	// Skipped occurrences are split at this occurrence's original time, which
+	// is where the rule is cut, not at its due date, which may have been moved
+	var before, after []time.Time
+	for _, t := range series.ExDates {
+		if t.Before(*item.RecurrenceID) {
+			before = append(before, t)
+		} else {
+			after = append(after, t)
+		}
+	}
+
+	rule.SetUntil(item.RecurrenceID.Add(-time.Second))
+	series.RRule = rule.String()
+	series.ExDates = before
+	if err := h.db.UpdateItemSeries(ctx, series); err != nil {
+		return err
+	}
+
+	split := &models.ItemSeries{
+		ID:          utils.NewItemSeriesID(),
+		UserID:      series.UserID,
+		Title:       title,
+		Description: description,
+		RRule:       newRule.String(),
+		Timezone:    timezone,
+		DTStart:     *item.DueAt,
+		ExDates:     after,
+	}
+	if err := h.db.CreateItemSeries(ctx, split); err != nil {
+		return err
+	}
+
+	item.SeriesID = &split.ID
+	item.RecurrenceID = item.DueAt
+	return nil
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/store"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+)
+
+func TestEditFutureOccurrencesSplitsExDatesAtRecurrenceID(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemory()
+	h := NewItemsHandler(st, &config.Config{})
+	alice := newTestUser(t, st)
+
+	day := func(n int) time.Time { return time.Date(2026, 1, 1+n, 9, 0, 0, 0, time.UTC) }
+	series := &models.ItemSeries{
+		ID: utils.NewItemSeriesID(), UserID: alice, Title: "Standup",
+		RRule: "FREQ=DAILY", Timezone: "UTC", DTStart: day(0),
+		ExDates: []time.Time{day(1), day(3), day(5)},
+	}
+	if err := st.CreateItemSeries(ctx, series); err != nil {
+		t.Fatalf("CreateItemSeries: %v", err)
+	}
+
+	// The occurrence originally on day 4 was moved back to day 2, before the
+	// skipped day 3
+	recurrenceID, dueAt := day(4), day(2)
+	item := &models.Item{
+		ID: utils.NewItemID(), UserID: alice, Title: "Standup", Status: models.ItemStatusActive,
+		DueAt: &dueAt, SeriesID: &series.ID, RecurrenceID: &recurrenceID,
+	}
+	if err := st.CreateItem(ctx, item); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+
+	if err := h.editFutureOccurrences(ctx, item, seriesEdit{title: "Sync"}); err != nil {
+		t.Fatalf("editFutureOccurrences: %v", err)
+	}
+
+	old, err := st.GetItemSeriesByID(ctx, series.ID)
+	if err != nil {
+		t.Fatalf("GetItemSeriesByID(old): %v", err)
+	}
+	split, err := st.GetItemSeriesByID(ctx, *item.SeriesID)
+	if err != nil {
+		t.Fatalf("GetItemSeriesByID(split): %v", err)
+	}
+	if want := []time.Time{day(1), day(3)}; !slices.EqualFunc(old.ExDates, want, time.Time.Equal) {
+		t.Errorf("old series exdates = %v, want %v", old.ExDates, want)
+	}
+	if want := []time.Time{day(5)}; !slices.EqualFunc(split.ExDates, want, time.Time.Equal) {
+		t.Errorf("new series exdates = %v, want %v", split.ExDates, want)
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/scheduler"
+	"github.com/gofiber/fiber/v3"
+	"github.com/jackc/pgx/v5"
+)
+
+// TasksHandler serves the admin API for scheduled tasks
+type TasksHandler struct {
+	db        *database.DB
+	config    *config.Config
+	scheduler *scheduler.Scheduler
+}
+
+func NewTasksHandler(db *database.DB, cfg *config.Config, sched *scheduler.Scheduler) *TasksHandler {
+	return &TasksHandler{
+		db:        db,
+		config:    cfg,
+		scheduler: sched,
+	}
+}
+
+// ListTasks returns each task's schedule, last run and next run, and whether
+// the instance serving the request is the scheduler leader
+func (h *TasksHandler) ListTasks(c fiber.Ctx) error {
+	tasks, err := h.scheduler.Tasks(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve tasks"))
+	}
+
+	return c.JSON(models.SuccessResponse(fiber.Map{
+		"instance": h.scheduler.Instance(),
+		"leader":   h.scheduler.IsLeader(),
+		"tasks":    tasks,
+	}))
+}
+
+// RunTask makes a task due now; the leader runs it within a few seconds
+func (h *TasksHandler) RunTask(c fiber.Ctx) error {
+	task, err := h.db.TriggerScheduledTask(c.Context(), c.Params("name"))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse("Task not found"))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to trigger task"))
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(models.SuccessResponse(task))
+}
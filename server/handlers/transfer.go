@@ -0,0 +1,430 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/logging"
+	"github.com/binduni/bun-golang-react-monorepo/server/middleware"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/outbox"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+	"github.com/gofiber/fiber/v3"
+)
+
+const (
+	transferFormatCSV    = "csv"
+	transferFormatJSON   = "json"
+	transferFormatNDJSON = "ndjson"
+
+	// maxImportRows bounds the size of a single import transaction
+	maxImportRows = 10000
+
+	// exportTimeout bounds how long a streaming export may hold a connection
+	exportTimeout = 5 * time.Minute
+)
+
+var exportCSVHeader = []string{
+	"id", "externalId", "title", "description", "status", "parentId", "dueAt", "tags", "createdAt", "updatedAt",
+}
+
+// importRow is the accepted shape of an imported item in every format
+type importRow struct {
+	ExternalID  string   `json:"externalId"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	DueAt       string   `json:"dueAt"`
+	Tags        []string `json:"tags"`
+}
+
+// ExportItems streams all of the user's items as CSV, JSON or NDJSON (?format=)
+func (h *ItemsHandler) ExportItems(c fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Unauthorized"))
+	}
+
+	format := strings.ToLower(c.Query("format", transferFormatJSON))
+	var contentType string
+	switch format {
+	case transferFormatCSV:
+		contentType = "text/csv; charset=utf-8"
+	case transferFormatJSON:
+		contentType = fiber.MIMEApplicationJSONCharsetUTF8
+	case transferFormatNDJSON:
+		contentType = "application/x-ndjson"
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Format must be csv, json or ndjson"))
+	}
+
+	filename := fmt.Sprintf("items-%s.%s", time.Now().UTC().Format("20060102"), format)
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+
+	// The writer runs after the handler returns, so it cannot use the request
+	// context; it keeps the request's logger
+	logger := logging.FromContext(c.Context())
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(logging.WithContext(context.Background(), logger), exportTimeout)
+		defer cancel()
+
+		if err := h.writeExport(ctx, w, userID, format); err != nil {
+			logger.Error("Item export failed", "user_id", userID, "error", err)
+		}
+	})
+}
+
+func (h *ItemsHandler) writeExport(ctx context.Context, w *bufio.Writer, userID, format string) error {
+	switch format {
+	case transferFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(exportCSVHeader); err != nil {
+			return err
+		}
+		err := h.db.StreamUserItems(ctx, userID, func(item *models.Item) error {
+			return cw.Write(itemCSVRecord(item))
+		})
+		cw.Flush()
+		if err != nil {
+			return err
+		}
+		return cw.Error()
+
+	case transferFormatNDJSON:
+		enc := json.NewEncoder(w)
+		return h.db.StreamUserItems(ctx, userID, func(item *models.Item) error {
+			return enc.Encode(item)
+		})
+
+	default:
+		if _, err := w.WriteString("["); err != nil {
+			return err
+		}
+		first := true
+		err := h.db.StreamUserItems(ctx, userID, func(item *models.Item) error {
+			if !first {
+				if err := w.WriteByte(','); err != nil {
+					return err
+				}
+			}
+			first = false
+			data, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(data)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		_, err = w.WriteString("]\n")
+		return err
+	}
+}
+
+func itemCSVRecord(item *models.Item) []string {
+	optional := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	dueAt := ""
+	if item.DueAt != nil {
+		dueAt = item.DueAt.UTC().Format(time.RFC3339)
+	}
+	return []string{
+		item.ID,
+		optional(item.ExternalID),
+		item.Title,
+		item.Description,
+		string(item.Status),
+		optional(item.ParentID),
+		dueAt,
+		strings.Join(item.Tags, ";"),
+		item.CreatedAt.UTC().Format(time.RFC3339),
+		item.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// ImportItems validates and imports items from a CSV, JSON array or NDJSON body.
+// The format comes from ?format= or the Content-Type; the body may also be a
+// multipart "file" field. With ?dryRun=true only the validation report is
+// returned. Otherwise all rows are committed in one transaction, or none if any
+// row is invalid. Rows with an externalId update the existing item with that ID.
+func (h *ItemsHandler) ImportItems(c fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Unauthorized"))
+	}
+
+	body := c.Body()
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Failed to read upload"))
+		}
+		defer f.Close()
+		if body, err = io.ReadAll(f); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Failed to read upload"))
+		}
+	}
+
+	format := strings.ToLower(c.Query("format"))
+	if format == "" {
+		format = detectImportFormat(c.Get(fiber.HeaderContentType))
+	}
+
+	report := models.ItemImportReport{
+		Format: format,
+		DryRun: fiber.Query[bool](c, "dryRun"),
+		Errors: []models.ItemImportRowError{},
+	}
+
+	var rows []importRow
+	var err error
+	switch format {
+	case transferFormatCSV:
+		rows, err = parseCSVImport(body)
+	case transferFormatJSON:
+		rows, err = parseJSONImport(body)
+	case transferFormatNDJSON:
+		rows, err = parseNDJSONImport(body)
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Format must be csv, json or ndjson"))
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Failed to parse import: " + err.Error()))
+	}
+	if len(rows) > maxImportRows {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(models.ErrorResponse(
+			fmt.Sprintf("Imports are limited to %d rows", maxImportRows)))
+	}
+
+	report.Total = len(rows)
+	items := make([]*models.Item, 0, len(rows))
+	seenExternal := make(map[string]int)
+	for i, row := range rows {
+		item, rowErrors := validateImportRow(i+1, row)
+		if item.ExternalID != nil {
+			if first, dup := seenExternal[*item.ExternalID]; dup {
+				rowErrors = append(rowErrors, models.ItemImportRowError{
+					Row: i + 1, Field: "externalId",
+					Message: fmt.Sprintf("duplicate externalId (also on row %d)", first),
+				})
+			} else {
+				seenExternal[*item.ExternalID] = i + 1
+			}
+		}
+		if len(rowErrors) > 0 {
+			report.Errors = append(report.Errors, rowErrors...)
+			continue
+		}
+		item.UserID = userID
+		items = append(items, item)
+	}
+	report.Valid = len(items)
+
+	if report.DryRun {
+		return c.JSON(models.SuccessResponse(report))
+	}
+	if len(report.Errors) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(models.ApiResponse[models.ItemImportReport]{
+			Success: false,
+			Data:    &report,
+			Error:   "Import contains invalid rows; nothing was imported",
+		})
+	}
+
+	err = h.db.WithTx(c.Context(), func(ctx context.Context) error {
+		var err error
+		report.Created, report.Updated, err = h.db.ImportItems(ctx, userID, items)
+		if err != nil {
+			return err
+		}
+
+		// One summary event instead of an event per row; subscribers refetch their items
+		summary := fiber.Map{
+			"created": report.Created,
+			"updated": report.Updated,
+		}
+		event := outbox.NewEvent(models.EventItemsImported, models.AggregateUser, userID, &userID)
+		return h.db.RecordDomainEvent(ctx, event, summary)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to import items"))
+	}
+	report.Committed = true
+
+	return c.JSON(models.SuccessResponse(report))
+}
+
+func detectImportFormat(contentType string) string {
+	switch {
+	case strings.HasPrefix(contentType, "text/csv"):
+		return transferFormatCSV
+	case strings.HasPrefix(contentType, "application/x-ndjson"),
+		strings.HasPrefix(contentType, "application/ndjson"):
+		return transferFormatNDJSON
+	default:
+		return transferFormatJSON
+	}
+}
+
+// validateImportRow converts a row to an item, collecting every problem found
+func validateImportRow(rowNum int, row importRow) (*models.Item, []models.ItemImportRowError) {
+	var errs []models.ItemImportRowError
+	fail := func(field, message string) {
+		errs = append(errs, models.ItemImportRowError{Row: rowNum, Field: field, Message: message})
+	}
+
+	item := &models.Item{
+		ID:          utils.NewItemID(),
+		Title:       strings.TrimSpace(row.Title),
+		Description: row.Description,
+		Status:      models.ItemStatus(strings.ToLower(strings.TrimSpace(row.Status))),
+		Tags:        utils.NormalizeTags(row.Tags),
+	}
+
+	if item.Title == "" {
+		fail("title", "title is required")
+	} else if len(item.Title) > 255 {
+		fail("title", "title must be at most 255 characters")
+	}
+
+	switch item.Status {
+	case "":
+		item.Status = models.ItemStatusActive
+	case models.ItemStatusActive, models.ItemStatusCompleted, models.ItemStatusArchived:
+	default:
+		fail("status", fmt.Sprintf("invalid status %q", row.Status))
+	}
+
+	if dueAt := strings.TrimSpace(row.DueAt); dueAt != "" {
+		t, err := time.Parse(time.RFC3339, dueAt)
+		if err != nil {
+			fail("dueAt", "dueAt must be an RFC 3339 timestamp")
+		} else {
+			item.DueAt = &t
+		}
+	}
+
+	if externalID := strings.TrimSpace(row.ExternalID); externalID != "" {
+		if len(externalID) > 255 {
+			fail("externalId", "externalId must be at most 255 characters")
+		}
+		item.ExternalID = &externalID
+	}
+
+	return item, errs
+}
+
+// parseCSVImport maps columns by (case-insensitive) header name. Tags are
+// separated by ";" within the tags column.
+func parseCSVImport(body []byte) ([]importRow, error) {
+	r := csv.NewReader(bytes.NewReader(body))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("CSV header must include a title column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[strings.ToLower(name)]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var rows []importRow
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var tags []string
+		if raw := field(record, "tags"); raw != "" {
+			tags = strings.Split(raw, ";")
+		}
+		rows = append(rows, importRow{
+			ExternalID:  field(record, "externalId"),
+			Title:       field(record, "title"),
+			Description: field(record, "description"),
+			Status:      field(record, "status"),
+			DueAt:       field(record, "dueAt"),
+			Tags:        tags,
+		})
+	}
+	return rows, nil
+}
+
+// parseJSONImport decodes a JSON array element by element
+func parseJSONImport(body []byte) ([]importRow, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("expected a JSON array of items")
+	}
+
+	var rows []importRow
+	for dec.More() {
+		var row importRow
+		if err := dec.Decode(&row); err != nil {
+			return nil, fmt.Errorf("item %d: %w", len(rows)+1, err)
+		}
+		rows = append(rows, row)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// parseNDJSONImport decodes one JSON object per non-empty line
+func parseNDJSONImport(body []byte) ([]importRow, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var rows []importRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var row importRow
+		if err := json.Unmarshal(text, &row); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
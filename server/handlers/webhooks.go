@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/middleware"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+	"github.com/binduni/bun-golang-react-monorepo/server/webhooks"
+	"github.com/gofiber/fiber/v3"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	maxWebhookEndpoints      = 20
+	maxWebhookDescription    = 255
+	defaultSecretGraceHours  = 24
+	maxSecretGraceHours      = 168
+	defaultDeliveryPageLimit = 50
+	maxDeliveryPageLimit     = 200
+)
+
+type WebhooksHandler struct {
+	db     *database.DB
+	config *config.Config
+}
+
+func NewWebhooksHandler(db *database.DB, cfg *config.Config) *WebhooksHandler {
+	return &WebhooksHandler{
+		db:     db,
+		config: cfg,
+	}
+}
+
+// ListEventTypes returns the event types the user can subscribe to
+func (h *WebhooksHandler) ListEventTypes(c fiber.Ctx) error {
+	isAdmin := middleware.GetUserRole(c) == models.RoleAdmin
+	types := []models.WebhookEventType{}
+	for eventType, adminOnly := range models.WebhookEventTypes {
+		if !adminOnly || isAdmin {
+			types = append(types, eventType)
+		}
+	}
+	return c.JSON(models.SuccessResponse(types))
+}
+
+// ListEndpoints returns the user's webhook endpoints
+func (h *WebhooksHandler) ListEndpoints(c fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Unauthorized"))
+	}
+
+	endpoints, err := h.db.GetUserWebhookEndpoints(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve webhooks"))
+	}
+
+	return c.JSON(models.SuccessResponse(endpoints))
+}
+
+// CreateEndpoint registers a webhook endpoint. The signing secret is only returned here and on rotation.
+func (h *WebhooksHandler) CreateEndpoint(c fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Unauthorized"))
+	}
+
+	var req models.WebhookEndpointRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Invalid request body"))
+	}
+	if req.URL == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("URL is required"))
+	}
+	if len(req.EventTypes) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("At least one event type is required"))
+	}
+
+	endpoints, err := h.db.GetUserWebhookEndpoints(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve webhooks"))
+	}
+	if len(endpoints) >= maxWebhookEndpoints {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Maximum number of webhooks reached"))
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to generate secret"))
+	}
+
+	endpoint := &models.WebhookEndpoint{
+		ID:      utils.NewWebhookID(),
+		UserID:  userID,
+		Enabled: true,
+		Secret:  secret,
+	}
+	if message := h.applyEndpointRequest(c, endpoint, &req); message != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse(message))
+	}
+
+	if err := h.db.CreateWebhookEndpoint(c.Context(), endpoint); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to create webhook"))
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.SuccessResponse(models.WebhookEndpointWithSecret{
+		WebhookEndpoint: endpoint,
+		Secret:          secret,
+	}))
+}
+
+func (h *WebhooksHandler) GetEndpoint(c fiber.Ctx) error {
+	endpoint, errResp := h.getOwnedEndpoint(c)
+	if endpoint == nil {
+		return errResp
+	}
+	return c.JSON(models.SuccessResponse(endpoint))
+}
+
+// UpdateEndpoint changes an endpoint's URL, description, subscriptions or enabled flag
+func (h *WebhooksHandler) UpdateEndpoint(c fiber.Ctx) error {
+	endpoint, errResp := h.getOwnedEndpoint(c)
+	if endpoint == nil {
+		return errResp
+	}
+
+	var req models.WebhookEndpointRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Invalid request body"))
+	}
+	if req.EventTypes != nil && len(req.EventTypes) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("At least one event type is required"))
+	}
+	if message := h.applyEndpointRequest(c, endpoint, &req); message != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse(message))
+	}
+
+	if err := h.db.UpdateWebhookEndpoint(c.Context(), endpoint); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to update webhook"))
+	}
+
+	return c.JSON(models.SuccessResponse(endpoint))
+}
+
+func (h *WebhooksHandler) DeleteEndpoint(c fiber.Ctx) error {
+	endpoint, errResp := h.getOwnedEndpoint(c)
+	if endpoint == nil {
+		return errResp
+	}
+
+	if err := h.db.DeleteWebhookEndpoint(c.Context(), endpoint.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to delete webhook"))
+	}
+
+	return c.JSON(models.SuccessResponse(fiber.Map{
+		"message": "Webhook deleted successfully",
+	}))
+}
+
+// RotateSecret issues a new signing secret. The old secret keeps signing
+// deliveries for ?graceHours= (default 24, 0 to revoke it immediately).
+func (h *WebhooksHandler) RotateSecret(c fiber.Ctx) error {
+	endpoint, errResp := h.getOwnedEndpoint(c)
+	if endpoint == nil {
+		return errResp
+	}
+
+	graceHours := fiber.Query(c, "graceHours", defaultSecretGraceHours)
+	if graceHours < 0 || graceHours > maxSecretGraceHours {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("graceHours must be between 0 and 168"))
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to generate secret"))
+	}
+
+	endpoint, err = h.db.RotateWebhookSecret(c.Context(), endpoint.ID, secret, time.Duration(graceHours)*time.Hour)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to rotate secret"))
+	}
+
+	return c.JSON(models.SuccessResponse(models.WebhookEndpointWithSecret{
+		WebhookEndpoint: endpoint,
+		Secret:          secret,
+	}))
+}
+
+// Ping queues a webhook.ping event to the endpoint regardless of its subscriptions
+func (h *WebhooksHandler) Ping(c fiber.Ctx) error {
+	endpoint, errResp := h.getOwnedEndpoint(c)
+	if endpoint == nil {
+		return errResp
+	}
+
+	event := webhooks.NewEvent(models.WebhookEventPing, fiber.Map{"webhookId": endpoint.ID})
+	delivery, err := h.db.EnqueueWebhookDelivery(c.Context(), endpoint.ID, event)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to queue ping"))
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(models.SuccessResponse(delivery))
+}
+
+// ListDeliveries returns recent deliveries for an endpoint (?status=pending|succeeded|dead, ?limit=)
+func (h *WebhooksHandler) ListDeliveries(c fiber.Ctx) error {
+	endpoint, errResp := h.getOwnedEndpoint(c)
+	if endpoint == nil {
+		return errResp
+	}
+
+	var status *models.WebhookDeliveryStatus
+	if raw := c.Query("status"); raw != "" {
+		s := models.WebhookDeliveryStatus(raw)
+		if s != models.WebhookDeliveryPending && s != models.WebhookDeliverySucceeded && s != models.WebhookDeliveryDead {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("Invalid status"))
+		}
+		status = &s
+	}
+	limit := fiber.Query(c, "limit", defaultDeliveryPageLimit)
+	if limit < 1 || limit > maxDeliveryPageLimit {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse("limit must be between 1 and 200"))
+	}
+
+	deliveries, err := h.db.GetWebhookDeliveries(c.Context(), endpoint.ID, status, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve deliveries"))
+	}
+
+	return c.JSON(models.SuccessResponse(deliveries))
+}
+
+// GetDelivery returns a delivery with its attempt log
+func (h *WebhooksHandler) GetDelivery(c fiber.Ctx) error {
+	delivery, errResp := h.getOwnedDelivery(c)
+	if delivery == nil {
+		return errResp
+	}
+	return c.JSON(models.SuccessResponse(delivery))
+}
+
+// Redeliver queues the delivery's event again as a new delivery with the same event ID
+func (h *WebhooksHandler) Redeliver(c fiber.Ctx) error {
+	delivery, errResp := h.getOwnedDelivery(c)
+	if delivery == nil {
+		return errResp
+	}
+
+	redelivery, err := h.db.RedeliverWebhook(c.Context(), delivery.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to queue redelivery"))
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(models.SuccessResponse(redelivery))
+}
+
+// getOwnedEndpoint loads the endpoint named by the :id param and verifies the
+// authenticated user owns it. When the endpoint is nil the error response has
+// already been written and the returned error should be passed through.
+func (h *WebhooksHandler) getOwnedEndpoint(c fiber.Ctx) (*models.WebhookEndpoint, error) {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		return nil, c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Unauthorized"))
+	}
+
+	endpoint, err := h.db.GetWebhookEndpoint(c.Context(), c.Params("id"))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse("Webhook not found"))
+		}
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve webhook"))
+	}
+
+	if endpoint.UserID != userID {
+		return nil, c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse("Webhook not found"))
+	}
+
+	return endpoint, nil
+}
+
+// getOwnedDelivery loads the :deliveryId delivery of an owned :id endpoint,
+// with the same contract as getOwnedEndpoint
+func (h *WebhooksHandler) getOwnedDelivery(c fiber.Ctx) (*models.WebhookDelivery, error) {
+	endpoint, errResp := h.getOwnedEndpoint(c)
+	if endpoint == nil {
+		return nil, errResp
+	}
+
+	delivery, err := h.db.GetWebhookDelivery(c.Context(), c.Params("deliveryId"))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse("Delivery not found"))
+		}
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse("Failed to retrieve delivery"))
+	}
+
+	if delivery.EndpointID != endpoint.ID {
+		return nil, c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse("Delivery not found"))
+	}
+
+	return delivery, nil
+}
+
+// applyEndpointRequest validates and applies the fields present in req,
+// returning an error message for the client when a field is invalid
+func (h *WebhooksHandler) applyEndpointRequest(c fiber.Ctx, endpoint *models.WebhookEndpoint, req *models.WebhookEndpointRequest) string {
+	if req.URL != nil {
+		// Plain http and local receivers are allowed while developing
+		if err := webhooks.ValidateURL(*req.URL, h.config.IsDevelopment()); err != nil {
+			return "Invalid URL: " + err.Error()
+		}
+		endpoint.URL = *req.URL
+	}
+	if req.Description != nil {
+		if len(*req.Description) > maxWebhookDescription {
+			return "Description must be at most 255 characters"
+		}
+		endpoint.Description = *req.Description
+	}
+	if req.EventTypes != nil {
+		isAdmin := middleware.GetUserRole(c) == models.RoleAdmin
+		seen := make(map[models.WebhookEventType]bool)
+		eventTypes := []models.WebhookEventType{}
+		for _, eventType := range req.EventTypes {
+			adminOnly, known := models.WebhookEventTypes[eventType]
+			if eventType != models.WebhookEventAll && (!known || (adminOnly && !isAdmin)) {
+				return "Unknown event type: " + string(eventType)
+			}
+			if !seen[eventType] {
+				seen[eventType] = true
+				eventTypes = append(eventTypes, eventType)
+			}
+		}
+		endpoint.EventTypes = eventTypes
+	}
+	if req.Enabled != nil {
+		endpoint.Enabled = *req.Enabled
+	}
+	return ""
+}
+
+func newWebhookSecret() (string, error) {
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + token, nil
+}
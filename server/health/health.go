@@ -0,0 +1,107 @@
+// Package health tracks the state Kubernetes probes report: whether the
+// server has started, whether it is draining, and a registry of named
+// dependency checks that decide readiness.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkTimeout bounds each check so a hung dependency cannot stall probes
+const checkTimeout = 2 * time.Second
+
+// Check reports whether a dependency is usable; nil means healthy
+type Check func(ctx context.Context) error
+
+// Result is the outcome of one check
+type Result struct {
+	Name       string  `json:"name"`
+	Healthy    bool    `json:"healthy"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Registry holds the readiness checks and lifecycle state of the server
+type Registry struct {
+	mu     sync.RWMutex
+	checks []namedCheck
+
+	started  atomic.Bool
+	draining atomic.Bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a readiness check. Checks run concurrently, so check must be
+// safe for concurrent use.
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, namedCheck{name: name, check: check})
+}
+
+// MarkStarted records that startup (migrations, workers, listener) is complete
+func (r *Registry) MarkStarted() {
+	r.started.Store(true)
+}
+
+// Started reports whether startup is complete
+func (r *Registry) Started() bool {
+	return r.started.Load()
+}
+
+// MarkDraining makes the server report unready so load balancers stop
+// routing to it before it shuts down
+func (r *Registry) MarkDraining() {
+	r.draining.Store(true)
+}
+
+// Draining reports whether shutdown has begun
+func (r *Registry) Draining() bool {
+	return r.draining.Load()
+}
+
+// Run runs every check concurrently and returns the results in registration
+// order, and whether all passed
+func (r *Registry) Run(ctx context.Context) ([]Result, bool) {
+	r.mu.RLock()
+	checks := r.checks
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Go(func() {
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			started := time.Now()
+			err := c.check(checkCtx)
+			results[i] = Result{
+				Name:       c.name,
+				Healthy:    err == nil,
+				DurationMs: float64(time.Since(started).Microseconds()) / 1000,
+			}
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		})
+	}
+	wg.Wait()
+
+	healthy := true
+	for _, result := range results {
+		healthy = healthy && result.Healthy
+	}
+	return results, healthy
+}
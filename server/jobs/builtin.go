@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+)
+
+// CleanupSessionsArgs deletes expired sessions
+type CleanupSessionsArgs struct{}
+
+func (CleanupSessionsArgs) Kind() string { return "sessions.cleanup" }
+
+// RegisterBuiltins registers the workers for the server's own jobs
+func RegisterBuiltins(q *Queue, db *database.DB) {
+	Register(q, func(ctx context.Context, job *models.Job, args CleanupSessionsArgs) error {
+		deleted, err := db.CleanupExpiredSessions(ctx)
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			slog.Info("Deleted expired sessions", "count", deleted)
+		}
+		return nil
+	}, nil)
+}
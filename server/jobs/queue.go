@@ -0,0 +1,360 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// DefaultMaxAttempts is used when InsertOpts.MaxAttempts is zero
+	DefaultMaxAttempts = 10
+
+	// DefaultTimeout bounds a single run when WorkerOpts.Timeout is zero
+	DefaultTimeout = time.Minute
+
+	// Retention is how long finished jobs are kept (see the jobs.prune task)
+	Retention = 7 * 24 * time.Hour
+
+	leaseMargin    = time.Minute
+	pollInterval   = 5 * time.Second
+	recordTimeout  = 10 * time.Second
+	shutdownGrace  = 5 * time.Second
+	retryBase      = 10 * time.Second
+	retryMax       = time.Hour
+	maxErrorLength = 2000
+)
+
+// Args are the typed arguments of a job, stored as JSON. Kind selects the
+// worker and must be stable across deploys.
+type Args interface {
+	Kind() string
+}
+
+// InsertOpts control how a job is queued. The zero value runs the job as
+// soon as possible at priority 0.
+type InsertOpts struct {
+	Priority    int       // Higher runs first
+	RunAt       time.Time // Zero means now
+	UniqueKey   string    // Skip the insert while a pending or running job has this key
+	MaxAttempts int       // Zero means DefaultMaxAttempts
+}
+
+// WorkerOpts configure a registered worker
+type WorkerOpts struct {
+	Timeout time.Duration // Zero means DefaultTimeout
+}
+
+// Insert queues a job. When ctx carries a transaction (database.WithTx) the
+// job is only queued if it commits. It returns false with the existing job
+// when a job with the same unique key is already pending or running.
+func Insert(ctx context.Context, db *database.DB, args Args, opts *InsertOpts) (*models.Job, bool, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil, false, err
+	}
+	return InsertRaw(ctx, db, args.Kind(), data, opts)
+}
+
+// InsertRaw queues a job with already encoded arguments
+func InsertRaw(ctx context.Context, db *database.DB, kind string, args json.RawMessage, opts *InsertOpts) (*models.Job, bool, error) {
+	if opts == nil {
+		opts = &InsertOpts{}
+	}
+	job := &models.Job{
+		ID:          utils.NewJobID(),
+		Kind:        kind,
+		Args:        args,
+		Priority:    opts.Priority,
+		RunAt:       opts.RunAt,
+		MaxAttempts: opts.MaxAttempts,
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.UniqueKey != "" {
+		job.UniqueKey = &opts.UniqueKey
+	}
+
+	inserted, err := db.InsertJob(ctx, job)
+	if err != nil {
+		return nil, false, err
+	}
+	return job, inserted, nil
+}
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job fails immediately instead of being retried
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+type worker struct {
+	timeout time.Duration
+	work    func(ctx context.Context, job *models.Job) error
+}
+
+// Queue runs jobs of the registered kinds with a bounded number of workers.
+// Any number of queues may run against one database.
+type Queue struct {
+	db          *database.DB
+	concurrency int
+	workers     map[string]*worker
+
+	// jobCtx is cancelled when a shutdown runs out of time, interrupting jobs
+	jobCtx    context.Context
+	cancelJob context.CancelFunc
+	wg        sync.WaitGroup
+	running   atomic.Bool
+	lastPoll  atomic.Int64  // Unix nanoseconds of the last claim attempt
+	stopped   chan struct{} // Closed when Run returns
+}
+
+func NewQueue(db *database.DB, concurrency int) *Queue {
+	jobCtx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		db:          db,
+		concurrency: max(concurrency, 1),
+		workers:     make(map[string]*worker),
+		jobCtx:      jobCtx,
+		cancelJob:   cancel,
+		stopped:     make(chan struct{}),
+	}
+}
+
+// Register adds the worker for jobs of T's kind. Register every worker
+// before calling Run.
+func Register[T Args](q *Queue, work func(ctx context.Context, job *models.Job, args T) error, opts *WorkerOpts) {
+	var zero T
+	w := &worker{timeout: DefaultTimeout}
+	if opts != nil && opts.Timeout > 0 {
+		w.timeout = opts.Timeout
+	}
+	w.work = func(ctx context.Context, job *models.Job) error {
+		var args T
+		if err := json.Unmarshal(job.Args, &args); err != nil {
+			return Permanent(fmt.Errorf("decode args: %w", err))
+		}
+		return work(ctx, job, args)
+	}
+	q.workers[zero.Kind()] = w
+}
+
+// Kinds returns the registered job kinds
+func (q *Queue) Kinds() []string {
+	kinds := make([]string, 0, len(q.workers))
+	for kind := range q.workers {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+	return kinds
+}
+
+// Run claims and runs jobs until ctx is done, waking on inserts and polling
+// as a fallback. Jobs still running when it returns keep going; call
+// Shutdown to wait for them.
+func (q *Queue) Run(ctx context.Context) {
+	defer close(q.stopped)
+
+	kinds := q.Kinds()
+	if len(kinds) == 0 {
+		return
+	}
+	q.running.Store(true)
+	defer q.running.Store(false)
+
+	// The lease outlasts the longest worker timeout, so only dead workers lose jobs
+	lease := DefaultTimeout
+	for _, w := range q.workers {
+		lease = max(lease, w.timeout)
+	}
+	lease += leaseMargin
+
+	wake := make(chan struct{}, 1)
+	go q.db.ListenLoop(ctx, database.JobsChannel, func(*pgconn.Notification) {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	})
+
+	slots := make(chan struct{}, q.concurrency)
+	done := make(chan struct{}, q.concurrency)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		q.lastPoll.Store(time.Now().UnixNano())
+		free := cap(slots) - len(slots)
+		claimed := 0
+		if free > 0 {
+			jobs, err := q.db.ClaimJobs(ctx, kinds, free, lease)
+			if err != nil && ctx.Err() == nil {
+				slog.Error("Failed to claim jobs", "error", err)
+			}
+			claimed = len(jobs)
+			for _, job := range jobs {
+				slots <- struct{}{}
+				q.wg.Add(1)
+				go func() {
+					defer func() {
+						<-slots
+						q.wg.Done()
+						select {
+						case done <- struct{}{}:
+						default:
+						}
+					}()
+					q.execute(job)
+				}()
+			}
+		}
+
+		// A full batch suggests more jobs are due; claim again if slots freed up
+		if claimed > 0 && claimed == free && len(slots) < cap(slots) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-done:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check reports an error unless Run is polling for jobs. Run polls at least
+// every pollInterval, plus the time a claim query may take.
+func (q *Queue) Check(ctx context.Context) error {
+	if !q.running.Load() {
+		return errors.New("job queue is not running")
+	}
+	if since := time.Since(time.Unix(0, q.lastPoll.Load())); since > 4*pollInterval {
+		return fmt.Errorf("job queue has not polled for %s", since.Round(time.Second))
+	}
+	return nil
+}
+
+// Shutdown waits for Run to return and running jobs to finish. If ctx ends
+// first, job contexts are cancelled and the interrupted jobs are queued for
+// retry. Cancel Run's context before calling Shutdown.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	select {
+	case <-q.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+	}
+
+	q.cancelJob()
+	select {
+	case <-finished:
+	case <-time.After(shutdownGrace):
+	}
+	return ctx.Err()
+}
+
+func (q *Queue) execute(job *models.Job) {
+	w := q.workers[job.Kind]
+
+	var err error
+	if job.Attempts > job.MaxAttempts {
+		// Reclaimed after its worker died on the last attempt
+		err = Permanent(errors.New("worker lost on final attempt"))
+	} else {
+		ctx, cancel := context.WithTimeout(q.jobCtx, w.timeout)
+		err = runWorker(ctx, w, job)
+		cancel()
+	}
+
+	// Record the outcome even when the job context was cancelled by a shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+	defer cancel()
+
+	var recorded bool
+	var recordErr error
+	var permanent *permanentError
+	switch {
+	case err == nil:
+		recorded, recordErr = q.db.CompleteJob(ctx, job.ID, job.Attempts)
+	case errors.As(err, &permanent) || job.Attempts >= job.MaxAttempts:
+		slog.Error("Job failed permanently", "job_id", job.ID, "kind", job.Kind, "attempts", job.Attempts, "error", err)
+		recorded, recordErr = q.db.FailJob(ctx, job.ID, job.Attempts, truncateError(err), nil)
+	default:
+		runAt := time.Now().Add(backoff(job.Attempts))
+		if q.jobCtx.Err() != nil {
+			runAt = time.Now() // Interrupted by shutdown rather than failed
+		}
+		recorded, recordErr = q.db.FailJob(ctx, job.ID, job.Attempts, truncateError(err), &runAt)
+	}
+
+	if recordErr != nil {
+		slog.Error("Failed to record job outcome", "job_id", job.ID, "error", recordErr)
+	} else if !recorded {
+		slog.Warn("Job was cancelled or reclaimed while running; outcome discarded", "job_id", job.ID)
+	}
+}
+
+// runWorker calls the worker, turning a panic into an error
+func runWorker(ctx context.Context, w *worker, job *models.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return w.work(ctx, job)
+}
+
+// backoff doubles from ten seconds up to an hour, with ±20% jitter
+func backoff(attempt int) time.Duration {
+	delay := retryMax
+	if attempt < 20 {
+		delay = min(retryBase<<(attempt-1), retryMax)
+	}
+	spread := int64(delay) / 5
+	return delay + time.Duration(rand.Int64N(2*spread+1)-spread)
+}
+
+func truncateError(err error) string {
+	message := err.Error()
+	if len(message) > maxErrorLength {
+		message = message[:maxErrorLength]
+	}
+	return strings.ToValidUTF8(message, "")
+}
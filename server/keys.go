@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+)
+
+func runKeys(cfg *config.Config, args []string) int {
+	return runSubcommand(cfg, "keys", args, map[string]func(*config.Config, []string) int{
+		"rotate": runKeysRotate,
+	})
+}
+
+// runKeysRotate generates a new JWT secret and prints the environment that
+// switches to it. The current secret moves to JWT_PREVIOUS_SECRETS, so tokens
+// it signed keep working until they expire.
+func runKeysRotate(cfg *config.Config, args []string) int {
+	fs := newFlagSet("keys rotate [--bytes N]")
+	size := fs.Int("bytes", 48, "entropy of the new secret in bytes")
+	if positional, err := parseFlags(fs, args); err != nil || len(positional) > 0 || *size < 32 {
+		return usageError(fs, err)
+	}
+
+	secret, err := utils.GenerateSecureToken(*size)
+	if err != nil {
+		return fail("failed to generate secret: %v", err)
+	}
+	// The well-known default secret is dropped rather than kept valid
+	previous := []string{}
+	for _, s := range append([]string{cfg.JWTSecret}, cfg.JWTPreviousSecrets...) {
+		if s != config.DefaultJWTSecret && !slices.Contains(previous, s) {
+			previous = append(previous, s)
+		}
+	}
+
+	fmt.Printf("JWT_SECRET=%s\n", secret)
+	fmt.Printf("JWT_PREVIOUS_SECRETS=%s\n", strings.Join(previous, ","))
+	fmt.Printf("\nSet these on every instance and restart. Tokens signed with a previous secret\n"+
+		"stay valid until they expire; drop JWT_PREVIOUS_SECRETS after %d days.\n", int(utils.RefreshTokenExpiry.Hours()/24))
+	return 0
+}
@@ -0,0 +1,83 @@
+// Package logging configures the application's slog logger and carries
+// request-scoped loggers through contexts.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+)
+
+// Redacted replaces the values of sensitive attributes
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute key fragments whose values are never logged
+var sensitiveKeys = []string{"password", "secret", "token", "authorization", "cookie", "apikey", "api_key"}
+
+// New returns a logger writing JSON or text to w at the given level, with
+// secrets redacted and email addresses masked
+func New(w io.Writer, level slog.Level, json bool) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redact}
+	if json {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q", s)
+	}
+	return level, nil
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return slog.String(a.Key, Redacted)
+		}
+	}
+	if strings.Contains(key, "email") && a.Value.Kind() == slog.KindString {
+		return slog.String(a.Key, MaskEmail(a.Value.String()))
+	}
+	return a
+}
+
+// MaskEmail keeps the first character of the local part and the domain, so
+// log lines can be correlated without exposing the address
+func MaskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return Redacted
+	}
+	return local[:1] + "***@" + domain
+}
+
+type loggerKey struct{}
+
+// WithContext returns a context carrying logger
+func WithContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the context's logger, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// WithRequestID returns a context carrying a request ID, whose logger adds it
+// to every line
+func WithRequestID(ctx context.Context, id string) context.Context {
+	ctx = utils.WithRequestID(ctx, id)
+	return WithContext(ctx, FromContext(ctx).With("request_id", id))
+}
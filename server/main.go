@@ -1,95 +1,381 @@
 package main
 
 import (
-	"log"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/binduni/bun-golang-react-monorepo/server/config"
 	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/handlers"
+	"github.com/binduni/bun-golang-react-monorepo/server/health"
+	"github.com/binduni/bun-golang-react-monorepo/server/jobs"
+	"github.com/binduni/bun-golang-react-monorepo/server/logging"
+	"github.com/binduni/bun-golang-react-monorepo/server/metrics"
+	"github.com/binduni/bun-golang-react-monorepo/server/middleware"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/outbox"
+	"github.com/binduni/bun-golang-react-monorepo/server/realtime"
 	"github.com/binduni/bun-golang-react-monorepo/server/routes"
+	"github.com/binduni/bun-golang-react-monorepo/server/scheduler"
+	"github.com/binduni/bun-golang-react-monorepo/server/store"
+	"github.com/binduni/bun-golang-react-monorepo/server/tracing"
+	"github.com/binduni/bun-golang-react-monorepo/server/webhooks"
 	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
 	"github.com/gofiber/fiber/v3/middleware/cors"
-	"github.com/gofiber/fiber/v3/middleware/logger"
 	"github.com/gofiber/fiber/v3/middleware/recover"
 	"github.com/joho/godotenv"
 )
 
 func main() {
 	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using system environment variables")
+	envErr := godotenv.Load()
+
+	// Load configuration: defaults, then the config file, env and global flags
+	cfg, args, err := config.Load(os.Args[1:])
+	if err == flag.ErrHelp {
+		printUsage(os.Stdout)
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+	setupLogging(cfg)
+	if envErr != nil {
+		slog.Debug("No .env file found, using system environment variables")
 	}
 
-	// Load configuration
-	cfg := config.Load()
+	os.Exit(run(cfg, args))
+}
+
+// runServe starts the HTTP server and background workers, and blocks until
+// the process is signalled to stop
+func runServe(cfg *config.Config, args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "Usage: server serve")
+		return 2
+	}
+
+	// Trace requests, exporting spans when an OTLP endpoint is configured
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Endpoint:    cfg.OTelEndpoint,
+		ServiceName: cfg.OTelServiceName,
+		Version:     Version,
+		Environment: cfg.Environment,
+		SampleRatio: cfg.TraceSampleRatio,
+	})
+	if err != nil {
+		slog.Error("Failed to set up tracing", "error", err)
+		return 1
+	}
+	defer func() {
+		// Flush the spans of the last requests
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("Failed to flush traces", "error", err)
+		}
+	}()
+
+	if cfg.JWTSecret == config.DefaultJWTSecret {
+		slog.Warn("Using the development JWT secret; set JWT_SECRET before deploying")
+	}
+
+	// Readiness checks are registered as dependencies come up
+	probes := health.NewRegistry()
 
 	// Connect to database
 	var db *database.DB
+	var broker *realtime.Broker
+	var presence *realtime.PresenceHub
+	var queue *jobs.Queue
+	var sched *scheduler.Scheduler
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var background sync.WaitGroup
 	if cfg.DatabaseURL != "" {
-		var err error
-		db, err = database.Connect(cfg.DatabaseURL)
+		db, err = database.Connect(cfg.DatabaseURL, databaseOptions(cfg))
 		if err != nil {
-			log.Fatalf("Failed to connect to database: %v", err)
+			slog.Error("Failed to connect to database", "error", err)
+			return 1
 		}
-		defer db.Close()
-	} else {
-		log.Println("⚠️  No DATABASE_URL provided, running without database")
+		defer db.Close() // Last, once requests and workers are done with it
+		metrics.RegisterDB(db)
+		probes.Register("database", db.Health)
+		probes.Register("migrations", func(ctx context.Context) error {
+			pending, err := db.PendingMigrations(ctx)
+			if err == nil && pending > 0 {
+				err = fmt.Errorf("%d pending migrations", pending)
+			}
+			return err
+		})
+
+		// Bring the schema up to date; instances starting together wait on a lock
+		if cfg.AutoMigrate {
+			if _, err := db.MigrateUp(context.Background()); err != nil {
+				slog.Error("Failed to migrate database", "error", err)
+				return 1
+			}
+		}
+
+		// Build everything that can reject the configuration before starting
+		// any worker, so a bad setting exits without leaving goroutines behind.
+		// Pub/sub for presence and the outbox is shared across instances
+		// unless configured in-process.
+		var pubsub realtime.PubSub
+		var pgPubSub *realtime.PostgresPubSub
+		if cfg.PresenceBackend == "memory" {
+			pubsub = realtime.NewMemoryPubSub()
+		} else {
+			pgPubSub = realtime.NewPostgresPubSub(db)
+			pubsub = pgPubSub
+		}
+
+		// In-process subscribers, run by the inprocess sink (on by default)
+		bus := outbox.NewBus()
+		bus.Subscribe(outbox.AllEvents, func(ctx context.Context, event *models.DomainEvent) error {
+			metrics.DomainEvent(string(event.Type))
+			return nil
+		})
+		sinks, err := outboxSinks(cfg.OutboxSinks, db, pubsub, bus)
+		if err != nil {
+			slog.Error("Invalid OUTBOX_SINKS", "error", err)
+			return 1
+		}
+
+		// Periodic maintenance, run by whichever instance holds the scheduler lock
+		sched, err = scheduler.New(db, scheduler.BuiltinTasks(db)...)
+		if err != nil {
+			slog.Error("Invalid scheduled task", "error", err)
+			return 1
+		}
+
+		// Deliver queued webhooks (local receivers are allowed in development)
+		dispatcher := webhooks.NewDispatcher(db, cfg.IsDevelopment())
+		background.Go(func() { dispatcher.Run(workerCtx) })
+
+		// Fan out item events to real-time subscribers
+		broker = realtime.NewBroker(db)
+		background.Go(func() { broker.Run(workerCtx) })
+
+		// Presence shares edit state with other instances over pub/sub
+		if pgPubSub != nil {
+			background.Go(func() { pgPubSub.Run(workerCtx) })
+		}
+		presence = realtime.NewPresenceHub(pubsub, handlers.ItemPresenceAuthorizer(db))
+		background.Go(func() { presence.Run(workerCtx) })
+
+		// Relay domain events committed to the outbox to the configured sinks
+		relay := outbox.NewRelay(db, sinks...)
+		background.Go(func() { relay.Run(workerCtx) })
+
+		// Run background jobs; they are drained on shutdown
+		queue = jobs.NewQueue(db, cfg.JobConcurrency)
+		jobs.RegisterBuiltins(queue, db)
+		go queue.Run(workerCtx)
+		probes.Register("job_queue", queue.Check)
+
+		go sched.Run(workerCtx)
+	}
+
+	// Auth and items work without a database, backed by memory
+	var st store.Store = db
+	if db == nil {
+		slog.Warn("No DATABASE_URL provided, running with in-memory store (data is lost on restart)")
+		st = store.NewMemory()
 	}
 
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
 		AppName:      "Monorepo API v" + Version,
-		ErrorHandler: errorHandler,
+		ErrorHandler: newErrorHandler(cfg.IsDevelopment()),
 	})
 
-	// Global middleware
+	// Global middleware: identify, trace, measure and log every request, including those that panic
+	app.Use(middleware.RequestID(cfg.TrustRequestID))
+	app.Use(middleware.Tracing())
+	app.Use(middleware.Metrics())
+	app.Use(middleware.RequestLogger(slog.Default()))
 	app.Use(recover.New())
 
-	// Logger middleware (development only)
-	if cfg.IsDevelopment() {
-		app.Use(logger.New(logger.Config{
-			Format: "[${time}] ${status} - ${method} ${path} (${latency})\n",
-		}))
-	}
+	// Security headers; routes adjust them with middleware.OverrideSecurityHeaders
+	app.Use(middleware.SecurityHeaders(middleware.SecurityPolicy{
+		HSTSMaxAge:            cfg.HSTSMaxAge,
+		HSTSIncludeSubdomains: cfg.HSTSIncludeSubdomains,
+		HSTSPreload:           cfg.HSTSPreload,
+		ContentSecurityPolicy: cfg.ContentSecurityPolicy,
+		ReferrerPolicy:        cfg.ReferrerPolicy,
+		PermissionsPolicy:     cfg.PermissionsPolicy,
+	}))
 
 	// CORS middleware
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     cfg.GetAllowedOrigins(),
 		AllowCredentials: true,
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"},
-		AllowHeaders:     []string{"Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length", "X-Request-Id"},
+		AllowMethods:     cfg.CORSAllowedMethods,
+		AllowHeaders:     cfg.CORSAllowedHeaders,
+		ExposeHeaders:    cfg.CORSExposedHeaders,
+		MaxAge:           int(cfg.CORSMaxAge.Seconds()),
 	}))
 
+	// Serve GET requests from read replicas when configured
+	if db != nil && db.HasReplicas() {
+		app.Use(middleware.ReplicaReads(db, cfg.JWTSecret, cfg.JWTPreviousSecrets...))
+	}
+
+	// Prometheus metrics, on an internal port when one is configured
+	metrics.SetBuildInfo(Version)
+	if cfg.MetricsPort != "" {
+		go func() {
+			if err := metrics.Serve(workerCtx, ":"+cfg.MetricsPort); err != nil {
+				slog.Error("Metrics server failed", "error", err)
+			}
+		}()
+	} else {
+		app.Get("/metrics", adaptor.HTTPHandler(metrics.Handler()))
+	}
+
 	// Setup routes
-	routes.SetupRoutes(app, cfg, db)
+	routes.SetupRoutes(app, cfg, db, st, broker, presence, queue, sched, probes)
+
+	// Startup is complete once the server accepts connections
+	app.Hooks().OnListen(func(fiber.ListenData) error {
+		probes.MarkStarted()
+		return nil
+	})
+
+	// On SIGINT/SIGTERM, fail readiness and keep serving for the pre-stop
+	// delay, then stop accepting connections and drain in-flight requests
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-signalCtx.Done()
+		stop() // A second signal terminates immediately
 
-	// Start server
+		slog.Info("Shutting down", "delay", cfg.ShutdownDelay, "timeout", cfg.ShutdownTimeout)
+		probes.MarkDraining()
+		time.Sleep(cfg.ShutdownDelay)
+
+		// Streams never finish on their own, so they are ended before the
+		// server drains; ones opened after this end at once, as a clean end of
+		// stream that clients reconnect from elsewhere
+		if broker != nil {
+			broker.Shutdown()
+		}
+		if presence != nil {
+			presence.Shutdown()
+		}
+		if err := app.ShutdownWithTimeout(cfg.ShutdownTimeout); err != nil {
+			slog.Warn("Requests still in flight at shutdown", "error", err)
+		}
+	}()
+
+	// Start server. If it cannot listen, the workers are still stopped and
+	// waited for before the pool closes.
 	port := cfg.Port
-	log.Printf("🚀 Server starting on port %s (env: %s)", port, cfg.Environment)
-	if err := app.Listen(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	status := 0
+	slog.Info("Server starting", "port", port, "env", cfg.Environment, "version", Version)
+	if err := app.Listen(":"+port, fiber.ListenConfig{DisableStartupMessage: !cfg.IsDevelopment()}); err != nil {
+		slog.Error("Failed to start server", "error", err)
+		status = 1
+	} else {
+		<-drained
 	}
-}
 
-// Global error handler
-func errorHandler(c fiber.Ctx, err error) error {
-	code := fiber.StatusInternalServerError
-	message := "Internal server error"
+	// Let running jobs, tasks and deliveries finish; interrupted jobs are
+	// retried by another instance
+	stopWorkers()
+	drainCtx, cancel := context.WithTimeout(context.Background(), jobDrainTimeout)
+	defer cancel()
+	if queue != nil {
+		if err := queue.Shutdown(drainCtx); err != nil {
+			slog.Warn("Job drain incomplete", "error", err)
+		}
+		if err := sched.Shutdown(drainCtx); err != nil {
+			slog.Warn("Scheduled tasks still running at shutdown", "error", err)
+		}
+	}
+	workersDone := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(workersDone)
+	}()
+	select {
+	case <-workersDone:
+	case <-drainCtx.Done():
+		slog.Warn("Background workers still running at shutdown")
+	}
+	slog.Info("Shutdown complete")
+	return status
+}
 
-	// Check for Fiber errors
-	if e, ok := err.(*fiber.Error); ok {
-		code = e.Code
-		message = e.Message
+// setupLogging makes the configured logger the default, which the standard
+// log package writes through too
+func setupLogging(cfg *config.Config) {
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	json := cfg.LogFormat == "json" || (cfg.LogFormat == "" && !cfg.IsDevelopment())
+	slog.SetDefault(logging.New(os.Stderr, level, json))
+	if err != nil {
+		slog.Warn("Invalid LOG_LEVEL, using info", "error", err)
 	}
+}
+
+// jobDrainTimeout bounds how long shutdown waits for running jobs
+const jobDrainTimeout = 30 * time.Second
+
+// Global error handler; detailed errors are shown in development
+func newErrorHandler(detailed bool) fiber.ErrorHandler {
+	return func(c fiber.Ctx, err error) error {
+		code := fiber.StatusInternalServerError
+		message := "Internal server error"
+
+		// Check for Fiber errors
+		if e, ok := err.(*fiber.Error); ok {
+			code = e.Code
+			message = e.Message
+		}
+		if code >= fiber.StatusInternalServerError {
+			logging.FromContext(c.Context()).Error("Request failed", "error", err)
+		}
+
+		// Development mode - show detailed errors
+		if detailed {
+			message = err.Error()
+		}
 
-	// Development mode - show detailed errors
-	if os.Getenv("ENVIRONMENT") == "development" {
-		message = err.Error()
+		response := models.ErrorResponse(message)
+		response.RequestID = middleware.GetRequestID(c)
+		return c.Status(code).JSON(response)
 	}
+}
 
-	return c.Status(code).JSON(fiber.Map{
-		"success": false,
-		"error":   message,
-	})
+// outboxSinks builds the outbox sinks named in the configuration
+func outboxSinks(names []string, db *database.DB, pubsub realtime.PubSub, bus *outbox.Bus) ([]outbox.Sink, error) {
+	sinks := make([]outbox.Sink, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case outbox.SinkStream:
+			sinks = append(sinks, outbox.NewStreamSink(db))
+		case outbox.SinkWebhooks:
+			sinks = append(sinks, outbox.NewWebhookSink(db))
+		case outbox.SinkInProcess:
+			sinks = append(sinks, bus)
+		case outbox.SinkPubSub:
+			sinks = append(sinks, outbox.NewPubSubSink(pubsub, "events."))
+		default:
+			return nil, fmt.Errorf("unknown sink %q", name)
+		}
+	}
+	return sinks, nil
 }
@@ -0,0 +1,161 @@
+// Package metrics exposes Prometheus metrics: HTTP requests, database pools
+// and queries, authentication outcomes, the Go runtime and build info.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every metric the server exports
+var Registry = prometheus.NewRegistry()
+
+// Results used as the "result" label of authentication metrics
+const (
+	Success = "success"
+	Failure = "failure"
+)
+
+var (
+	buildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "server_build_info",
+		Help: "Always 1; labelled with the server version and Go version.",
+	}, []string{"version", "go_version"})
+
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route template and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests currently being served, including open streams.",
+	})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Database query latency by DB method and kind (read, write, maintenance).",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"query", "kind"})
+
+	dbQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Failed database queries by DB method and kind.",
+	}, []string{"query", "kind"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_logins_total",
+		Help: "Password login attempts by result.",
+	}, []string{"result"})
+
+	tokenRefreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_token_refreshes_total",
+		Help: "Access token refreshes by result.",
+	}, []string{"result"})
+
+	domainEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "outbox_events_total",
+		Help: "Domain events relayed to in-process subscribers by type; an event retried after a failure counts again.",
+	}, []string{"type"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		buildInfo,
+		httpRequests, httpDuration, httpInFlight,
+		dbQueryDuration, dbQueryErrors,
+		logins, tokenRefreshes,
+		domainEvents,
+	)
+
+	// Export both results from the start so rates and ratios are defined
+	for _, result := range []string{Success, Failure} {
+		logins.WithLabelValues(result)
+		tokenRefreshes.WithLabelValues(result)
+	}
+}
+
+// SetBuildInfo records the running server version
+func SetBuildInfo(version string) {
+	buildInfo.WithLabelValues(version, runtime.Version()).Set(1)
+}
+
+// RequestStarted counts a request in flight; call the returned function when
+// it completes
+func RequestStarted() func(method, route string, status int) {
+	started := time.Now()
+	httpInFlight.Inc()
+	return func(method, route string, status int) {
+		httpInFlight.Dec()
+		code := strconv.Itoa(status)
+		httpRequests.WithLabelValues(method, route, code).Inc()
+		httpDuration.WithLabelValues(method, route, code).Observe(time.Since(started).Seconds())
+	}
+}
+
+// Login records a password login attempt
+func Login(result string) {
+	logins.WithLabelValues(result).Inc()
+}
+
+// TokenRefresh records an access token refresh
+func TokenRefresh(result string) {
+	tokenRefreshes.WithLabelValues(result).Inc()
+}
+
+// DomainEvent records a domain event relayed by the outbox
+func DomainEvent(eventType string) {
+	domainEvents.WithLabelValues(eventType).Inc()
+}
+
+// RegisterDB exports the database's pool statistics and query latencies
+func RegisterDB(db *database.DB) {
+	Registry.MustRegister(poolCollector{db: db})
+	db.OnQuery(func(stat database.QueryStat) {
+		kind := string(stat.Kind)
+		dbQueryDuration.WithLabelValues(stat.Name, kind).Observe(stat.Duration.Seconds())
+		if stat.Err != nil {
+			dbQueryErrors.WithLabelValues(stat.Name, kind).Inc()
+		}
+	})
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Serve serves /metrics on addr until ctx is done
+func Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", Handler())
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
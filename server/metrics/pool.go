@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	poolAcquiredConns = prometheus.NewDesc("db_pool_acquired_connections",
+		"Connections currently checked out of the pool.", []string{"pool"}, nil)
+	poolIdleConns = prometheus.NewDesc("db_pool_idle_connections",
+		"Idle connections in the pool.", []string{"pool"}, nil)
+	poolTotalConns = prometheus.NewDesc("db_pool_total_connections",
+		"Open connections in the pool, including ones being established.", []string{"pool"}, nil)
+	poolMaxConns = prometheus.NewDesc("db_pool_max_connections",
+		"Maximum size of the pool.", []string{"pool"}, nil)
+	poolAcquires = prometheus.NewDesc("db_pool_acquires_total",
+		"Successful connection acquisitions.", []string{"pool"}, nil)
+	poolEmptyAcquires = prometheus.NewDesc("db_pool_empty_acquires_total",
+		"Acquisitions that had to wait for a connection.", []string{"pool"}, nil)
+	poolCanceledAcquires = prometheus.NewDesc("db_pool_canceled_acquires_total",
+		"Acquisitions cancelled by their context while waiting.", []string{"pool"}, nil)
+	poolAcquireWait = prometheus.NewDesc("db_pool_acquire_wait_seconds_total",
+		"Time spent waiting for a connection when none was idle.", []string{"pool"}, nil)
+)
+
+// poolCollector reads the pgx pool statistics of the primary and each
+// replica at scrape time
+type poolCollector struct {
+	db *database.DB
+}
+
+func (c poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		poolAcquiredConns, poolIdleConns, poolTotalConns, poolMaxConns,
+		poolAcquires, poolEmptyAcquires, poolCanceledAcquires, poolAcquireWait,
+	} {
+		ch <- desc
+	}
+}
+
+func (c poolCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, stat := range c.db.PoolStats() {
+		ch <- prometheus.MustNewConstMetric(poolAcquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()), name)
+		ch <- prometheus.MustNewConstMetric(poolIdleConns, prometheus.GaugeValue, float64(stat.IdleConns()), name)
+		ch <- prometheus.MustNewConstMetric(poolTotalConns, prometheus.GaugeValue, float64(stat.TotalConns()), name)
+		ch <- prometheus.MustNewConstMetric(poolMaxConns, prometheus.GaugeValue, float64(stat.MaxConns()), name)
+		ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(stat.AcquireCount()), name)
+		ch <- prometheus.MustNewConstMetric(poolEmptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()), name)
+		ch <- prometheus.MustNewConstMetric(poolCanceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()), name)
+		ch <- prometheus.MustNewConstMetric(poolAcquireWait, prometheus.CounterValue, stat.EmptyAcquireWaitTime().Seconds(), name)
+	}
+}
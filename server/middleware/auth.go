@@ -8,8 +8,9 @@ import (
 	"github.com/gofiber/fiber/v3"
 )
 
-// AuthMiddleware validates JWT tokens and attaches user info to context
-func AuthMiddleware(jwtSecret string) fiber.Handler {
+// AuthMiddleware validates JWT tokens and attaches user info to context.
+// Tokens signed with a previous secret are accepted during a rotation.
+func AuthMiddleware(jwtSecret string, previousSecrets ...string) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
@@ -21,7 +22,7 @@ func AuthMiddleware(jwtSecret string) fiber.Handler {
 			return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Invalid authorization header format"))
 		}
 
-		claims, err := utils.ValidateToken(tokenString, jwtSecret)
+		claims, err := utils.ValidateToken(tokenString, jwtSecret, previousSecrets...)
 		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse("Invalid or expired token"))
 		}
@@ -35,11 +36,27 @@ func AuthMiddleware(jwtSecret string) fiber.Handler {
 		c.Locals("userID", claims.UserID)
 		c.Locals("userEmail", claims.Email)
 		c.Locals("userRole", claims.Role)
+		c.Locals("sessionID", claims.SessionID)
 
 		return c.Next()
 	}
 }
 
+// TokenFromQuery copies an access token from a query parameter into the
+// Authorization header when the header is absent. It is meant for endpoints
+// such as EventSource streams where browsers cannot set request headers, and
+// must run before AuthMiddleware.
+func TokenFromQuery(param string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if c.Get("Authorization") == "" {
+			if token := c.Query(param); token != "" {
+				c.Request().Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		return c.Next()
+	}
+}
+
 // GetUserID retrieves the authenticated user ID from context
 func GetUserID(c fiber.Ctx) string {
 	if userID, ok := c.Locals("userID").(string); ok {
@@ -48,6 +65,14 @@ func GetUserID(c fiber.Ctx) string {
 	return ""
 }
 
+// GetSessionID retrieves the session the access token was issued for, if any
+func GetSessionID(c fiber.Ctx) string {
+	if sessionID, ok := c.Locals("sessionID").(string); ok {
+		return sessionID
+	}
+	return ""
+}
+
 // GetUserRole retrieves the authenticated user role from context
 func GetUserRole(c fiber.Ctx) models.UserRole {
 	if role, ok := c.Locals("userRole").(models.UserRole); ok {
@@ -72,20 +97,10 @@ func RequireRole(allowedRoles ...models.UserRole) fiber.Handler {
 }
 
 // OptionalAuth middleware that doesn't fail if token is missing
-func OptionalAuth(jwtSecret string) fiber.Handler {
+func OptionalAuth(jwtSecret string, previousSecrets ...string) fiber.Handler {
 	return func(c fiber.Ctx) error {
-		authHeader := c.Get("Authorization")
-		if authHeader == "" {
-			return c.Next()
-		}
-
-		tokenString, err := utils.ExtractBearerToken(authHeader)
-		if err != nil {
-			return c.Next()
-		}
-
-		claims, err := utils.ValidateToken(tokenString, jwtSecret)
-		if err != nil || claims.Type != models.TokenTypeAccess {
+		claims := optionalClaims(c, jwtSecret, previousSecrets)
+		if claims == nil {
 			return c.Next()
 		}
 
@@ -93,11 +108,26 @@ func OptionalAuth(jwtSecret string) fiber.Handler {
 		c.Locals("userID", claims.UserID)
 		c.Locals("userEmail", claims.Email)
 		c.Locals("userRole", claims.Role)
+		c.Locals("sessionID", claims.SessionID)
 
 		return c.Next()
 	}
 }
 
+// optionalClaims returns the claims of a valid access token in the
+// Authorization header, or nil if there is none
+func optionalClaims(c fiber.Ctx, jwtSecret string, previousSecrets []string) *utils.JWTClaims {
+	tokenString, err := utils.ExtractBearerToken(c.Get("Authorization"))
+	if err != nil {
+		return nil
+	}
+	claims, err := utils.ValidateToken(tokenString, jwtSecret, previousSecrets...)
+	if err != nil || claims.Type != models.TokenTypeAccess {
+		return nil
+	}
+	return claims
+}
+
 // GetClientIP extracts the client IP address
 func GetClientIP(c fiber.Ctx) string {
 	// Check X-Forwarded-For header first (for proxies)
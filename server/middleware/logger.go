@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/logging"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestLogger logs one line per request and puts logger in the request
+// context for handlers and the database layer, tagged with the request ID set
+// by RequestID and the trace and span IDs of Tracing's span. Requests are logged by route
+// template rather than path, since some paths carry secrets (calendar feed
+// tokens).
+func RequestLogger(logger *slog.Logger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		started := time.Now()
+		ctx := logging.WithContext(c.Context(), logger)
+		if id := utils.RequestIDFromContext(ctx); id != "" {
+			ctx = logging.WithRequestID(ctx, id)
+		}
+		if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+			logger := logging.FromContext(ctx).With("trace_id", span.TraceID().String(), "span_id", span.SpanID().String())
+			ctx = logging.WithContext(ctx, logger)
+		}
+		c.SetContext(ctx)
+
+		// Handle errors here so the logged status is the one sent
+		if err := c.Next(); err != nil {
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("route", c.Route().Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(started).Microseconds())/1000),
+			slog.String("ip", GetClientIP(c)),
+		}
+		if userID := GetUserID(c); userID != "" {
+			attrs = append(attrs, slog.String("user_id", userID))
+		}
+		if sessionID := GetSessionID(c); sessionID != "" {
+			attrs = append(attrs, slog.String("session_id", sessionID))
+		}
+
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logging.FromContext(c.Context()).LogAttrs(c.Context(), level, "Request", attrs...)
+		return nil
+	}
+}
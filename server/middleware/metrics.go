@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/binduni/bun-golang-react-monorepo/server/metrics"
+	"github.com/gofiber/fiber/v3"
+)
+
+// Metrics records the rate, errors and duration of requests by route
+// template. Requests matching no route are counted as "unmatched", so
+// scanners cannot create new label values. Place it outside RequestLogger,
+// which turns errors into responses, so the final status is recorded.
+func Metrics() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		done := metrics.RequestStarted()
+		err := c.Next()
+		route := c.Route().Path
+		if !c.Matched() {
+			route = "unmatched"
+		}
+		done(c.Method(), route, c.Response().StatusCode())
+		return err
+	}
+}
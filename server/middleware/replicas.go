@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/gofiber/fiber/v3"
+)
+
+// ReplicaReads lets GET and HEAD requests read from replicas. Any other
+// request pins the client to the primary until replicas have caught up with
+// its writes, so it always reads what it wrote.
+func ReplicaReads(db *database.DB, jwtSecret string, previousSecrets ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		// Signed-in clients are told apart by user, so the pin survives token
+		// refreshes and new logins; anonymous ones by address
+		var session string
+		if claims := optionalClaims(c, jwtSecret, previousSecrets); claims != nil {
+			session = "user:" + claims.UserID
+		} else {
+			session = "ip:" + GetClientIP(c)
+		}
+
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead:
+			c.SetContext(db.ReadFromReplicas(c.Context(), session))
+		default:
+			db.PinToPrimary(session)
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+	"github.com/gofiber/fiber/v3"
+)
+
+// RequestID assigns every request an ID, returned in the X-Request-Id header
+// and in error response bodies and carried in the request context for logs,
+// database transactions and outgoing calls. A well-formed incoming
+// X-Request-Id is adopted when trustIncoming is set, e.g. behind a proxy that
+// assigns IDs; otherwise a fresh req_ TypeID is generated.
+func RequestID(trustIncoming bool) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id := c.Get(utils.HeaderRequestID)
+		if !trustIncoming || !utils.ValidRequestID(id) {
+			id = utils.NewRequestID()
+		} else {
+			// Header values alias buffers fasthttp reuses after the request
+			id = strings.Clone(id)
+		}
+		c.Set(utils.HeaderRequestID, id)
+		c.SetContext(utils.WithRequestID(c.Context(), id))
+
+		err := c.Next()
+		if err == nil && c.Response().StatusCode() >= fiber.StatusBadRequest {
+			addRequestID(c, id)
+		}
+		return err
+	}
+}
+
+// GetRequestID returns the request's ID
+func GetRequestID(c fiber.Ctx) string {
+	return utils.RequestIDFromContext(c.Context())
+}
+
+// addRequestID sets requestId in a JSON error response. Bodies that are not
+// an ApiResponse are left alone.
+func addRequestID(c fiber.Ctx, id string) {
+	if !bytes.HasPrefix(c.Response().Header.ContentType(), []byte(fiber.MIMEApplicationJSON)) {
+		return
+	}
+	decoder := json.NewDecoder(bytes.NewReader(c.Response().Body()))
+	decoder.DisallowUnknownFields()
+	var body models.ApiResponse[json.RawMessage]
+	if err := decoder.Decode(&body); err != nil || body.Success || body.RequestID != "" {
+		return
+	}
+	body.RequestID = id
+	if data, err := json.Marshal(body); err == nil {
+		c.Response().SetBodyRaw(data)
+	}
+}
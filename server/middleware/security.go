@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// SecurityPolicy is the security headers sent with responses. An empty
+// value omits its header; X-Content-Type-Options: nosniff is always sent.
+type SecurityPolicy struct {
+	// HSTSMaxAge sends Strict-Transport-Security when positive. Browsers
+	// ignore it over plain HTTP, so it is safe behind a TLS-terminating proxy.
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+
+	ContentSecurityPolicy string
+	ReferrerPolicy        string
+	PermissionsPolicy     string
+}
+
+// SecurityHeaders sets the policy's headers on every response, before the
+// handler runs so they survive errors and panics
+func SecurityHeaders(policy SecurityPolicy) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		c.Locals("securityPolicy", policy)
+		policy.apply(c)
+		return c.Next()
+	}
+}
+
+// OverrideSecurityHeaders adjusts the policy for the routes it is mounted
+// on, starting from the one SecurityHeaders applied
+func OverrideSecurityHeaders(override func(*SecurityPolicy)) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		policy, _ := c.Locals("securityPolicy").(SecurityPolicy)
+		override(&policy)
+		c.Locals("securityPolicy", policy)
+		policy.apply(c)
+		return c.Next()
+	}
+}
+
+func (p SecurityPolicy) apply(c fiber.Ctx) {
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	setHeader(c, fiber.HeaderStrictTransportSecurity, p.hsts())
+	setHeader(c, fiber.HeaderContentSecurityPolicy, p.ContentSecurityPolicy)
+	setHeader(c, fiber.HeaderReferrerPolicy, p.ReferrerPolicy)
+	setHeader(c, fiber.HeaderPermissionsPolicy, p.PermissionsPolicy)
+}
+
+func (p SecurityPolicy) hsts() string {
+	if p.HSTSMaxAge <= 0 {
+		return ""
+	}
+	value := "max-age=" + strconv.Itoa(int(p.HSTSMaxAge.Seconds()))
+	if p.HSTSIncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if p.HSTSPreload {
+		value += "; preload"
+	}
+	return value
+}
+
+// setHeader sets a response header, or removes it when value is empty
+func setHeader(c fiber.Ctx, key, value string) {
+	if value == "" {
+		c.Response().Header.Del(key)
+		return
+	}
+	c.Set(key, value)
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"github.com/binduni/bun-golang-react-monorepo/server/tracing"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span for each request, continuing the trace of an
+// incoming W3C traceparent header. Spans are named by route template, like
+// "GET /api/items/:id". Place it outside RequestLogger so the final status is
+// recorded and log lines carry the trace ID.
+func Tracing() fiber.Handler {
+	tracer := tracing.Tracer("middleware")
+	return func(c fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.Context(), headerCarrier{c})
+		ctx, span := tracer.Start(ctx, c.Method(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Method()),
+				semconv.ClientAddress(GetClientIP(c)),
+				attribute.String("request.id", utils.RequestIDFromContext(ctx)),
+			),
+		)
+		defer span.End()
+		c.SetContext(ctx)
+
+		err := c.Next()
+
+		route := c.Route().Path
+		if !c.Matched() {
+			route = "unmatched"
+		} else {
+			span.SetName(c.Method() + " " + route)
+		}
+		status := c.Response().StatusCode()
+		span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+		}
+		return err
+	}
+}
+
+// headerCarrier reads propagation headers from a Fiber request
+type headerCarrier struct {
+	c fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string { return h.c.Get(key) }
+
+func (h headerCarrier) Set(string, string) {}
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0)
+	for key := range h.c.Request().Header.All() {
+		keys = append(keys, string(key))
+	}
+	return keys
+}
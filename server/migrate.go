@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+)
+
+const migrateUsage = `Usage: server migrate <command>
+
+Commands:
+  up          Apply all pending migrations
+  down [n]    Revert the last n applied migrations (default 1)
+  status      List migrations and whether they are applied
+  redo        Revert and re-apply the last applied migration`
+
+// runMigrate runs a migrate subcommand and returns the process exit code
+func runMigrate(cfg *config.Config, args []string) int {
+	if len(args) == 0 || !slices.Contains([]string{"up", "down", "status", "redo"}, args[0]) {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return fail("%v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp(ctx)
+		if err != nil {
+			return fail("migration failed: %v", err)
+		}
+		fmt.Printf("%d migration(s) applied\n", len(applied))
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				fmt.Fprintln(os.Stderr, "down expects a positive number of migrations")
+				return 2
+			}
+		}
+		reverted, err := db.MigrateDown(ctx, steps)
+		if err != nil {
+			return fail("migration failed: %v", err)
+		}
+		fmt.Printf("%d migration(s) reverted\n", len(reverted))
+
+	case "redo":
+		if _, err := db.MigrateRedo(ctx); err != nil {
+			return fail("migration failed: %v", err)
+		}
+
+	case "status":
+		statuses, err := db.GetMigrationStatus(ctx)
+		if err != nil {
+			return fail("failed to read migration status: %v", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			state, appliedAt := "pending", ""
+			if s.AppliedAt != nil {
+				state, appliedAt = "applied", s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			switch {
+			case s.Unknown:
+				state = "applied (unknown)"
+			case s.Drifted:
+				state = "applied (modified)"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+		}
+		w.Flush()
+	}
+	return 0
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -49,15 +50,87 @@ const (
 )
 
 type Item struct {
-	ID          string     `json:"id"` // TypeID: item_xxx
-	UserID      string     `json:"userId"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	Status      ItemStatus `json:"status"`
-	CreatedAt   time.Time  `json:"createdAt"`
-	UpdatedAt   time.Time  `json:"updatedAt"`
+	ID           string     `json:"id"` // TypeID: item_xxx
+	UserID       string     `json:"userId"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	Status       ItemStatus `json:"status"`
+	ExternalID   *string    `json:"externalId,omitempty"` // Caller-supplied ID for import upserts
+	ParentID     *string    `json:"parentId,omitempty"`   // TypeID: item_xxx (subtasks only)
+	Rank         string     `json:"rank"`                 // Manual sort key; lists are ordered by rank ascending
+	DueAt        *time.Time `json:"dueAt,omitempty"`
+	Tags         []string   `json:"tags"`
+	SeriesID     *string    `json:"seriesId,omitempty"`     // TypeID: series_xxx (recurring items only)
+	RecurrenceID *time.Time `json:"recurrenceId,omitempty"` // Originally scheduled time of this occurrence
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+
+	// Computed on read for items that have subtasks
+	Progress *ItemProgress `json:"progress,omitempty"`
+}
+
+// ItemProgress rolls up the status of all (non-archived) descendants of an item
+type ItemProgress struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Percent   int `json:"percent"`
+}
+
+// ItemNode is an item with its nested subtasks, as returned by subtree queries
+type ItemNode struct {
+	*Item
+	Depth    int         `json:"depth"` // 0 for the subtree root
+	Children []*ItemNode `json:"children"`
+}
+
+// ItemFilter narrows item listings; empty fields match everything
+type ItemFilter struct {
+	Statuses   []ItemStatus
+	Tags       []string // Items must carry at least one of these tags
+	HasDueDate bool
 }
 
+// ItemImportReport summarizes a bulk import (or its dry run)
+type ItemImportReport struct {
+	Format    string               `json:"format"`
+	DryRun    bool                 `json:"dryRun"`
+	Committed bool                 `json:"committed"`
+	Total     int                  `json:"total"`
+	Valid     int                  `json:"valid"`
+	Created   int                  `json:"created"`
+	Updated   int                  `json:"updated"`
+	Errors    []ItemImportRowError `json:"errors"`
+}
+
+// ItemImportRowError describes why a single import row was rejected
+type ItemImportRowError struct {
+	Row     int    `json:"row"` // 1-based data row (excluding any CSV header)
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ItemSeries is the recurrence template shared by all occurrences of a recurring item
+type ItemSeries struct {
+	ID          string      `json:"id"` // TypeID: series_xxx
+	UserID      string      `json:"userId"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	RRule       string      `json:"rrule"`    // RFC 5545 RRULE value, e.g. FREQ=WEEKLY;BYDAY=MO
+	Timezone    string      `json:"timezone"` // IANA timezone used to expand the rule
+	DTStart     time.Time   `json:"dtstart"`
+	ExDates     []time.Time `json:"exdates"` // Skipped occurrences
+	CreatedAt   time.Time   `json:"createdAt"`
+	UpdatedAt   time.Time   `json:"updatedAt"`
+}
+
+// ItemEditScope selects which occurrences of a recurring item an update applies to
+type ItemEditScope string
+
+const (
+	ItemEditScopeThis   ItemEditScope = "this"
+	ItemEditScopeFuture ItemEditScope = "future"
+)
+
 // ============================================================================
 // OAuth Models
 // ============================================================================
@@ -82,6 +155,312 @@ type OAuthAccount struct {
 	UpdatedAt         time.Time     `json:"updatedAt"`
 }
 
+// ============================================================================
+// Item Event Models
+// ============================================================================
+
+type ItemEventType string
+
+const (
+	ItemEventCreated  ItemEventType = "item.created"
+	ItemEventUpdated  ItemEventType = "item.updated"
+	ItemEventDeleted  ItemEventType = "item.deleted"
+	ItemEventImported ItemEventType = "items.imported"
+)
+
+// ItemEvent is a change to a user's items, streamed to clients over SSE
+type ItemEvent struct {
+	ID        int64           `json:"id"`
+	UserID    string          `json:"userId"`
+	Type      ItemEventType   `json:"type"`
+	ItemID    *string         `json:"itemId,omitempty"`
+	Payload   json.RawMessage `json:"payload"` // Item snapshot (or summary for bulk events)
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// ============================================================================
+// Calendar Feed Models
+// ============================================================================
+
+type CalendarFeed struct {
+	UserID    string    `json:"userId"`
+	TokenHash string    `json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CalendarFeedResponse is returned once when a feed token is (re)generated
+type CalendarFeedResponse struct {
+	URL       string    `json:"url"`
+	WebcalURL string    `json:"webcalUrl"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ============================================================================
+// Domain Event Models
+// ============================================================================
+
+type DomainEventType string
+
+const (
+	EventItemCreated    DomainEventType = "item.created"
+	EventItemUpdated    DomainEventType = "item.updated"
+	EventItemDeleted    DomainEventType = "item.deleted"
+	EventItemsImported  DomainEventType = "items.imported"
+	EventUserRegistered DomainEventType = "user.registered"
+	EventUserLogin      DomainEventType = "user.login"
+	EventSessionRevoked DomainEventType = "session.revoked"
+)
+
+// Aggregate types domain events refer to
+const (
+	AggregateItem    = "item"
+	AggregateUser    = "user"
+	AggregateSession = "session"
+)
+
+// DomainEvent is a state change recorded in the outbox in the same
+// transaction as the change itself, then relayed to sinks
+type DomainEvent struct {
+	Seq           int64           `json:"seq"`
+	ID            string          `json:"id"` // TypeID: evt_xxx (stable across redeliveries)
+	Type          DomainEventType `json:"type"`
+	AggregateType string          `json:"aggregateType"`
+	AggregateID   string          `json:"aggregateId"`
+	UserID        *string         `json:"userId,omitempty"`
+	RequestID     *string         `json:"requestId,omitempty"` // API request that raised the event
+	Payload       json.RawMessage `json:"payload"`
+	Attempts      int             `json:"-"`
+	CreatedAt     time.Time       `json:"createdAt"`
+}
+
+// ============================================================================
+// Job Models
+// ============================================================================
+
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "pending"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusCompleted JobStatus = "completed"
+	JobStatusFailed    JobStatus = "failed" // Out of attempts or failed permanently
+	JobStatusCancelled JobStatus = "cancelled"
+)
+
+// Job is a unit of background work run by a worker registered for its kind
+type Job struct {
+	ID          string          `json:"id"` // TypeID: job_xxx
+	Kind        string          `json:"kind"`
+	Args        json.RawMessage `json:"args"`
+	Priority    int             `json:"priority"`
+	RunAt       time.Time       `json:"runAt"`
+	UniqueKey   *string         `json:"uniqueKey,omitempty"`
+	Status      JobStatus       `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"maxAttempts"`
+	LockedUntil *time.Time      `json:"lockedUntil,omitempty"`
+	LastError   *string         `json:"lastError,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+	FinishedAt  *time.Time      `json:"finishedAt,omitempty"`
+}
+
+// JobStats counts jobs per kind and status
+type JobStats struct {
+	Kind   string    `json:"kind"`
+	Status JobStatus `json:"status"`
+	Count  int       `json:"count"`
+}
+
+// ============================================================================
+// Scheduled Task Models
+// ============================================================================
+
+type TaskStatus string
+
+const (
+	TaskStatusRunning   TaskStatus = "running"
+	TaskStatusSucceeded TaskStatus = "succeeded"
+	TaskStatusFailed    TaskStatus = "failed"
+)
+
+// ScheduledTask is the shared state of a cron task
+type ScheduledTask struct {
+	Name           string      `json:"name"`
+	Schedule       string      `json:"schedule"`
+	NextRunAt      time.Time   `json:"nextRunAt"`
+	LastRunAt      *time.Time  `json:"lastRunAt,omitempty"`
+	LastFinishedAt *time.Time  `json:"lastFinishedAt,omitempty"`
+	LastStatus     *TaskStatus `json:"lastStatus,omitempty"`
+	LastError      *string     `json:"lastError,omitempty"`
+	LastDurationMs *int        `json:"lastDurationMs,omitempty"`
+	LastRunBy      *string     `json:"lastRunBy,omitempty"`
+	RunCount       int         `json:"runCount"`
+	FailureCount   int         `json:"failureCount"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	UpdatedAt      time.Time   `json:"updatedAt"`
+}
+
+// ============================================================================
+// Webhook Models
+// ============================================================================
+
+type WebhookEventType string
+
+const (
+	WebhookEventItemCreated    WebhookEventType = "item.created"
+	WebhookEventItemUpdated    WebhookEventType = "item.updated"
+	WebhookEventItemDeleted    WebhookEventType = "item.deleted"
+	WebhookEventItemsImported  WebhookEventType = "items.imported"
+	WebhookEventUserLogin      WebhookEventType = "user.login"
+	WebhookEventUserRegistered WebhookEventType = "user.registered" // Admin endpoints only
+	WebhookEventSessionRevoked WebhookEventType = "session.revoked"
+	WebhookEventPing           WebhookEventType = "webhook.ping"
+	WebhookEventAll            WebhookEventType = "*"
+)
+
+// WebhookEventTypes lists the event types endpoints can subscribe to, and
+// whether a subscription requires the admin role
+var WebhookEventTypes = map[WebhookEventType]bool{
+	WebhookEventItemCreated:    false,
+	WebhookEventItemUpdated:    false,
+	WebhookEventItemDeleted:    false,
+	WebhookEventItemsImported:  false,
+	WebhookEventUserLogin:      false,
+	WebhookEventUserRegistered: true,
+	WebhookEventSessionRevoked: false,
+	WebhookEventPing:           false,
+}
+
+type WebhookEndpoint struct {
+	ID                      string             `json:"id"` // TypeID: whk_xxx
+	UserID                  string             `json:"userId"`
+	URL                     string             `json:"url"`
+	Description             string             `json:"description"`
+	EventTypes              []WebhookEventType `json:"eventTypes"`
+	Enabled                 bool               `json:"enabled"`
+	Secret                  string             `json:"-"`
+	PreviousSecret          *string            `json:"-"`
+	PreviousSecretExpiresAt *time.Time         `json:"previousSecretExpiresAt,omitempty"`
+	CreatedAt               time.Time          `json:"createdAt"`
+	UpdatedAt               time.Time          `json:"updatedAt"`
+}
+
+// SigningSecrets returns the secrets deliveries are currently signed with
+func (e *WebhookEndpoint) SigningSecrets(now time.Time) []string {
+	secrets := []string{e.Secret}
+	if e.PreviousSecret != nil && e.PreviousSecretExpiresAt != nil && now.Before(*e.PreviousSecretExpiresAt) {
+		secrets = append(secrets, *e.PreviousSecret)
+	}
+	return secrets
+}
+
+// WebhookEndpointWithSecret is returned once when an endpoint is created or its secret rotated
+type WebhookEndpointWithSecret struct {
+	*WebhookEndpoint
+	Secret string `json:"secret"`
+}
+
+type WebhookEndpointRequest struct {
+	URL         *string            `json:"url"`
+	Description *string            `json:"description"`
+	EventTypes  []WebhookEventType `json:"eventTypes"`
+	Enabled     *bool              `json:"enabled"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryDead      WebhookDeliveryStatus = "dead" // Retries exhausted
+)
+
+type WebhookDelivery struct {
+	ID             string                    `json:"id"` // TypeID: whd_xxx
+	EndpointID     string                    `json:"endpointId"`
+	EventID        string                    `json:"eventId"`
+	EventType      WebhookEventType          `json:"eventType"`
+	RequestID      *string                   `json:"requestId,omitempty"` // Sent as X-Request-Id
+	Payload        json.RawMessage           `json:"payload"`
+	Status         WebhookDeliveryStatus     `json:"status"`
+	Attempts       int                       `json:"attempts"`
+	NextAttemptAt  time.Time                 `json:"nextAttemptAt"`
+	LastAttemptAt  *time.Time                `json:"lastAttemptAt,omitempty"`
+	ResponseStatus *int                      `json:"responseStatus,omitempty"`
+	LastError      *string                   `json:"lastError,omitempty"`
+	CreatedAt      time.Time                 `json:"createdAt"`
+	CompletedAt    *time.Time                `json:"completedAt,omitempty"`
+	AttemptLog     []*WebhookDeliveryAttempt `json:"attemptLog,omitempty"`
+}
+
+type WebhookDeliveryAttempt struct {
+	ID             int64     `json:"id"`
+	DeliveryID     string    `json:"deliveryId"`
+	Attempt        int       `json:"attempt"`
+	ResponseStatus *int      `json:"responseStatus,omitempty"`
+	ResponseBody   *string   `json:"responseBody,omitempty"`
+	Error          *string   `json:"error,omitempty"`
+	DurationMs     int       `json:"durationMs"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// WebhookEvent is the JSON body POSTed to webhook endpoints
+type WebhookEvent struct {
+	ID        string           `json:"id"` // TypeID: evt_xxx
+	Type      WebhookEventType `json:"type"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Data      any              `json:"data"`
+}
+
+// ============================================================================
+// Presence Models
+// ============================================================================
+
+type PresenceMessageType string
+
+const (
+	// Client to server
+	PresenceJoin   PresenceMessageType = "join"
+	PresenceLeave  PresenceMessageType = "leave"
+	PresenceTyping PresenceMessageType = "typing"
+	PresenceLock   PresenceMessageType = "lock"
+	PresenceUnlock PresenceMessageType = "unlock"
+	PresencePing   PresenceMessageType = "ping"
+
+	// Server to client (PresenceLock is also the reply to a lock request)
+	PresenceSnapshot PresenceMessageType = "presence"
+	PresenceLockLost PresenceMessageType = "lockLost"
+	PresenceError    PresenceMessageType = "error"
+	PresencePong     PresenceMessageType = "pong"
+)
+
+// PresenceMember is one connection viewing an item
+type PresenceMember struct {
+	ConnectionID string     `json:"connectionId"`
+	UserID       string     `json:"userId"`
+	Name         string     `json:"name"`
+	Typing       bool       `json:"typing"`
+	Editing      bool       `json:"editing"`
+	JoinedAt     time.Time  `json:"joinedAt"`
+	LockedAt     *time.Time `json:"lockedAt,omitempty"`
+}
+
+type PresenceClientMessage struct {
+	Type   PresenceMessageType `json:"type"`
+	ItemID string              `json:"itemId"`
+	Typing bool                `json:"typing"`
+}
+
+type PresenceServerMessage struct {
+	Type     PresenceMessageType `json:"type"`
+	ItemID   string              `json:"itemId,omitempty"`
+	Members  []PresenceMember    `json:"members,omitempty"`
+	LockedBy *PresenceMember     `json:"lockedBy,omitempty"`
+	Granted  *bool               `json:"granted,omitempty"`
+	Error    string              `json:"error,omitempty"`
+}
+
 // ============================================================================
 // Session Models
 // ============================================================================
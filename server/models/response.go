@@ -6,6 +6,9 @@ type ApiResponse[T any] struct {
 	Data    *T     `json:"data,omitempty"`
 	Error   string `json:"error,omitempty"`
 	Message string `json:"message,omitempty"`
+
+	// RequestID identifies the request in server logs; set on error responses
+	RequestID string `json:"requestId,omitempty"`
 }
 
 // SuccessResponse creates a successful API response
@@ -0,0 +1,183 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/logging"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// MaxAttempts is how many relay attempts an event gets before it is marked failed
+	MaxAttempts = 15
+
+	// Retention is how long published events are kept (see the outbox.prune task)
+	Retention = 7 * 24 * time.Hour
+
+	batchSize    = 100
+	claimLease   = 2 * time.Minute
+	pollInterval = 5 * time.Second
+	retryBase    = time.Second
+	retryMax     = 10 * time.Minute
+)
+
+// Sink receives relayed domain events. Deliveries are at-least-once: sinks
+// must deduplicate on DomainEvent.ID.
+type Sink interface {
+	Name() string
+	Deliver(ctx context.Context, event *models.DomainEvent) error
+}
+
+// TransactionalSink is a Sink that only writes to this database through ctx.
+// Its Deliver runs in a transaction with the record of the delivery, which
+// makes it exactly-once; other sinks run outside any transaction.
+type TransactionalSink interface {
+	Sink
+	Transactional()
+}
+
+// NewEvent builds a domain event for RecordDomainEvent
+func NewEvent(eventType models.DomainEventType, aggregateType, aggregateID string, userID *string) *models.DomainEvent {
+	return &models.DomainEvent{
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		UserID:        userID,
+	}
+}
+
+// Relay moves committed outbox events to sinks. Several relays may run
+// against one database; each claims events with a lease, so no transaction
+// or row lock is held while sinks run. Each sink's delivery is tracked
+// separately so a failing sink only retries itself.
+type Relay struct {
+	db    *database.DB
+	sinks []Sink
+}
+
+func NewRelay(db *database.DB, sinks ...Sink) *Relay {
+	return &Relay{
+		db:    db,
+		sinks: sinks,
+	}
+}
+
+// Run relays events until ctx is done, waking on commit notifications and
+// polling as a fallback
+func (r *Relay) Run(ctx context.Context) {
+	wake := make(chan struct{}, 1)
+	go r.db.ListenLoop(ctx, database.OutboxChannel, func(*pgconn.Notification) {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	})
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		relayed, err := r.relayBatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Outbox relay failed", "error", err)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if relayed == batchSize {
+			continue // More events are probably waiting
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// relayBatch claims a batch, commits the claim, then delivers and records
+// each event. Events left unrecorded (e.g. on shutdown) are claimed again
+// once their lease expires.
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	events, err := r.db.ClaimDomainEvents(ctx, batchSize, claimLease)
+	if err != nil {
+		return 0, fmt.Errorf("claim events: %w", err)
+	}
+
+	for _, event := range events {
+		if err := r.relayEvent(ctx, event); err != nil {
+			return len(events), err
+		}
+	}
+	return len(events), nil
+}
+
+// relayEvent delivers an event to each sink that has not received it yet.
+// Sink failures are recorded on the event; only database errors are returned.
+func (r *Relay) relayEvent(ctx context.Context, event *models.DomainEvent) error {
+	delivered, err := r.db.GetDeliveredSinks(ctx, event.Seq)
+	if err != nil {
+		return err
+	}
+
+	// Sinks see the request that raised the event, e.g. for webhook deliveries
+	if event.RequestID != nil {
+		ctx = logging.WithRequestID(ctx, *event.RequestID)
+	}
+
+	var failures []string
+	for _, sink := range r.sinks {
+		if delivered[sink.Name()] {
+			continue
+		}
+		if err := r.deliver(ctx, sink, event); err != nil {
+			failures = append(failures, sink.Name()+": "+err.Error())
+		}
+	}
+
+	if len(failures) == 0 {
+		return r.db.MarkDomainEventPublished(ctx, event.Seq)
+	}
+
+	attempts := event.Attempts + 1
+	failed := attempts >= MaxAttempts
+	if failed {
+		logging.FromContext(ctx).Error("Outbox event failed permanently", "event_id", event.ID, "type", event.Type, "attempts", attempts, "error", strings.Join(failures, "; "))
+	}
+	return r.db.MarkDomainEventRetry(ctx, event.Seq, strings.Join(failures, "; "), time.Now().Add(backoff(attempts)), failed)
+}
+
+// deliver hands an event to one sink and records the delivery
+func (r *Relay) deliver(ctx context.Context, sink Sink, event *models.DomainEvent) error {
+	if _, ok := sink.(TransactionalSink); ok {
+		return r.db.WithTx(ctx, func(ctx context.Context) error {
+			if err := sink.Deliver(ctx, event); err != nil {
+				return err
+			}
+			return r.db.MarkSinkDelivered(ctx, event.Seq, sink.Name())
+		})
+	}
+	if err := sink.Deliver(ctx, event); err != nil {
+		return err
+	}
+	return r.db.MarkSinkDelivered(ctx, event.Seq, sink.Name())
+}
+
+// backoff doubles from one second up to ten minutes, with ±20% jitter
+func backoff(attempt int) time.Duration {
+	delay := retryMax
+	if attempt < 20 {
+		delay = min(retryBase<<(attempt-1), retryMax)
+	}
+	spread := int64(delay) / 5
+	return delay + time.Duration(rand.Int64N(2*spread+1)-spread)
+}
@@ -0,0 +1,155 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/realtime"
+)
+
+// Sink names, as used in the OUTBOX_SINKS setting
+const (
+	SinkStream    = "stream"
+	SinkWebhooks  = "webhooks"
+	SinkInProcess = "inprocess"
+	SinkPubSub    = "pubsub"
+)
+
+// StreamSink appends item events to the item event log behind the SSE stream
+type StreamSink struct {
+	db *database.DB
+}
+
+func NewStreamSink(db *database.DB) *StreamSink {
+	return &StreamSink{db: db}
+}
+
+func (s *StreamSink) Name() string { return SinkStream }
+
+func (s *StreamSink) Transactional() {}
+
+func (s *StreamSink) Deliver(ctx context.Context, event *models.DomainEvent) error {
+	if event.UserID == nil || !strings.HasPrefix(string(event.Type), "item") {
+		return nil
+	}
+	var itemID *string
+	if event.AggregateType == models.AggregateItem {
+		itemID = &event.AggregateID
+	}
+	_, err := s.db.RecordItemEvent(ctx, *event.UserID, models.ItemEventType(event.Type), itemID, event.Payload)
+	return err
+}
+
+// WebhookSink queues webhook deliveries for subscribed endpoints. The
+// webhook event reuses the domain event ID so receivers see one ID per event.
+type WebhookSink struct {
+	db *database.DB
+}
+
+func NewWebhookSink(db *database.DB) *WebhookSink {
+	return &WebhookSink{db: db}
+}
+
+func (s *WebhookSink) Name() string { return SinkWebhooks }
+
+func (s *WebhookSink) Transactional() {}
+
+func (s *WebhookSink) Deliver(ctx context.Context, event *models.DomainEvent) error {
+	eventType := models.WebhookEventType(event.Type)
+	adminOnly, ok := models.WebhookEventTypes[eventType]
+	if !ok {
+		return nil
+	}
+
+	// Admin-only events go to admin endpoints rather than the subject user's
+	ownerID := event.UserID
+	if adminOnly {
+		ownerID = nil
+	} else if ownerID == nil {
+		return nil
+	}
+
+	_, err := s.db.EnqueueWebhookEvent(ctx, ownerID, &models.WebhookEvent{
+		ID:        event.ID,
+		Type:      eventType,
+		CreatedAt: event.CreatedAt,
+		Data:      event.Payload,
+	})
+	return err
+}
+
+// Handler processes a domain event. Handlers may run more than once for the
+// same event and should be idempotent on DomainEvent.ID.
+type Handler func(ctx context.Context, event *models.DomainEvent) error
+
+// Bus is an in-process sink dispatching events to subscribed handlers
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[models.DomainEventType][]Handler
+}
+
+// AllEvents subscribes a handler to every event type
+const AllEvents models.DomainEventType = "*"
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[models.DomainEventType][]Handler)}
+}
+
+// Subscribe registers handler for an event type, or AllEvents
+func (b *Bus) Subscribe(eventType models.DomainEventType, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+func (b *Bus) Name() string { return SinkInProcess }
+
+// Deliver runs every matching handler. If any fails the event is retried and
+// all handlers run again.
+func (b *Bus) Deliver(ctx context.Context, event *models.DomainEvent) error {
+	b.mu.RLock()
+	handlers := append(append([]Handler{}, b.handlers[event.Type]...), b.handlers[AllEvents]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PubSubSink publishes events broker-style to "<prefix><type>" subjects on a
+// realtime.PubSub, e.g. "events.item.created". With the Postgres pub/sub any
+// instance can consume them; with the in-memory one they stay in-process.
+type PubSubSink struct {
+	pubsub realtime.PubSub
+	prefix string
+}
+
+func NewPubSubSink(pubsub realtime.PubSub, prefix string) *PubSubSink {
+	return &PubSubSink{pubsub: pubsub, prefix: prefix}
+}
+
+func (s *PubSubSink) Name() string { return SinkPubSub }
+
+func (s *PubSubSink) Deliver(ctx context.Context, event *models.DomainEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	err = s.pubsub.Publish(ctx, s.prefix+string(event.Type), data)
+	if errors.Is(err, realtime.ErrPayloadTooLarge) {
+		// Retrying cannot help; consumers can still read the event from the outbox
+		slog.Warn("Outbox event too large for pubsub, skipped", "event_id", event.ID)
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,156 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// subscriberBuffer is how many events a slow client may fall behind before
+	// it is disconnected (it resumes from the event log with Last-Event-ID)
+	subscriberBuffer = 64
+
+	// EventRetention is how long item events stay available for resume
+	// (pruned by the item-events.prune scheduled task)
+	EventRetention = 24 * time.Hour
+
+	fetchEventTimeout = 5 * time.Second
+)
+
+// Subscriber receives the item events of a single user. Events is closed when
+// the subscriber is removed or has fallen too far behind.
+type Subscriber struct {
+	Events chan *models.ItemEvent
+	userID string
+	once   sync.Once
+}
+
+func (s *Subscriber) close() {
+	s.once.Do(func() { close(s.Events) })
+}
+
+// Broker fans item events out to local subscribers. Events are written to the
+// database event log and announced with NOTIFY, so every replica running a
+// Broker sees every event regardless of which replica produced it.
+type Broker struct {
+	db *database.DB
+
+	mu          sync.RWMutex
+	subscribers map[string]map[*Subscriber]struct{}
+	closed      bool
+}
+
+func NewBroker(db *database.DB) *Broker {
+	return &Broker{
+		db:          db,
+		subscribers: make(map[string]map[*Subscriber]struct{}),
+	}
+}
+
+// Subscribe registers a subscriber for a user's events. After Shutdown the
+// subscriber's Events channel is already closed, so a stream opened while the
+// server drains ends at once.
+func (b *Broker) Subscribe(userID string) *Subscriber {
+	sub := &Subscriber{
+		Events: make(chan *models.ItemEvent, subscriberBuffer),
+		userID: userID,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.close()
+		return sub
+	}
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[*Subscriber]struct{})
+	}
+	b.subscribers[userID][sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe removes a subscriber and closes its channel
+func (b *Broker) Unsubscribe(sub *Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if subs, ok := b.subscribers[sub.userID]; ok {
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(b.subscribers, sub.userID)
+		}
+	}
+	sub.close()
+}
+
+// Shutdown disconnects every subscriber and refuses new ones
+func (b *Broker) Shutdown() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for userID, subs := range b.subscribers {
+		for sub := range subs {
+			sub.close()
+		}
+		delete(b.subscribers, userID)
+	}
+}
+
+func (b *Broker) hasSubscribers(userID string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscribers[userID]) > 0
+}
+
+// dispatch delivers an event to the user's subscribers, dropping any that cannot keep up
+func (b *Broker) dispatch(event *models.ItemEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subscribers[event.UserID] {
+		select {
+		case sub.Events <- event:
+		default:
+			delete(b.subscribers[event.UserID], sub)
+			sub.close()
+		}
+	}
+	if len(b.subscribers[event.UserID]) == 0 {
+		delete(b.subscribers, event.UserID)
+	}
+}
+
+// Run listens for item event notifications until ctx is done, reconnecting
+// with backoff when the listener connection fails
+func (b *Broker) Run(ctx context.Context) {
+	b.db.ListenLoop(ctx, database.ItemEventsChannel, func(notification *pgconn.Notification) {
+		b.handleNotification(ctx, notification)
+	})
+}
+
+func (b *Broker) handleNotification(ctx context.Context, notification *pgconn.Notification) {
+	var ref struct {
+		ID     int64  `json:"id"`
+		UserID string `json:"userId"`
+	}
+	if err := json.Unmarshal([]byte(notification.Payload), &ref); err != nil {
+		return
+	}
+	if !b.hasSubscribers(ref.UserID) {
+		return
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, fetchEventTimeout)
+	event, err := b.db.GetItemEvent(fetchCtx, ref.ID)
+	cancel()
+	if err != nil {
+		slog.Error("Failed to load item event", "event_id", ref.ID, "error", err)
+		return
+	}
+	b.dispatch(event)
+}
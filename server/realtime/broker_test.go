@@ -0,0 +1,48 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBrokerShutdownEndsStreams(t *testing.T) {
+	b := NewBroker(nil)
+	open := b.Subscribe("user_1")
+
+	b.Shutdown()
+	if _, ok := <-open.Events; ok {
+		t.Error("subscriber open at shutdown still receives events")
+	}
+
+	// A stream still being set up while the server drains ends at once
+	late := b.Subscribe("user_1")
+	select {
+	case _, ok := <-late.Events:
+		if ok {
+			t.Error("subscriber after shutdown received an event")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscriber after shutdown was left open")
+	}
+	if b.hasSubscribers("user_1") {
+		t.Error("subscriber after shutdown was registered")
+	}
+	b.Unsubscribe(late)
+}
+
+func TestPresenceShutdownClosesLateClients(t *testing.T) {
+	h := NewPresenceHub(NewMemoryPubSub(), nil)
+	h.Shutdown()
+
+	c := &Client{rooms: make(map[string]struct{}), done: make(chan struct{})}
+	h.connect(c)
+	select {
+	case <-c.done:
+	case <-time.After(time.Second):
+		t.Fatal("client connecting after shutdown was left open")
+	}
+	if c.closeCode != closeGoingAway {
+		t.Errorf("close code = %d, want going away", c.closeCode)
+	}
+	h.disconnect(c)
+}
@@ -0,0 +1,181 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+	"github.com/fasthttp/websocket"
+)
+
+const (
+	writeWait        = 10 * time.Second
+	pongWait         = 60 * time.Second
+	pingInterval     = 25 * time.Second
+	maxMessageSize   = 4096
+	clientSendBuffer = 32
+	authorizeTimeout = 5 * time.Second
+
+	closeGoingAway     = websocket.CloseGoingAway
+	closeTryAgainLater = websocket.CloseTryAgainLater
+)
+
+// Client is a single WebSocket connection to the presence hub
+type Client struct {
+	ID     string
+	UserID string
+	Name   string
+
+	send  chan []byte
+	rooms map[string]struct{} // guarded by the hub's mutex
+
+	done        chan struct{}
+	closeOnce   sync.Once
+	closeCode   int
+	closeReason string
+}
+
+// enqueue queues a message without blocking. A client that cannot keep up
+// is disconnected rather than buffered without bound.
+func (c *Client) enqueue(data []byte) {
+	select {
+	case c.send <- data:
+	default:
+		c.shutdown(closeTryAgainLater, "client too slow")
+	}
+}
+
+func (c *Client) sendMessage(msg models.PresenceServerMessage) {
+	if data, err := json.Marshal(msg); err == nil {
+		c.enqueue(data)
+	}
+}
+
+func (c *Client) sendError(itemID, message string) {
+	c.sendMessage(models.PresenceServerMessage{Type: models.PresenceError, ItemID: itemID, Error: message})
+}
+
+func (c *Client) shutdown(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		close(c.done)
+	})
+}
+
+// Serve runs a presence connection for an authenticated user until it closes
+func (h *PresenceHub) Serve(conn *websocket.Conn, userID, name string) {
+	c := &Client{
+		ID:     utils.NewConnectionID(),
+		UserID: userID,
+		Name:   name,
+		send:   make(chan []byte, clientSendBuffer),
+		rooms:  make(map[string]struct{}),
+		done:   make(chan struct{}),
+	}
+
+	h.connect(c)
+
+	writerDone := make(chan struct{})
+	go c.writePump(conn, writerDone)
+
+	c.readPump(h, conn)
+	c.shutdown(websocket.CloseNormalClosure, "")
+	h.disconnect(c)
+	<-writerDone
+}
+
+func (c *Client) readPump(h *PresenceHub, conn *websocket.Conn) {
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		// Any traffic counts as a heartbeat
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		var msg models.PresenceClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.sendError("", "Invalid message")
+			continue
+		}
+		if msg.Type != models.PresencePing && msg.ItemID == "" {
+			c.sendError("", "itemId is required")
+			continue
+		}
+
+		switch msg.Type {
+		case models.PresenceJoin:
+			ctx, cancel := context.WithTimeout(context.Background(), authorizeTimeout)
+			err = h.join(ctx, c, msg.ItemID)
+			cancel()
+		case models.PresenceLeave:
+			err = h.leave(c, msg.ItemID)
+		case models.PresenceTyping:
+			err = h.setTyping(c, msg.ItemID, msg.Typing)
+		case models.PresenceLock:
+			err = h.lock(c, msg.ItemID)
+		case models.PresenceUnlock:
+			err = h.unlock(c, msg.ItemID)
+		case models.PresencePing:
+			c.sendMessage(models.PresenceServerMessage{Type: models.PresencePong})
+		default:
+			c.sendError(msg.ItemID, "Unknown message type")
+		}
+		if err != nil {
+			c.sendError(msg.ItemID, clientErrorMessage(err))
+		}
+	}
+}
+
+// writePump owns all writes to the connection and sends heartbeat pings
+func (c *Client) writePump(conn *websocket.Conn, writerDone chan<- struct{}) {
+	defer close(writerDone)
+	defer conn.Close() // Unblocks readPump when the writer stops first
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case data := <-c.send:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				c.shutdown(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				c.shutdown(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-c.done:
+			message := websocket.FormatCloseMessage(c.closeCode, c.closeReason)
+			conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeWait))
+			return
+		}
+	}
+}
+
+func clientErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, ErrItemNotFound):
+		return "Item not found"
+	case errors.Is(err, errNotJoined):
+		return "Not joined to item"
+	case errors.Is(err, errTooManyRooms):
+		return "Too many items joined"
+	default:
+		return "Request failed"
+	}
+}
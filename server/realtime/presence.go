@@ -0,0 +1,531 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+)
+
+const (
+	// presenceSweepInterval is how often typing flags and stale members expire
+	presenceSweepInterval = 5 * time.Second
+
+	// presenceAnnounceEvery sweeps pass between re-announcements of local
+	// members, which keep them alive on other instances
+	presenceAnnounceEvery = 3
+
+	// presenceTTL drops members of other instances that stopped announcing
+	presenceTTL = 45 * time.Second
+
+	// typingTTL clears a typing indicator that was not refreshed
+	typingTTL = 6 * time.Second
+
+	maxRoomsPerClient = 20
+	publishTimeout    = 5 * time.Second
+)
+
+var (
+	// ErrItemNotFound is returned by an authorize function to refuse a join
+	ErrItemNotFound = errors.New("item not found")
+
+	errNotJoined    = errors.New("not joined to item")
+	errTooManyRooms = errors.New("too many items joined")
+)
+
+// AuthorizeFunc reports whether a user may join an item's presence room
+type AuthorizeFunc func(ctx context.Context, userID, itemID string) error
+
+type presenceKind string
+
+const (
+	presenceUpsert presenceKind = "upsert"
+	presenceRemove presenceKind = "remove"
+	presenceSync   presenceKind = "sync" // asks other instances to re-announce
+)
+
+// presenceMessage is exchanged between instances over PubSub
+type presenceMessage struct {
+	Kind         presenceKind           `json:"kind"`
+	Instance     string                 `json:"instance"`
+	Member       *models.PresenceMember `json:"member,omitempty"`
+	ConnectionID string                 `json:"connectionId,omitempty"`
+}
+
+type memberState struct {
+	models.PresenceMember
+	instance string
+	seenAt   time.Time
+	typingAt time.Time
+}
+
+type presenceRoom struct {
+	members     map[string]*memberState // by connection ID, across all instances
+	clients     map[*Client]struct{}    // local connections
+	unsubscribe func()
+}
+
+type outgoing struct {
+	itemID  string
+	message presenceMessage
+}
+
+// PresenceHub tracks who is viewing and editing each item. Each instance
+// owns its local connections and shares their state with other instances
+// through PubSub; edit-lock conflicts resolve deterministically to the
+// earliest lock so every instance agrees on the holder.
+type PresenceHub struct {
+	pubsub     PubSub
+	authorize  AuthorizeFunc
+	instanceID string
+
+	mu      sync.Mutex
+	rooms   map[string]*presenceRoom
+	clients map[*Client]struct{}
+	closed  bool
+}
+
+func NewPresenceHub(pubsub PubSub, authorize AuthorizeFunc) *PresenceHub {
+	instanceID, _ := utils.GenerateSecureToken(9)
+	return &PresenceHub{
+		pubsub:     pubsub,
+		authorize:  authorize,
+		instanceID: instanceID,
+		rooms:      make(map[string]*presenceRoom),
+		clients:    make(map[*Client]struct{}),
+	}
+}
+
+func presenceTopic(itemID string) string {
+	return "presence:" + itemID
+}
+
+// Run expires stale state and re-announces local members until ctx is done
+func (h *PresenceHub) Run(ctx context.Context) {
+	ticker := time.NewTicker(presenceSweepInterval)
+	defer ticker.Stop()
+
+	for tick := 1; ; tick++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.sweep(tick%presenceAnnounceEvery == 0)
+		}
+	}
+}
+
+// Shutdown disconnects every local client, and any that connect afterwards
+func (h *PresenceHub) Shutdown() {
+	h.mu.Lock()
+	h.closed = true
+	clients := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.Unlock()
+
+	for _, client := range clients {
+		client.shutdown(closeGoingAway, "server shutting down")
+	}
+}
+
+func (h *PresenceHub) join(ctx context.Context, c *Client, itemID string) error {
+	if err := h.authorize(ctx, c.UserID, itemID); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	if room, ok := h.rooms[itemID]; ok {
+		if _, joined := room.clients[c]; joined {
+			c.sendMessage(h.snapshotLocked(itemID, room))
+			h.mu.Unlock()
+			return nil
+		}
+	}
+	if len(c.rooms) >= maxRoomsPerClient {
+		h.mu.Unlock()
+		return errTooManyRooms
+	}
+
+	room, ok := h.rooms[itemID]
+	created := !ok
+	if created {
+		room = &presenceRoom{
+			members: make(map[string]*memberState),
+			clients: make(map[*Client]struct{}),
+		}
+		room.unsubscribe = h.pubsub.Subscribe(presenceTopic(itemID), func(payload []byte) {
+			h.receive(itemID, payload)
+		})
+		h.rooms[itemID] = room
+	}
+
+	now := time.Now().UTC()
+	member := &memberState{
+		PresenceMember: models.PresenceMember{
+			ConnectionID: c.ID,
+			UserID:       c.UserID,
+			Name:         c.Name,
+			JoinedAt:     now,
+		},
+		instance: h.instanceID,
+		seenAt:   now,
+	}
+	room.members[c.ID] = member
+	room.clients[c] = struct{}{}
+	c.rooms[itemID] = struct{}{}
+	h.broadcastLocked(itemID, room)
+
+	out := []outgoing{h.upsert(itemID, member)}
+	if created {
+		out = append(out, outgoing{itemID, presenceMessage{Kind: presenceSync}})
+	}
+	h.mu.Unlock()
+
+	h.publish(out)
+	return nil
+}
+
+func (h *PresenceHub) leave(c *Client, itemID string) error {
+	h.mu.Lock()
+	if _, ok := c.rooms[itemID]; !ok {
+		h.mu.Unlock()
+		return errNotJoined
+	}
+	out := h.removeLocked(c, itemID)
+	h.mu.Unlock()
+
+	h.publish(out)
+	return nil
+}
+
+func (h *PresenceHub) connect(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		c.shutdown(closeGoingAway, "server shutting down")
+		return
+	}
+	h.clients[c] = struct{}{}
+}
+
+// disconnect removes a closed client from all of its rooms
+func (h *PresenceHub) disconnect(c *Client) {
+	h.mu.Lock()
+	delete(h.clients, c)
+	var out []outgoing
+	for itemID := range c.rooms {
+		out = append(out, h.removeLocked(c, itemID)...)
+	}
+	h.mu.Unlock()
+
+	h.publish(out)
+}
+
+func (h *PresenceHub) removeLocked(c *Client, itemID string) []outgoing {
+	room := h.rooms[itemID]
+	delete(c.rooms, itemID)
+	delete(room.clients, c)
+	delete(room.members, c.ID)
+
+	if len(room.clients) == 0 {
+		// Remote members are only tracked while someone here is watching
+		room.unsubscribe()
+		delete(h.rooms, itemID)
+	} else {
+		h.broadcastLocked(itemID, room)
+	}
+	return []outgoing{{itemID, presenceMessage{Kind: presenceRemove, ConnectionID: c.ID}}}
+}
+
+func (h *PresenceHub) setTyping(c *Client, itemID string, typing bool) error {
+	h.mu.Lock()
+	member, room := h.localMemberLocked(c, itemID)
+	if member == nil {
+		h.mu.Unlock()
+		return errNotJoined
+	}
+	member.typingAt = time.Now()
+	if member.Typing == typing {
+		h.mu.Unlock()
+		return nil
+	}
+	member.Typing = typing
+	h.broadcastLocked(itemID, room)
+	out := []outgoing{h.upsert(itemID, member)}
+	h.mu.Unlock()
+
+	h.publish(out)
+	return nil
+}
+
+// lock requests the item's edit lock and replies with the outcome
+func (h *PresenceHub) lock(c *Client, itemID string) error {
+	h.mu.Lock()
+	member, room := h.localMemberLocked(c, itemID)
+	if member == nil {
+		h.mu.Unlock()
+		return errNotJoined
+	}
+
+	if holder := lockHolder(room); holder != nil && holder != member {
+		c.sendMessage(lockReply(itemID, false, holder))
+		h.mu.Unlock()
+		return nil
+	}
+
+	var out []outgoing
+	if !member.Editing {
+		lockedAt := time.Now().UTC()
+		member.Editing = true
+		member.LockedAt = &lockedAt
+		h.broadcastLocked(itemID, room)
+		out = append(out, h.upsert(itemID, member))
+	}
+	c.sendMessage(lockReply(itemID, true, member))
+	h.mu.Unlock()
+
+	h.publish(out)
+	return nil
+}
+
+func (h *PresenceHub) unlock(c *Client, itemID string) error {
+	h.mu.Lock()
+	member, room := h.localMemberLocked(c, itemID)
+	if member == nil {
+		h.mu.Unlock()
+		return errNotJoined
+	}
+	if !member.Editing {
+		h.mu.Unlock()
+		return nil
+	}
+	member.Editing = false
+	member.LockedAt = nil
+	h.broadcastLocked(itemID, room)
+	out := []outgoing{h.upsert(itemID, member)}
+	h.mu.Unlock()
+
+	h.publish(out)
+	return nil
+}
+
+func (h *PresenceHub) localMemberLocked(c *Client, itemID string) (*memberState, *presenceRoom) {
+	if _, ok := c.rooms[itemID]; !ok {
+		return nil, nil
+	}
+	room := h.rooms[itemID]
+	return room.members[c.ID], room
+}
+
+// receive applies a presence message from another instance
+func (h *PresenceHub) receive(itemID string, payload []byte) {
+	var msg presenceMessage
+	if err := json.Unmarshal(payload, &msg); err != nil || msg.Instance == h.instanceID {
+		return
+	}
+
+	h.mu.Lock()
+	room, ok := h.rooms[itemID]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+
+	var out []outgoing
+	changed := false
+	switch msg.Kind {
+	case presenceUpsert:
+		if msg.Member == nil {
+			break
+		}
+		existing, ok := room.members[msg.Member.ConnectionID]
+		if ok && existing.instance == h.instanceID {
+			break
+		}
+		changed = !ok || !samePresence(existing.PresenceMember, *msg.Member)
+		room.members[msg.Member.ConnectionID] = &memberState{
+			PresenceMember: *msg.Member,
+			instance:       msg.Instance,
+			seenAt:         time.Now(),
+		}
+	case presenceRemove:
+		if existing, ok := room.members[msg.ConnectionID]; ok && existing.instance == msg.Instance {
+			delete(room.members, msg.ConnectionID)
+			changed = true
+		}
+	case presenceSync:
+		out = h.announceLocked(itemID, room)
+	}
+
+	if changed {
+		out = append(out, h.resolveLocksLocked(itemID, room)...)
+		h.broadcastLocked(itemID, room)
+	}
+	h.mu.Unlock()
+
+	h.publish(out)
+}
+
+// resolveLocksLocked revokes locks local members lost to an earlier lock on another instance
+func (h *PresenceHub) resolveLocksLocked(itemID string, room *presenceRoom) []outgoing {
+	holder := lockHolder(room)
+	var out []outgoing
+	for client := range room.clients {
+		member := room.members[client.ID]
+		if member == nil || !member.Editing || member == holder {
+			continue
+		}
+		member.Editing = false
+		member.LockedAt = nil
+		client.sendMessage(models.PresenceServerMessage{
+			Type:     models.PresenceLockLost,
+			ItemID:   itemID,
+			LockedBy: &holder.PresenceMember,
+		})
+		out = append(out, h.upsert(itemID, member))
+	}
+	return out
+}
+
+func (h *PresenceHub) sweep(announce bool) {
+	now := time.Now()
+
+	h.mu.Lock()
+	var out []outgoing
+	for itemID, room := range h.rooms {
+		changed := false
+		for id, member := range room.members {
+			if member.instance != h.instanceID {
+				if now.Sub(member.seenAt) > presenceTTL {
+					delete(room.members, id)
+					changed = true
+				}
+				continue
+			}
+			if member.Typing && now.Sub(member.typingAt) > typingTTL {
+				member.Typing = false
+				changed = true
+				if !announce {
+					out = append(out, h.upsert(itemID, member))
+				}
+			}
+		}
+		if changed {
+			out = append(out, h.resolveLocksLocked(itemID, room)...)
+			h.broadcastLocked(itemID, room)
+		}
+		if announce {
+			out = append(out, h.announceLocked(itemID, room)...)
+		}
+	}
+	h.mu.Unlock()
+
+	h.publish(out)
+}
+
+func (h *PresenceHub) announceLocked(itemID string, room *presenceRoom) []outgoing {
+	var out []outgoing
+	for _, member := range room.members {
+		if member.instance == h.instanceID {
+			out = append(out, h.upsert(itemID, member))
+		}
+	}
+	return out
+}
+
+func (h *PresenceHub) upsert(itemID string, member *memberState) outgoing {
+	m := member.PresenceMember
+	return outgoing{itemID, presenceMessage{Kind: presenceUpsert, Member: &m}}
+}
+
+func (h *PresenceHub) snapshotLocked(itemID string, room *presenceRoom) models.PresenceServerMessage {
+	members := make([]models.PresenceMember, 0, len(room.members))
+	for _, member := range room.members {
+		members = append(members, member.PresenceMember)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if !members[i].JoinedAt.Equal(members[j].JoinedAt) {
+			return members[i].JoinedAt.Before(members[j].JoinedAt)
+		}
+		return members[i].ConnectionID < members[j].ConnectionID
+	})
+
+	msg := models.PresenceServerMessage{
+		Type:    models.PresenceSnapshot,
+		ItemID:  itemID,
+		Members: members,
+	}
+	if holder := lockHolder(room); holder != nil {
+		lockedBy := holder.PresenceMember
+		msg.LockedBy = &lockedBy
+	}
+	return msg
+}
+
+func (h *PresenceHub) broadcastLocked(itemID string, room *presenceRoom) {
+	data, err := json.Marshal(h.snapshotLocked(itemID, room))
+	if err != nil {
+		return
+	}
+	for client := range room.clients {
+		client.enqueue(data)
+	}
+}
+
+// publish sends messages to other instances; it must not be called with h.mu held
+func (h *PresenceHub) publish(out []outgoing) {
+	for _, o := range out {
+		o.message.Instance = h.instanceID
+		data, err := json.Marshal(o.message)
+		if err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+		if err := h.pubsub.Publish(ctx, presenceTopic(o.itemID), data); err != nil {
+			slog.Error("Failed to publish presence", "item_id", o.itemID, "error", err)
+		}
+		cancel()
+	}
+}
+
+// lockHolder returns the member holding the edit lock: the earliest lock wins,
+// ties broken by connection ID
+func lockHolder(room *presenceRoom) *memberState {
+	var holder *memberState
+	for _, member := range room.members {
+		if !member.Editing || member.LockedAt == nil {
+			continue
+		}
+		if holder == nil || member.LockedAt.Before(*holder.LockedAt) ||
+			(member.LockedAt.Equal(*holder.LockedAt) && member.ConnectionID < holder.ConnectionID) {
+			holder = member
+		}
+	}
+	return holder
+}
+
+func lockReply(itemID string, granted bool, holder *memberState) models.PresenceServerMessage {
+	lockedBy := holder.PresenceMember
+	return models.PresenceServerMessage{
+		Type:     models.PresenceLock,
+		ItemID:   itemID,
+		Granted:  &granted,
+		LockedBy: &lockedBy,
+	}
+}
+
+func samePresence(a, b models.PresenceMember) bool {
+	sameLock := (a.LockedAt == nil) == (b.LockedAt == nil) &&
+		(a.LockedAt == nil || a.LockedAt.Equal(*b.LockedAt))
+	return sameLock && a.ConnectionID == b.ConnectionID && a.UserID == b.UserID &&
+		a.Name == b.Name && a.Typing == b.Typing && a.Editing == b.Editing &&
+		a.JoinedAt.Equal(b.JoinedAt)
+}
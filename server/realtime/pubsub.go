@@ -0,0 +1,126 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PubSub delivers messages published on a topic to its subscribers. Handlers
+// run on the delivering goroutine and must not block.
+type PubSub interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Subscribe registers handler for topic until the returned function is called
+	Subscribe(topic string, handler func(payload []byte)) (unsubscribe func())
+}
+
+// MemoryPubSub is an in-process PubSub for single-instance deployments
+type MemoryPubSub struct {
+	mu       sync.RWMutex
+	nextID   uint64
+	handlers map[string]map[uint64]func([]byte)
+}
+
+func NewMemoryPubSub() *MemoryPubSub {
+	return &MemoryPubSub{
+		handlers: make(map[string]map[uint64]func([]byte)),
+	}
+}
+
+func (m *MemoryPubSub) Publish(_ context.Context, topic string, payload []byte) error {
+	m.deliver(topic, payload)
+	return nil
+}
+
+func (m *MemoryPubSub) Subscribe(topic string, handler func([]byte)) func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	id := m.nextID
+	if m.handlers[topic] == nil {
+		m.handlers[topic] = make(map[uint64]func([]byte))
+	}
+	m.handlers[topic][id] = handler
+
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.handlers[topic], id)
+		if len(m.handlers[topic]) == 0 {
+			delete(m.handlers, topic)
+		}
+	}
+}
+
+func (m *MemoryPubSub) deliver(topic string, payload []byte) {
+	m.mu.RLock()
+	handlers := make([]func([]byte), 0, len(m.handlers[topic]))
+	for _, handler := range m.handlers[topic] {
+		handlers = append(handlers, handler)
+	}
+	m.mu.RUnlock()
+
+	// Called outside the lock so handlers may (un)subscribe
+	for _, handler := range handlers {
+		handler(payload)
+	}
+}
+
+// PubSubChannel is the NOTIFY channel used by PostgresPubSub
+const PubSubChannel = "realtime_pubsub"
+
+// maxNotifyPayload stays under Postgres' 8000-byte NOTIFY payload limit
+const maxNotifyPayload = 7900
+
+var ErrPayloadTooLarge = errors.New("pubsub payload too large")
+
+// PostgresPubSub fans messages out across instances with LISTEN/NOTIFY. Every
+// message, including the instance's own, is delivered once through the listener.
+type PostgresPubSub struct {
+	db    *database.DB
+	local *MemoryPubSub
+}
+
+type pubSubEnvelope struct {
+	Topic   string `json:"t"`
+	Payload []byte `json:"p"`
+}
+
+func NewPostgresPubSub(db *database.DB) *PostgresPubSub {
+	return &PostgresPubSub{
+		db:    db,
+		local: NewMemoryPubSub(),
+	}
+}
+
+func (p *PostgresPubSub) Publish(ctx context.Context, topic string, payload []byte) error {
+	data, err := json.Marshal(pubSubEnvelope{Topic: topic, Payload: payload})
+	if err != nil {
+		return err
+	}
+	if len(data) > maxNotifyPayload {
+		return ErrPayloadTooLarge
+	}
+	return p.db.Notify(ctx, PubSubChannel, string(data))
+}
+
+func (p *PostgresPubSub) Subscribe(topic string, handler func([]byte)) func() {
+	return p.local.Subscribe(topic, handler)
+}
+
+// Run delivers notifications to local subscribers until ctx is done
+func (p *PostgresPubSub) Run(ctx context.Context) {
+	p.db.ListenLoop(ctx, PubSubChannel, func(notification *pgconn.Notification) {
+		var envelope pubSubEnvelope
+		if err := json.Unmarshal([]byte(notification.Payload), &envelope); err != nil {
+			slog.Warn("Ignoring malformed pubsub message", "error", err)
+			return
+		}
+		p.local.deliver(envelope.Topic, envelope.Payload)
+	})
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/handlers"
+	"github.com/binduni/bun-golang-react-monorepo/server/jobs"
+	"github.com/binduni/bun-golang-react-monorepo/server/middleware"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/scheduler"
+	"github.com/gofiber/fiber/v3"
+)
+
+func SetupAdminRoutes(router fiber.Router, cfg *config.Config, db *database.DB, queue *jobs.Queue, sched *scheduler.Scheduler) {
+	jobsHandler := handlers.NewJobsHandler(db, cfg, queue)
+	tasksHandler := handlers.NewTasksHandler(db, cfg, sched)
+
+	// All admin routes require an admin
+	router.Use(middleware.AuthMiddleware(cfg.JWTSecret, cfg.JWTPreviousSecrets...))
+	router.Use(middleware.RequireRole(models.RoleAdmin))
+
+	router.Get("/jobs", jobsHandler.ListJobs)
+	router.Post("/jobs", jobsHandler.EnqueueJob)
+	router.Get("/jobs/stats", jobsHandler.GetStats)
+	router.Get("/jobs/:id", jobsHandler.GetJob)
+	router.Post("/jobs/:id/retry", jobsHandler.RetryJob)
+	router.Post("/jobs/:id/cancel", jobsHandler.CancelJob)
+
+	router.Get("/tasks", tasksHandler.ListTasks)
+	router.Post("/tasks/:name/run", tasksHandler.RunTask)
+}
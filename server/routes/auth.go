@@ -2,14 +2,14 @@ package routes
 
 import (
 	"github.com/binduni/bun-golang-react-monorepo/server/config"
-	"github.com/binduni/bun-golang-react-monorepo/server/database"
 	"github.com/binduni/bun-golang-react-monorepo/server/handlers"
 	"github.com/binduni/bun-golang-react-monorepo/server/middleware"
+	"github.com/binduni/bun-golang-react-monorepo/server/store"
 	"github.com/gofiber/fiber/v3"
 )
 
-func SetupAuthRoutes(router fiber.Router, cfg *config.Config, db *database.DB) {
-	authHandler := handlers.NewAuthHandler(db, cfg)
+func SetupAuthRoutes(router fiber.Router, cfg *config.Config, st store.Store) {
+	authHandler := handlers.NewAuthHandler(st, cfg)
 
 	// Public routes (no authentication required)
 	router.Post("/register", authHandler.Register)
@@ -17,8 +17,9 @@ func SetupAuthRoutes(router fiber.Router, cfg *config.Config, db *database.DB) {
 	router.Post("/refresh", authHandler.RefreshToken)
 
 	// Protected routes (authentication required)
-	protected := router.Group("", middleware.AuthMiddleware(cfg.JWTSecret))
+	protected := router.Group("", middleware.AuthMiddleware(cfg.JWTSecret, cfg.JWTPreviousSecrets...))
 	protected.Post("/logout", authHandler.Logout)
 	protected.Get("/me", authHandler.GetCurrentUser)
 	protected.Get("/sessions", authHandler.GetSessions)
+	protected.Delete("/sessions/:id", authHandler.RevokeSession)
 }
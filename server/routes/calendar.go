@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/handlers"
+	"github.com/binduni/bun-golang-react-monorepo/server/middleware"
+	"github.com/gofiber/fiber/v3"
+)
+
+func SetupCalendarRoutes(router fiber.Router, cfg *config.Config, db *database.DB) {
+	calendarHandler := handlers.NewCalendarHandler(db, cfg)
+
+	// Public feed (authenticated by the secret token in the URL)
+	router.Get("/feed/:token.ics", calendarHandler.Feed)
+
+	// Feed token management (authentication required)
+	protected := router.Group("", middleware.AuthMiddleware(cfg.JWTSecret, cfg.JWTPreviousSecrets...))
+	protected.Get("/token", calendarHandler.GetFeedStatus)
+	protected.Post("/token", calendarHandler.RegenerateFeedToken)
+	protected.Delete("/token", calendarHandler.RevokeFeedToken)
+}
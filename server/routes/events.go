@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/handlers"
+	"github.com/binduni/bun-golang-react-monorepo/server/middleware"
+	"github.com/binduni/bun-golang-react-monorepo/server/realtime"
+	"github.com/gofiber/fiber/v3"
+)
+
+func SetupEventRoutes(router fiber.Router, cfg *config.Config, db *database.DB, broker *realtime.Broker) {
+	eventsHandler := handlers.NewEventsHandler(db, cfg, broker)
+
+	// EventSource cannot send headers, so the access token may come from ?access_token=
+	router.Use(middleware.TokenFromQuery("access_token"))
+	router.Use(middleware.AuthMiddleware(cfg.JWTSecret, cfg.JWTPreviousSecrets...))
+
+	router.Get("/items", eventsHandler.StreamItemEvents)
+}
+
+func SetupPresenceRoutes(router fiber.Router, cfg *config.Config, db *database.DB, hub *realtime.PresenceHub) {
+	presenceHandler := handlers.NewPresenceHandler(db, cfg, hub)
+
+	// Browsers cannot set headers on WebSocket handshakes either
+	router.Use(middleware.TokenFromQuery("access_token"))
+	router.Use(middleware.AuthMiddleware(cfg.JWTSecret, cfg.JWTPreviousSecrets...))
+
+	router.Get("/ws", presenceHandler.Connect)
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/binduni/bun-golang-react-monorepo/server/handlers"
+	"github.com/binduni/bun-golang-react-monorepo/server/health"
+	"github.com/gofiber/fiber/v3"
+)
+
+func SetupHealthRoutes(router fiber.Router, probes *health.Registry) {
+	healthHandler := handlers.NewHealthHandler(probes)
+
+	router.Get("/livez", healthHandler.Livez)
+	router.Get("/readyz", healthHandler.Readyz)
+	router.Get("/startupz", healthHandler.Startupz)
+}
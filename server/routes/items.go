@@ -2,21 +2,40 @@ package routes
 
 import (
 	"github.com/binduni/bun-golang-react-monorepo/server/config"
-	"github.com/binduni/bun-golang-react-monorepo/server/database"
 	"github.com/binduni/bun-golang-react-monorepo/server/handlers"
 	"github.com/binduni/bun-golang-react-monorepo/server/middleware"
+	"github.com/binduni/bun-golang-react-monorepo/server/store"
 	"github.com/gofiber/fiber/v3"
 )
 
-func SetupItemsRoutes(router fiber.Router, cfg *config.Config, db *database.DB) {
-	itemsHandler := handlers.NewItemsHandler(db, cfg)
+func SetupItemsRoutes(router fiber.Router, cfg *config.Config, st store.Store) {
+	itemsHandler := handlers.NewItemsHandler(st, cfg)
 
 	// All items routes require authentication
-	router.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	router.Use(middleware.AuthMiddleware(cfg.JWTSecret, cfg.JWTPreviousSecrets...))
+
+	// Bulk import/export (registered before /:id so the paths are not taken as IDs)
+	// Exports hold user content, so a browser opening one inline runs nothing
+	router.Get("/export", middleware.OverrideSecurityHeaders(func(p *middleware.SecurityPolicy) {
+		p.ContentSecurityPolicy = "default-src 'none'; sandbox"
+	}), itemsHandler.ExportItems)
+	router.Post("/import", itemsHandler.ImportItems)
 
 	router.Get("/", itemsHandler.ListItems)
 	router.Get("/:id", itemsHandler.GetItem)
 	router.Post("/", itemsHandler.CreateItem)
 	router.Put("/:id", itemsHandler.UpdateItem)
 	router.Delete("/:id", itemsHandler.DeleteItem)
+
+	// Manual ordering
+	router.Post("/:id/move", itemsHandler.MoveItem)
+
+	// Subtasks
+	router.Get("/:id/children", itemsHandler.ListChildren)
+	router.Get("/:id/subtree", itemsHandler.GetSubtree)
+	router.Put("/:id/parent", itemsHandler.MoveSubtree)
+
+	// Recurring items
+	router.Get("/:id/series", itemsHandler.GetItemSeries)
+	router.Post("/:id/skip", itemsHandler.SkipOccurrence)
 }
@@ -6,11 +6,16 @@ import (
 
 	"github.com/binduni/bun-golang-react-monorepo/server/config"
 	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/health"
+	"github.com/binduni/bun-golang-react-monorepo/server/jobs"
 	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/realtime"
+	"github.com/binduni/bun-golang-react-monorepo/server/scheduler"
+	"github.com/binduni/bun-golang-react-monorepo/server/store"
 	"github.com/gofiber/fiber/v3"
 )
 
-func SetupRoutes(app *fiber.App, cfg *config.Config, db *database.DB) {
+func SetupRoutes(app *fiber.App, cfg *config.Config, db *database.DB, st store.Store, broker *realtime.Broker, presence *realtime.PresenceHub, queue *jobs.Queue, sched *scheduler.Scheduler, probes *health.Registry) {
 	// Root endpoint - API information
 	app.Get("/", func(c fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -31,6 +36,7 @@ func SetupRoutes(app *fiber.App, cfg *config.Config, db *database.DB) {
 					"logout":   "POST /api/auth/logout",
 					"me":       "GET /api/auth/me",
 					"sessions": "GET /api/auth/sessions",
+					"revoke":   "DELETE /api/auth/sessions/:id",
 				},
 				"oauth": fiber.Map{
 					"providers": "GET /api/auth/oauth/providers",
@@ -39,18 +45,64 @@ func SetupRoutes(app *fiber.App, cfg *config.Config, db *database.DB) {
 					"twitter":   "GET /api/auth/oauth/twitter",
 				},
 				"items": fiber.Map{
-					"list":   "GET /api/items",
-					"get":    "GET /api/items/:id",
-					"create": "POST /api/items",
-					"update": "PUT /api/items/:id",
-					"delete": "DELETE /api/items/:id",
+					"list":     "GET /api/items",
+					"get":      "GET /api/items/:id",
+					"create":   "POST /api/items",
+					"update":   "PUT /api/items/:id",
+					"delete":   "DELETE /api/items/:id",
+					"export":   "GET /api/items/export?format=csv|json|ndjson",
+					"import":   "POST /api/items/import?format=csv|json|ndjson&dryRun=true",
+					"move":     "POST /api/items/:id/move",
+					"children": "GET /api/items/:id/children",
+					"subtree":  "GET /api/items/:id/subtree",
+					"parent":   "PUT /api/items/:id/parent",
+					"series":   "GET /api/items/:id/series",
+					"skip":     "POST /api/items/:id/skip",
+				},
+				"events": fiber.Map{
+					"items": "GET /api/events/items (Server-Sent Events)",
+				},
+				"webhooks": fiber.Map{
+					"eventTypes": "GET /api/webhooks/event-types",
+					"list":       "GET /api/webhooks",
+					"create":     "POST /api/webhooks",
+					"get":        "GET /api/webhooks/:id",
+					"update":     "PUT /api/webhooks/:id",
+					"delete":     "DELETE /api/webhooks/:id",
+					"rotate":     "POST /api/webhooks/:id/rotate-secret",
+					"ping":       "POST /api/webhooks/:id/ping",
+					"deliveries": "GET /api/webhooks/:id/deliveries",
+					"delivery":   "GET /api/webhooks/:id/deliveries/:deliveryId",
+					"redeliver":  "POST /api/webhooks/:id/deliveries/:deliveryId/redeliver",
+				},
+				"presence": fiber.Map{
+					"ws": "GET /api/presence/ws (WebSocket)",
+				},
+				"admin": fiber.Map{
+					"jobs":    "GET /api/admin/jobs?status=&kind=&limit=",
+					"enqueue": "POST /api/admin/jobs",
+					"stats":   "GET /api/admin/jobs/stats",
+					"job":     "GET /api/admin/jobs/:id",
+					"retry":   "POST /api/admin/jobs/:id/retry",
+					"cancel":  "POST /api/admin/jobs/:id/cancel",
+					"tasks":   "GET /api/admin/tasks",
+					"runTask": "POST /api/admin/tasks/:name/run",
+				},
+				"calendar": fiber.Map{
+					"token":      "GET /api/calendar/token",
+					"regenerate": "POST /api/calendar/token",
+					"revoke":     "DELETE /api/calendar/token",
+					"feed":       "GET /api/calendar/feed/:token.ics",
 				},
 			},
 			"docs": "https://github.com/your-repo/docs",
 		})
 	})
 
-	// Health check endpoint
+	// Kubernetes probes
+	SetupHealthRoutes(app, probes)
+
+	// Health summary for humans; probes should use /livez, /readyz and /startupz
 	app.Get("/health", func(c fiber.Ctx) error {
 		var m runtime.MemStats
 		runtime.ReadMemStats(&m)
@@ -68,37 +120,53 @@ func SetupRoutes(app *fiber.App, cfg *config.Config, db *database.DB) {
 
 		// Check database connection
 		if db != nil {
-			if err := db.Health(); err != nil {
+			if err := db.Health(c.Context()); err != nil {
 				health["database"] = "unhealthy"
 				health["status"] = "degraded"
 			} else {
 				health["database"] = "healthy"
 			}
+			if db.HasReplicas() {
+				health["replicas"] = db.ReplicaStatus()
+			}
 		} else {
-			health["database"] = "not_configured"
+			health["database"] = "in_memory"
 		}
 
+		if health["status"] != "healthy" {
+			c.Status(fiber.StatusServiceUnavailable)
+		}
 		return c.JSON(health)
 	})
 
 	// API group
 	api := app.Group("/api")
 
-	// Mount auth routes
-	auth := api.Group("/auth")
+	// Mount auth and items routes (served from memory when no database is configured)
+	SetupAuthRoutes(api.Group("/auth"), cfg, st)
+	SetupItemsRoutes(api.Group("/items"), cfg, st)
+
+	// Mount calendar feed routes
 	if db != nil {
-		SetupAuthRoutes(auth, cfg, db)
+		SetupCalendarRoutes(api.Group("/calendar"), cfg, db)
 	}
 
-	// Mount items routes
-	items := api.Group("/items")
+	// Mount webhook routes
 	if db != nil {
-		SetupItemsRoutes(items, cfg, db)
-	} else {
-		// Return empty data when database is not configured
-		items.Get("/", func(c fiber.Ctx) error {
-			return c.JSON(models.SuccessResponse([]any{}))
-		})
+		SetupWebhookRoutes(api.Group("/webhooks"), cfg, db)
+	}
+
+	// Mount real-time event stream routes
+	if db != nil && broker != nil {
+		SetupEventRoutes(api.Group("/events"), cfg, db, broker)
+	}
+	if db != nil && presence != nil {
+		SetupPresenceRoutes(api.Group("/presence"), cfg, db, presence)
+	}
+
+	// Mount admin routes
+	if db != nil && queue != nil && sched != nil {
+		SetupAdminRoutes(api.Group("/admin"), cfg, db, queue, sched)
 	}
 
 	// 404 handler
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/handlers"
+	"github.com/binduni/bun-golang-react-monorepo/server/middleware"
+	"github.com/gofiber/fiber/v3"
+)
+
+func SetupWebhookRoutes(router fiber.Router, cfg *config.Config, db *database.DB) {
+	webhooksHandler := handlers.NewWebhooksHandler(db, cfg)
+
+	// All webhook routes require authentication
+	router.Use(middleware.AuthMiddleware(cfg.JWTSecret, cfg.JWTPreviousSecrets...))
+
+	router.Get("/event-types", webhooksHandler.ListEventTypes)
+	router.Get("/", webhooksHandler.ListEndpoints)
+	router.Post("/", webhooksHandler.CreateEndpoint)
+	router.Get("/:id", webhooksHandler.GetEndpoint)
+	router.Put("/:id", webhooksHandler.UpdateEndpoint)
+	router.Delete("/:id", webhooksHandler.DeleteEndpoint)
+	router.Post("/:id/rotate-secret", webhooksHandler.RotateSecret)
+	router.Post("/:id/ping", webhooksHandler.Ping)
+	router.Get("/:id/deliveries", webhooksHandler.ListDeliveries)
+	router.Get("/:id/deliveries/:deliveryId", webhooksHandler.GetDelivery)
+	router.Post("/:id/deliveries/:deliveryId/redeliver", webhooksHandler.Redeliver)
+}
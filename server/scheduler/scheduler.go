@@ -0,0 +1,273 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+)
+
+const (
+	// DefaultTimeout bounds a task run when Task.Timeout is zero
+	DefaultTimeout = 5 * time.Minute
+
+	electionInterval = 15 * time.Second
+	checkInterval    = 10 * time.Second
+	recordTimeout    = 10 * time.Second
+	maxErrorLength   = 2000
+)
+
+// Task is a function run on a cron schedule by the leader instance
+type Task struct {
+	Name     string
+	Schedule string // Cron expression, evaluated in UTC
+	Timeout  time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type task struct {
+	Task
+	schedule *utils.CronSchedule
+}
+
+// Scheduler runs tasks on one instance at a time. Instances compete for a
+// Postgres advisory lock; the holder is the leader and runs due tasks. Each
+// tick is claimed in the database, so it runs once even across a failover.
+type Scheduler struct {
+	db       *database.DB
+	tasks    map[string]*task
+	instance string
+
+	leader  atomic.Bool
+	mu      sync.Mutex
+	running map[string]bool
+	wg      sync.WaitGroup
+	stopped chan struct{} // Closed when Run returns
+}
+
+func New(db *database.DB, tasks ...Task) (*Scheduler, error) {
+	instance, err := os.Hostname()
+	if err != nil {
+		instance = "unknown"
+	}
+
+	s := &Scheduler{
+		db:       db,
+		tasks:    make(map[string]*task, len(tasks)),
+		instance: instance,
+		running:  make(map[string]bool),
+		stopped:  make(chan struct{}),
+	}
+	for _, t := range tasks {
+		schedule, err := utils.ParseCron(t.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("task %s: %w", t.Name, err)
+		}
+		if _, dup := s.tasks[t.Name]; dup {
+			return nil, fmt.Errorf("task %s registered twice", t.Name)
+		}
+		if t.Timeout <= 0 {
+			t.Timeout = DefaultTimeout
+		}
+		s.tasks[t.Name] = &task{Task: t, schedule: schedule}
+	}
+	return s, nil
+}
+
+// IsLeader reports whether this instance currently runs the tasks
+func (s *Scheduler) IsLeader() bool {
+	return s.leader.Load()
+}
+
+// Instance is the name recorded as last_run_by for runs on this instance
+func (s *Scheduler) Instance() string {
+	return s.instance
+}
+
+// Tasks returns the shared state of every task
+func (s *Scheduler) Tasks(ctx context.Context) ([]*models.ScheduledTask, error) {
+	return s.db.GetScheduledTasks(ctx)
+}
+
+// Run competes for leadership until ctx is done, running due tasks while leader
+func (s *Scheduler) Run(ctx context.Context) {
+	defer close(s.stopped)
+
+	for {
+		lock, err := s.db.TryAdvisoryLock(ctx, database.SchedulerLockKey)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Scheduler leader election failed", "error", err)
+		}
+		if lock != nil {
+			s.lead(ctx, lock)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(electionInterval):
+		}
+	}
+}
+
+// Shutdown waits for Run to return and running tasks to finish. Cancel Run's
+// context first; that also cancels the tasks' contexts.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	select {
+	case <-s.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) lead(ctx context.Context, lock *database.AdvisoryLock) {
+	s.leader.Store(true)
+	slog.Info("Scheduler leadership acquired", "instance", s.instance)
+	defer func() {
+		s.leader.Store(false)
+		releaseCtx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+		defer cancel()
+		lock.Release(releaseCtx)
+	}()
+
+	now := time.Now().UTC()
+	for _, t := range s.tasks {
+		if err := s.db.SyncScheduledTask(ctx, t.Name, t.Schedule, t.schedule.Next(now)); err != nil {
+			slog.Error("Scheduler failed to register task", "task", t.Name, "error", err)
+			return
+		}
+	}
+
+	for {
+		wait := s.startDueTasks(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if err := lock.Check(ctx); err != nil {
+			if ctx.Err() == nil {
+				slog.Warn("Scheduler lost leadership", "error", err)
+			}
+			return
+		}
+	}
+}
+
+// startDueTasks claims and starts every due task that is not already running,
+// and returns how long to wait before checking again
+func (s *Scheduler) startDueTasks(ctx context.Context) time.Duration {
+	wait := checkInterval
+
+	states, err := s.db.GetScheduledTasks(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("Scheduler failed to load tasks", "error", err)
+		}
+		return wait
+	}
+
+	now := time.Now().UTC()
+	for _, state := range states {
+		t, ok := s.tasks[state.Name]
+		if !ok {
+			continue // Registered by another version of the server
+		}
+		if until := state.NextRunAt.Sub(now); until > 0 {
+			wait = min(wait, until)
+			continue
+		}
+		if s.isRunning(t.Name) {
+			continue // Missed ticks while running are skipped, not queued
+		}
+
+		// Missed ticks (e.g. while no instance was up) run once, then the schedule resumes
+		next := t.schedule.Next(now)
+		claimed, err := s.db.ClaimScheduledTask(ctx, t.Name, state.NextRunAt, next, s.instance)
+		if err != nil {
+			slog.Error("Scheduler failed to claim task", "task", t.Name, "error", err)
+			continue
+		}
+		if claimed {
+			s.start(ctx, t)
+		}
+	}
+	return wait
+}
+
+func (s *Scheduler) isRunning(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running[name]
+}
+
+func (s *Scheduler) start(ctx context.Context, t *task) {
+	s.mu.Lock()
+	s.running[t.Name] = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, t.Name)
+			s.mu.Unlock()
+			s.wg.Done()
+		}()
+
+		started := time.Now()
+		runCtx, cancel := context.WithTimeout(ctx, t.Timeout)
+		err := runTask(runCtx, t)
+		cancel()
+
+		var lastError *string
+		if err != nil {
+			slog.Error("Scheduled task failed", "task", t.Name, "error", err)
+			message := err.Error()
+			if len(message) > maxErrorLength {
+				message = strings.ToValidUTF8(message[:maxErrorLength], "")
+			}
+			lastError = &message
+		}
+
+		recordCtx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+		defer cancel()
+		if err := s.db.FinishScheduledTask(recordCtx, t.Name, lastError, time.Since(started)); err != nil {
+			slog.Error("Scheduler failed to record task outcome", "task", t.Name, "error", err)
+		}
+	}()
+}
+
+// runTask calls the task, turning a panic into an error. Its queries are
+// maintenance queries, bounded only by the statement timeout.
+func runTask(ctx context.Context, t *task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return t.Run(database.Maintenance(ctx))
+}
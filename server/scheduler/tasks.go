@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/jobs"
+	"github.com/binduni/bun-golang-react-monorepo/server/outbox"
+	"github.com/binduni/bun-golang-react-monorepo/server/realtime"
+)
+
+// BuiltinTasks returns the server's maintenance tasks
+func BuiltinTasks(db *database.DB) []Task {
+	return []Task{
+		{
+			Name:     "sessions.cleanup",
+			Schedule: "*/15 * * * *",
+			Run: func(ctx context.Context) error {
+				deleted, err := db.CleanupExpiredSessions(ctx)
+				logCount("Deleted expired sessions", int64(deleted))
+				return err
+			},
+		},
+		{
+			// Rank keys grow long from repeated moves between neighbours
+			Name:     "items.rebalance-ranks",
+			Schedule: "@hourly",
+			Run: func(ctx context.Context) error {
+				users, err := db.RebalanceLongItemRanks(ctx)
+				logCount("Rebalanced item ranks", int64(users))
+				return err
+			},
+		},
+		{
+			Name:     "item-events.prune",
+			Schedule: "*/10 * * * *",
+			Run: func(ctx context.Context) error {
+				deleted, err := db.PruneItemEvents(ctx, realtime.EventRetention)
+				logCount("Pruned item events", deleted)
+				return err
+			},
+		},
+		{
+			Name:     "outbox.prune",
+			Schedule: "5 * * * *",
+			Run: func(ctx context.Context) error {
+				deleted, err := db.PruneDomainEvents(ctx, outbox.Retention)
+				logCount("Pruned outbox events", deleted)
+				return err
+			},
+		},
+		{
+			Name:     "jobs.prune",
+			Schedule: "35 * * * *",
+			Run: func(ctx context.Context) error {
+				deleted, err := db.PruneJobs(ctx, jobs.Retention)
+				logCount("Pruned finished jobs", deleted)
+				return err
+			},
+		},
+	}
+}
+
+func logCount(msg string, n int64) {
+	if n > 0 {
+		slog.Info(msg, "count", n)
+	}
+}
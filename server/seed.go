@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+type seedItem struct {
+	title, description string
+	status             models.ItemStatus
+}
+
+type seedUser struct {
+	email, name string
+	role        models.UserRole
+	provider    models.OAuthProvider // Set for OAuth-only users, who have no password
+	items       []seedItem
+}
+
+var seedUsers = []seedUser{
+	{email: "admin@example.com", name: "Admin User", role: models.RoleAdmin, items: []seedItem{
+		{"Set up CI/CD pipeline", "Configure GitHub Actions for automated deployments", models.ItemStatusCompleted},
+		{"Security audit", "Review authentication flow and fix vulnerabilities", models.ItemStatusActive},
+	}},
+	{email: "john.doe@example.com", name: "John Doe", role: models.RoleUser, items: []seedItem{
+		{"Complete project setup", "Set up the monorepo with Bun, Go and React", models.ItemStatusCompleted},
+		{"Implement authentication", "Add JWT auth with social login support", models.ItemStatusActive},
+	}},
+	{email: "jane.smith@example.com", name: "Jane Smith", role: models.RoleUser, items: []seedItem{
+		{"Design system components", "Create reusable UI components with Tailwind", models.ItemStatusActive},
+		{"Write API documentation", "Document all REST endpoints with examples", models.ItemStatusActive},
+	}},
+	{email: "mod@example.com", name: "Moderator User", role: models.RoleModerator},
+	{email: "oauth.user@gmail.com", name: "OAuth User", role: models.RoleUser, provider: models.OAuthProviderGoogle},
+}
+
+// runSeed inserts sample users and items. Users that already exist are left
+// untouched, so seeding twice is harmless.
+func runSeed(cfg *config.Config, args []string) int {
+	fs := newFlagSet("seed [--password PASSWORD] [--force]")
+	password := fs.String("password", "Password123", "password of the seeded users")
+	force := fs.Bool("force", false, "seed outside the development environment")
+	if positional, err := parseFlags(fs, args); err != nil || len(positional) > 0 {
+		return usageError(fs, err)
+	}
+	if !cfg.IsDevelopment() && !*force {
+		return fail("refusing to seed the %s environment without --force", cfg.Environment)
+	}
+	if !utils.ValidatePassword(*password) {
+		return fail("password must be at least 8 characters")
+	}
+
+	hash, err := utils.HashPassword(*password)
+	if err != nil {
+		return fail("failed to hash password: %v", err)
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return fail("%v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	created := 0
+	for _, seed := range seedUsers {
+		if _, err := db.GetUserByEmail(ctx, seed.email); err == nil {
+			fmt.Printf("  exists   %s\n", seed.email)
+			continue
+		} else if err != pgx.ErrNoRows {
+			return fail("%v", err)
+		}
+
+		user := &models.User{
+			ID:            utils.NewUserID(),
+			Email:         seed.email,
+			Name:          seed.name,
+			Role:          seed.role,
+			EmailVerified: true,
+		}
+		if seed.provider == "" {
+			user.PasswordHash = &hash
+		}
+
+		if err := db.WithTx(ctx, func(ctx context.Context) error {
+			if err := db.CreateUser(ctx, user); err != nil {
+				return err
+			}
+			if seed.provider != "" {
+				account := &models.OAuthAccount{
+					ID:                utils.NewOAuthAccountID(),
+					UserID:            user.ID,
+					Provider:          seed.provider,
+					ProviderAccountID: "seed-" + user.ID,
+				}
+				if err := db.CreateOAuthAccount(ctx, account); err != nil {
+					return err
+				}
+			}
+			// Inserted oldest first, since new items go to the top of the list
+			for i := len(seed.items) - 1; i >= 0; i-- {
+				item := &models.Item{
+					ID:          utils.NewItemID(),
+					UserID:      user.ID,
+					Title:       seed.items[i].title,
+					Description: seed.items[i].description,
+					Status:      seed.items[i].status,
+				}
+				if err := db.CreateItem(ctx, item); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return fail("failed to seed %s: %v", seed.email, err)
+		}
+
+		fmt.Printf("  created  %s (%s)\n", seed.email, seed.role)
+		created++
+	}
+
+	fmt.Printf("%d user(s) created", created)
+	if created > 0 {
+		fmt.Printf("; password users sign in with %q", *password)
+	}
+	fmt.Println()
+	return 0
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/config"
+)
+
+func runSessions(cfg *config.Config, args []string) int {
+	return runSubcommand(cfg, "sessions", args, map[string]func(*config.Config, []string) int{
+		"purge": runSessionsPurge,
+	})
+}
+
+// runSessionsPurge deletes expired sessions, or every session of one user or
+// of all users
+func runSessionsPurge(cfg *config.Config, args []string) int {
+	fs := newFlagSet("sessions purge [--user EMAIL | --all]")
+	email := fs.String("user", "", "delete every session of this user")
+	all := fs.Bool("all", false, "delete every session of every user")
+	if positional, err := parseFlags(fs, args); err != nil || len(positional) > 0 || (*all && *email != "") {
+		return usageError(fs, err)
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return fail("%v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	switch {
+	case *all:
+		deleted, err := db.DeleteAllSessions(ctx)
+		if err != nil {
+			return fail("failed to purge sessions: %v", err)
+		}
+		fmt.Printf("%d session(s) deleted\n", deleted)
+
+	case *email != "":
+		user, err := findUser(ctx, db, *email)
+		if err != nil {
+			return fail("%v", err)
+		}
+		deleted, err := db.DeleteUserSessions(ctx, user.ID)
+		if err != nil {
+			return fail("failed to purge sessions: %v", err)
+		}
+		fmt.Printf("%d session(s) of %s deleted\n", deleted, user.Email)
+
+	default:
+		deleted, err := db.CleanupExpiredSessions(ctx)
+		if err != nil {
+			return fail("failed to purge sessions: %v", err)
+		}
+		fmt.Printf("%d expired session(s) deleted\n", deleted)
+	}
+	return 0
+}
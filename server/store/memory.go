@@ -0,0 +1,950 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxMemoryEvents bounds the domain events a Memory store keeps
+	maxMemoryEvents = 1000
+
+	// maxTreeDepth bounds hierarchy walks, as the recursive queries do
+	maxTreeDepth = 64
+)
+
+// Memory is a thread-safe, in-process Store. Data is lost on restart.
+//
+// Transactions hold the store's write lock until they end and restore a
+// snapshot when they fail, so they are serializable. Stored values are never
+// modified in place, so a snapshot copies only the maps, not the values; but
+// every write takes one, making writes O(n) in the number of stored rows. That
+// suits development and test data, not large datasets.
+type Memory struct {
+	mu   sync.RWMutex
+	data *memoryData
+}
+
+type memoryData struct {
+	users    map[string]*models.User
+	sessions map[string]*models.Session
+	items    map[string]*models.Item
+	series   map[string]*models.ItemSeries
+	oauth    map[string]*models.OAuthAccount
+	events   []*models.DomainEvent
+	eventSeq int64
+}
+
+func NewMemory() *Memory {
+	return &Memory{data: &memoryData{
+		users:    make(map[string]*models.User),
+		sessions: make(map[string]*models.Session),
+		items:    make(map[string]*models.Item),
+		series:   make(map[string]*models.ItemSeries),
+		oauth:    make(map[string]*models.OAuthAccount),
+	}}
+}
+
+func (d *memoryData) snapshot() *memoryData {
+	return &memoryData{
+		users:    maps.Clone(d.users),
+		sessions: maps.Clone(d.sessions),
+		items:    maps.Clone(d.items),
+		series:   maps.Clone(d.series),
+		oauth:    maps.Clone(d.oauth),
+		events:   slices.Clone(d.events),
+		eventSeq: d.eventSeq,
+	}
+}
+
+type memoryTxKey struct{}
+
+func (m *Memory) inTx(ctx context.Context) bool {
+	tx, _ := ctx.Value(memoryTxKey{}).(*Memory)
+	return tx == m
+}
+
+// WithTx runs fn atomically: if it fails, every change it made is undone
+func (m *Memory) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !m.inTx(ctx) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		ctx = context.WithValue(ctx, memoryTxKey{}, m)
+	}
+
+	snapshot := m.data.snapshot()
+	if err := fn(ctx); err != nil {
+		m.data = snapshot
+		return err
+	}
+	return nil
+}
+
+// read calls fn under the read lock, or directly within a transaction
+func (m *Memory) read(ctx context.Context, fn func(d *memoryData) error) error {
+	if !m.inTx(ctx) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+	}
+	return fn(m.data)
+}
+
+// write calls fn atomically under the write lock, or directly within a transaction
+func (m *Memory) write(ctx context.Context, fn func(d *memoryData) error) error {
+	return m.WithTx(ctx, func(context.Context) error {
+		return fn(m.data)
+	})
+}
+
+func cloneUser(u *models.User) *models.User {
+	c := *u
+	return &c
+}
+
+func cloneSession(s *models.Session) *models.Session {
+	c := *s
+	return &c
+}
+
+func cloneItem(i *models.Item) *models.Item {
+	c := *i
+	c.Tags = slices.Clone(i.Tags)
+	c.Progress = nil
+	return &c
+}
+
+func cloneSeries(s *models.ItemSeries) *models.ItemSeries {
+	c := *s
+	c.ExDates = slices.Clone(s.ExDates)
+	return &c
+}
+
+func cloneOAuthAccount(a *models.OAuthAccount) *models.OAuthAccount {
+	c := *a
+	return &c
+}
+
+// ============================================================================
+// Users
+// ============================================================================
+
+func (m *Memory) CreateUser(ctx context.Context, user *models.User) error {
+	return m.write(ctx, func(d *memoryData) error {
+		if _, ok := d.users[user.ID]; ok {
+			return fmt.Errorf("user %s already exists", user.ID)
+		}
+		for _, u := range d.users {
+			if u.Email == user.Email {
+				return database.ErrEmailTaken
+			}
+		}
+		user.CreatedAt = time.Now()
+		user.UpdatedAt = user.CreatedAt
+		d.users[user.ID] = cloneUser(user)
+		return nil
+	})
+}
+
+func (m *Memory) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	var user *models.User
+	err := m.read(ctx, func(d *memoryData) error {
+		u, ok := d.users[id]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		user = cloneUser(u)
+		return nil
+	})
+	return user, err
+}
+
+func (m *Memory) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user *models.User
+	err := m.read(ctx, func(d *memoryData) error {
+		for _, u := range d.users {
+			if u.Email == email {
+				user = cloneUser(u)
+				return nil
+			}
+		}
+		return pgx.ErrNoRows
+	})
+	return user, err
+}
+
+func (m *Memory) UpdateUserRole(ctx context.Context, id string, role models.UserRole) error {
+	return m.updateUser(ctx, id, func(u *models.User) { u.Role = role })
+}
+
+func (m *Memory) UpdateUserPassword(ctx context.Context, id, passwordHash string) error {
+	return m.updateUser(ctx, id, func(u *models.User) { u.PasswordHash = &passwordHash })
+}
+
+func (m *Memory) updateUser(ctx context.Context, id string, update func(u *models.User)) error {
+	return m.write(ctx, func(d *memoryData) error {
+		u, ok := d.users[id]
+		if !ok {
+			return fmt.Errorf("user not found")
+		}
+		u = cloneUser(u)
+		update(u)
+		u.UpdatedAt = time.Now()
+		d.users[id] = u
+		return nil
+	})
+}
+
+// ============================================================================
+// Sessions
+// ============================================================================
+
+func (m *Memory) CreateSession(ctx context.Context, session *models.Session) error {
+	return m.write(ctx, func(d *memoryData) error {
+		if _, ok := d.users[session.UserID]; !ok {
+			return fmt.Errorf("user %s does not exist", session.UserID)
+		}
+		session.CreatedAt = time.Now()
+		d.sessions[session.ID] = cloneSession(session)
+		return nil
+	})
+}
+
+func (m *Memory) GetSessionByID(ctx context.Context, id string) (*models.Session, error) {
+	var session *models.Session
+	err := m.read(ctx, func(d *memoryData) error {
+		s, ok := d.sessions[id]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		session = cloneSession(s)
+		return nil
+	})
+	return session, err
+}
+
+// GetUserSessions returns a user's unexpired sessions, newest first
+func (m *Memory) GetUserSessions(ctx context.Context, userID string) ([]*models.Session, error) {
+	var sessions []*models.Session
+	err := m.read(ctx, func(d *memoryData) error {
+		now := time.Now()
+		for _, s := range d.sessions {
+			if s.UserID == userID && s.ExpiresAt.After(now) {
+				sessions = append(sessions, cloneSession(s))
+			}
+		}
+		return nil
+	})
+	slices.SortFunc(sessions, func(a, b *models.Session) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return sessions, err
+}
+
+func (m *Memory) DeleteSession(ctx context.Context, id string) error {
+	return m.write(ctx, func(d *memoryData) error {
+		if _, ok := d.sessions[id]; !ok {
+			return fmt.Errorf("session not found")
+		}
+		delete(d.sessions, id)
+		return nil
+	})
+}
+
+func (m *Memory) DeleteUserSessions(ctx context.Context, userID string) (int64, error) {
+	return m.deleteSessions(ctx, func(s *models.Session) bool { return s.UserID == userID })
+}
+
+func (m *Memory) DeleteAllSessions(ctx context.Context) (int64, error) {
+	return m.deleteSessions(ctx, func(*models.Session) bool { return true })
+}
+
+func (m *Memory) CleanupExpiredSessions(ctx context.Context) (int, error) {
+	now := time.Now()
+	deleted, err := m.deleteSessions(ctx, func(s *models.Session) bool { return s.ExpiresAt.Before(now) })
+	return int(deleted), err
+}
+
+func (m *Memory) deleteSessions(ctx context.Context, match func(s *models.Session) bool) (int64, error) {
+	var deleted int64
+	err := m.write(ctx, func(d *memoryData) error {
+		for id, s := range d.sessions {
+			if match(s) {
+				delete(d.sessions, id)
+				deleted++
+			}
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// ============================================================================
+// Items
+// ============================================================================
+
+// CreateItem inserts an item. Items without a rank are placed at the top of the user's list.
+func (m *Memory) CreateItem(ctx context.Context, item *models.Item) error {
+	return m.write(ctx, func(d *memoryData) error {
+		if err := d.checkItem(item); err != nil {
+			return err
+		}
+		if _, ok := d.items[item.ID]; ok {
+			return fmt.Errorf("item %s already exists", item.ID)
+		}
+		if item.Tags == nil {
+			item.Tags = []string{}
+		}
+		if item.Rank == "" {
+			rank, err := d.topItemRank(item.UserID)
+			if err != nil || len(rank) > utils.MaxRankLength {
+				d.rebalanceItemRanks(item.UserID)
+				if rank, err = d.topItemRank(item.UserID); err != nil {
+					return err
+				}
+			}
+			item.Rank = rank
+		}
+		item.CreatedAt = time.Now()
+		item.UpdatedAt = item.CreatedAt
+		d.items[item.ID] = cloneItem(item)
+		return nil
+	})
+}
+
+// checkItem enforces the constraints the items table declares
+func (d *memoryData) checkItem(item *models.Item) error {
+	if _, ok := d.users[item.UserID]; !ok {
+		return fmt.Errorf("user %s does not exist", item.UserID)
+	}
+	switch item.Status {
+	case models.ItemStatusActive, models.ItemStatusCompleted, models.ItemStatusArchived:
+	default:
+		return fmt.Errorf("invalid item status %q", item.Status)
+	}
+	if item.ParentID != nil {
+		if *item.ParentID == item.ID {
+			return fmt.Errorf("item %s cannot be its own parent", item.ID)
+		}
+		if _, ok := d.items[*item.ParentID]; !ok {
+			return fmt.Errorf("parent item %s does not exist", *item.ParentID)
+		}
+	}
+	if item.SeriesID != nil {
+		if _, ok := d.series[*item.SeriesID]; !ok {
+			return fmt.Errorf("item series %s does not exist", *item.SeriesID)
+		}
+	}
+	if item.ExternalID != nil {
+		for _, other := range d.items {
+			if other.ID != item.ID && other.UserID == item.UserID && other.ExternalID != nil && *other.ExternalID == *item.ExternalID {
+				return fmt.Errorf("external ID %s already used", *item.ExternalID)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Memory) GetItemByID(ctx context.Context, id string) (*models.Item, error) {
+	var item *models.Item
+	err := m.read(ctx, func(d *memoryData) error {
+		i, ok := d.items[id]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		item = cloneItem(i)
+		return nil
+	})
+	return item, err
+}
+
+// GetUserItems returns a user's items in list order
+func (m *Memory) GetUserItems(ctx context.Context, userID string) ([]*models.Item, error) {
+	var items []*models.Item
+	err := m.read(ctx, func(d *memoryData) error {
+		items = d.userItems(userID)
+		return nil
+	})
+	return items, err
+}
+
+// userItems returns copies of a user's items ordered by rank, newest first on ties
+func (d *memoryData) userItems(userID string) []*models.Item {
+	var items []*models.Item
+	for _, i := range d.items {
+		if i.UserID == userID {
+			items = append(items, cloneItem(i))
+		}
+	}
+	slices.SortFunc(items, func(a, b *models.Item) int {
+		return cmp.Or(cmp.Compare(a.Rank, b.Rank), b.CreatedAt.Compare(a.CreatedAt))
+	})
+	return items
+}
+
+// GetFilteredUserItems returns a user's items matching filter, soonest due first
+func (m *Memory) GetFilteredUserItems(ctx context.Context, userID string, filter models.ItemFilter) ([]*models.Item, error) {
+	var items []*models.Item
+	err := m.read(ctx, func(d *memoryData) error {
+		for _, i := range d.items {
+			if i.UserID != userID ||
+				(len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, i.Status)) ||
+				(len(filter.Tags) > 0 && !slices.ContainsFunc(i.Tags, func(tag string) bool { return slices.Contains(filter.Tags, tag) })) ||
+				(filter.HasDueDate && i.DueAt == nil) {
+				continue
+			}
+			items = append(items, cloneItem(i))
+		}
+		return nil
+	})
+	slices.SortFunc(items, func(a, b *models.Item) int {
+		switch {
+		case a.DueAt == nil && b.DueAt != nil:
+			return 1
+		case a.DueAt != nil && b.DueAt == nil:
+			return -1
+		case a.DueAt != nil && b.DueAt != nil:
+			if c := a.DueAt.Compare(*b.DueAt); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.Rank, b.Rank)
+	})
+	return items, err
+}
+
+func (m *Memory) UpdateItem(ctx context.Context, item *models.Item) error {
+	return m.write(ctx, func(d *memoryData) error {
+		existing, ok := d.items[item.ID]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		updated := cloneItem(existing)
+		updated.Title = item.Title
+		updated.Description = item.Description
+		updated.Status = item.Status
+		updated.DueAt = item.DueAt
+		updated.Tags = slices.Clone(item.Tags)
+		if updated.Tags == nil {
+			updated.Tags = []string{}
+		}
+		updated.SeriesID = item.SeriesID
+		updated.RecurrenceID = item.RecurrenceID
+		if err := d.checkItem(updated); err != nil {
+			return err
+		}
+
+		updated.UpdatedAt = time.Now()
+		item.Tags = updated.Tags
+		item.UpdatedAt = updated.UpdatedAt
+		d.items[item.ID] = updated
+		return nil
+	})
+}
+
+// DeleteItem deletes an item along with its subtasks
+func (m *Memory) DeleteItem(ctx context.Context, id string) error {
+	return m.write(ctx, func(d *memoryData) error {
+		if _, ok := d.items[id]; !ok {
+			return fmt.Errorf("item not found")
+		}
+		for _, node := range d.subtree(id) {
+			delete(d.items, node.ID)
+		}
+		return nil
+	})
+}
+
+// StreamUserItems calls fn for each of the user's items in list order.
+// Iteration stops at the first error from fn.
+func (m *Memory) StreamUserItems(ctx context.Context, userID string, fn func(*models.Item) error) error {
+	items, err := m.GetUserItems(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportItems inserts items atomically, upserting items that carry an
+// external ID. New items are appended to the end of the user's list in input
+// order. It returns the number of created and updated items.
+func (m *Memory) ImportItems(ctx context.Context, userID string, items []*models.Item) (created, updated int, err error) {
+	err = m.write(ctx, func(d *memoryData) error {
+		// All imported keys share a prefix sorting after the current last item
+		var last string
+		for _, i := range d.items {
+			if i.UserID == userID {
+				last = max(last, i.Rank)
+			}
+		}
+		prefix, err := utils.RankBetween(last, "")
+		if err != nil {
+			prefix = "z"
+		}
+		ranks := utils.EvenRanks(len(items))
+
+		now := time.Now()
+		for n, item := range items {
+			if item.Tags == nil {
+				item.Tags = []string{}
+			}
+
+			var existing *models.Item
+			if item.ExternalID != nil {
+				for _, i := range d.items {
+					if i.UserID == userID && i.ExternalID != nil && *i.ExternalID == *item.ExternalID {
+						existing = i
+						break
+					}
+				}
+			}
+
+			row := cloneItem(item)
+			if existing != nil {
+				row = cloneItem(existing)
+				row.Title = item.Title
+				row.Description = item.Description
+				row.Status = item.Status
+				row.DueAt = item.DueAt
+				row.Tags = slices.Clone(item.Tags)
+			} else {
+				row.UserID = userID
+				row.ParentID = nil
+				row.SeriesID = nil
+				row.RecurrenceID = nil
+				row.Rank = prefix + ranks[n]
+				row.CreatedAt = now
+			}
+			row.UpdatedAt = now
+
+			if err := d.checkItem(row); err != nil {
+				return fmt.Errorf("row %d: %w", n+1, err)
+			}
+			d.items[row.ID] = row
+			if existing != nil {
+				updated++
+			} else {
+				created++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return created, updated, nil
+}
+
+// ============================================================================
+// Item Ranks
+// ============================================================================
+
+// topItemRank returns a rank placing a new item above all of the user's items
+func (d *memoryData) topItemRank(userID string) (string, error) {
+	var first string
+	for _, i := range d.items {
+		if i.UserID == userID && i.Rank != "" && (first == "" || i.Rank < first) {
+			first = i.Rank
+		}
+	}
+	return utils.RankBetween("", first)
+}
+
+// LockUserItems is a no-op; transactions are exclusive already
+func (m *Memory) LockUserItems(ctx context.Context, userID string) error {
+	return nil
+}
+
+// MoveItem places itemID directly before or after anchorID in the user's list
+// and returns the item's new rank. Ties and keys that grow past
+// utils.MaxRankLength trigger a rebalance of the user's list.
+func (m *Memory) MoveItem(ctx context.Context, userID, itemID, anchorID string, before bool) (string, error) {
+	var rank string
+	err := m.write(ctx, func(d *memoryData) error {
+		var err error
+		rank, err = d.rankNextTo(userID, itemID, anchorID, before)
+		if err == pgx.ErrNoRows {
+			return err
+		}
+		if err != nil || len(rank) > utils.MaxRankLength {
+			d.rebalanceItemRanks(userID)
+			if rank, err = d.rankNextTo(userID, itemID, anchorID, before); err != nil {
+				return err
+			}
+		}
+
+		if item, ok := d.items[itemID]; ok {
+			item = cloneItem(item)
+			item.Rank = rank
+			item.UpdatedAt = time.Now()
+			d.items[itemID] = item
+		}
+		return nil
+	})
+	return rank, err
+}
+
+// rankNextTo computes a key between anchorID and its neighbour (ignoring the moved item).
+// It fails when the anchor shares its rank with another item.
+func (d *memoryData) rankNextTo(userID, itemID, anchorID string, before bool) (string, error) {
+	anchor, ok := d.items[anchorID]
+	if !ok || anchor.UserID != userID {
+		return "", pgx.ErrNoRows
+	}
+
+	var neighbour string
+	for _, i := range d.items {
+		if i.UserID != userID || i.ID == itemID || i.ID == anchorID {
+			continue
+		}
+		switch {
+		case i.Rank == anchor.Rank:
+			return "", fmt.Errorf("rank of %s is not unique", anchorID)
+		case before && i.Rank < anchor.Rank && i.Rank > neighbour:
+			neighbour = i.Rank
+		case !before && i.Rank > anchor.Rank && (neighbour == "" || i.Rank < neighbour):
+			neighbour = i.Rank
+		}
+	}
+	if anchor.Rank == "" {
+		return "", fmt.Errorf("rank of %s is not unique", anchorID)
+	}
+
+	if before {
+		return utils.RankBetween(neighbour, anchor.Rank)
+	}
+	return utils.RankBetween(anchor.Rank, neighbour)
+}
+
+// rebalanceItemRanks rewrites a user's ranks as short, evenly spaced keys
+// preserving the current order
+func (d *memoryData) rebalanceItemRanks(userID string) {
+	items := d.userItems(userID)
+	ranks := utils.EvenRanks(len(items))
+	for n, item := range items {
+		item.Rank = ranks[n]
+		d.items[item.ID] = item
+	}
+}
+
+// RebalanceLongItemRanks rebalances every user whose longest rank exceeds
+// utils.MaxRankLength and returns the number of users rebalanced
+func (m *Memory) RebalanceLongItemRanks(ctx context.Context) (int, error) {
+	var rebalanced int
+	err := m.write(ctx, func(d *memoryData) error {
+		users := make(map[string]bool)
+		for _, i := range d.items {
+			if len(i.Rank) > utils.MaxRankLength {
+				users[i.UserID] = true
+			}
+		}
+		for userID := range users {
+			d.rebalanceItemRanks(userID)
+		}
+		rebalanced = len(users)
+		return nil
+	})
+	return rebalanced, err
+}
+
+// ============================================================================
+// Item Hierarchy
+// ============================================================================
+
+func (m *Memory) GetItemChildren(ctx context.Context, parentID string) ([]*models.Item, error) {
+	var items []*models.Item
+	err := m.read(ctx, func(d *memoryData) error {
+		items = d.children(parentID)
+		return nil
+	})
+	return items, err
+}
+
+// children returns copies of an item's direct subtasks ordered by rank, oldest first on ties
+func (d *memoryData) children(parentID string) []*models.Item {
+	var items []*models.Item
+	for _, i := range d.items {
+		if i.ParentID != nil && *i.ParentID == parentID {
+			items = append(items, cloneItem(i))
+		}
+	}
+	slices.SortFunc(items, func(a, b *models.Item) int {
+		return cmp.Or(cmp.Compare(a.Rank, b.Rank), a.CreatedAt.Compare(b.CreatedAt))
+	})
+	return items
+}
+
+// subtree returns rootID and its descendants level by level, as flat nodes
+func (d *memoryData) subtree(rootID string) []*models.ItemNode {
+	root, ok := d.items[rootID]
+	if !ok {
+		return nil
+	}
+
+	nodes := []*models.ItemNode{{Item: cloneItem(root)}}
+	level := nodes
+	for depth := 1; depth <= maxTreeDepth && len(level) > 0; depth++ {
+		var next []*models.ItemNode
+		for _, parent := range level {
+			for _, child := range d.children(parent.ID) {
+				next = append(next, &models.ItemNode{Item: child, Depth: depth})
+			}
+		}
+		slices.SortStableFunc(next, func(a, b *models.ItemNode) int {
+			return cmp.Or(cmp.Compare(a.Rank, b.Rank), a.CreatedAt.Compare(b.CreatedAt))
+		})
+		nodes = append(nodes, next...)
+		level = next
+	}
+	return nodes
+}
+
+// GetItemSubtree returns rootID and all of its descendants as nodes ordered by
+// depth. Nodes are returned flat with empty Children.
+func (m *Memory) GetItemSubtree(ctx context.Context, rootID string) ([]*models.ItemNode, error) {
+	var nodes []*models.ItemNode
+	err := m.read(ctx, func(d *memoryData) error {
+		nodes = d.subtree(rootID)
+		return nil
+	})
+	return nodes, err
+}
+
+// GetItemDepth returns the number of ancestors of an item (0 for top-level items)
+func (m *Memory) GetItemDepth(ctx context.Context, id string) (int, error) {
+	var depth int
+	err := m.read(ctx, func(d *memoryData) error {
+		item, ok := d.items[id]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		for item.ParentID != nil && depth < maxTreeDepth {
+			depth++
+			if item, ok = d.items[*item.ParentID]; !ok {
+				break
+			}
+		}
+		return nil
+	})
+	return depth, err
+}
+
+// GetItemSubtreeHeight returns the number of levels below an item (0 for leaves)
+func (m *Memory) GetItemSubtreeHeight(ctx context.Context, id string) (int, error) {
+	var height int
+	err := m.read(ctx, func(d *memoryData) error {
+		for _, node := range d.subtree(id) {
+			height = max(height, node.Depth)
+		}
+		return nil
+	})
+	return height, err
+}
+
+// IsItemDescendant reports whether candidateID lies in the subtree rooted at ancestorID
+// (an item counts as its own descendant)
+func (m *Memory) IsItemDescendant(ctx context.Context, ancestorID, candidateID string) (bool, error) {
+	var found bool
+	err := m.read(ctx, func(d *memoryData) error {
+		found = slices.ContainsFunc(d.subtree(ancestorID), func(node *models.ItemNode) bool {
+			return node.ID == candidateID
+		})
+		return nil
+	})
+	return found, err
+}
+
+// SetItemParent moves an item (and with it, its subtree) under a new parent
+func (m *Memory) SetItemParent(ctx context.Context, id string, parentID *string) error {
+	return m.write(ctx, func(d *memoryData) error {
+		item, ok := d.items[id]
+		if !ok {
+			return fmt.Errorf("item not found")
+		}
+		item = cloneItem(item)
+		item.ParentID = parentID
+		if err := d.checkItem(item); err != nil {
+			return err
+		}
+		item.UpdatedAt = time.Now()
+		d.items[id] = item
+		return nil
+	})
+}
+
+// GetItemsProgress rolls up descendant status counts for each of the given items.
+// Items without (non-archived) descendants are absent from the result.
+func (m *Memory) GetItemsProgress(ctx context.Context, ids []string) (map[string]*models.ItemProgress, error) {
+	progress := make(map[string]*models.ItemProgress)
+	err := m.read(ctx, func(d *memoryData) error {
+		for _, id := range ids {
+			var p models.ItemProgress
+			for _, node := range d.subtree(id)[1:] {
+				if node.Status == models.ItemStatusArchived {
+					continue
+				}
+				p.Total++
+				if node.Status == models.ItemStatusCompleted {
+					p.Completed++
+				}
+			}
+			if p.Total > 0 {
+				p.Percent = p.Completed * 100 / p.Total
+				progress[id] = &p
+			}
+		}
+		return nil
+	})
+	return progress, err
+}
+
+// ============================================================================
+// Item Series
+// ============================================================================
+
+func (m *Memory) CreateItemSeries(ctx context.Context, series *models.ItemSeries) error {
+	return m.write(ctx, func(d *memoryData) error {
+		if _, ok := d.users[series.UserID]; !ok {
+			return fmt.Errorf("user %s does not exist", series.UserID)
+		}
+		if series.ExDates == nil {
+			series.ExDates = []time.Time{}
+		}
+		series.CreatedAt = time.Now()
+		series.UpdatedAt = series.CreatedAt
+		d.series[series.ID] = cloneSeries(series)
+		return nil
+	})
+}
+
+func (m *Memory) GetItemSeriesByID(ctx context.Context, id string) (*models.ItemSeries, error) {
+	var series *models.ItemSeries
+	err := m.read(ctx, func(d *memoryData) error {
+		s, ok := d.series[id]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		series = cloneSeries(s)
+		return nil
+	})
+	return series, err
+}
+
+// LockItemSeries only checks the series exists; transactions are exclusive already
+func (m *Memory) LockItemSeries(ctx context.Context, id string) error {
+	return m.read(ctx, func(d *memoryData) error {
+		if _, ok := d.series[id]; !ok {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+}
+
+func (m *Memory) UpdateItemSeries(ctx context.Context, series *models.ItemSeries) error {
+	return m.write(ctx, func(d *memoryData) error {
+		existing, ok := d.series[series.ID]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		updated := cloneSeries(series)
+		updated.UserID = existing.UserID
+		updated.CreatedAt = existing.CreatedAt
+		updated.UpdatedAt = time.Now()
+		series.UpdatedAt = updated.UpdatedAt
+		d.series[series.ID] = updated
+		return nil
+	})
+}
+
+// ============================================================================
+// OAuth Accounts
+// ============================================================================
+
+func (m *Memory) CreateOAuthAccount(ctx context.Context, account *models.OAuthAccount) error {
+	return m.write(ctx, func(d *memoryData) error {
+		if _, ok := d.users[account.UserID]; !ok {
+			return fmt.Errorf("user %s does not exist", account.UserID)
+		}
+		for _, a := range d.oauth {
+			if a.Provider == account.Provider && (a.ProviderAccountID == account.ProviderAccountID || a.UserID == account.UserID) {
+				return database.ErrOAuthAccountLinked
+			}
+		}
+		account.CreatedAt = time.Now()
+		account.UpdatedAt = account.CreatedAt
+		d.oauth[account.ID] = cloneOAuthAccount(account)
+		return nil
+	})
+}
+
+func (m *Memory) GetOAuthAccount(ctx context.Context, provider models.OAuthProvider, providerAccountID string) (*models.OAuthAccount, error) {
+	var account *models.OAuthAccount
+	err := m.read(ctx, func(d *memoryData) error {
+		for _, a := range d.oauth {
+			if a.Provider == provider && a.ProviderAccountID == providerAccountID {
+				account = cloneOAuthAccount(a)
+				return nil
+			}
+		}
+		return pgx.ErrNoRows
+	})
+	return account, err
+}
+
+// ============================================================================
+// Domain Events
+// ============================================================================
+
+// RecordDomainEvent keeps the event if the surrounding transaction succeeds.
+// No relay runs in memory; DomainEvents exposes the most recent events.
+func (m *Memory) RecordDomainEvent(ctx context.Context, event *models.DomainEvent, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if event.ID == "" {
+		event.ID = utils.NewEventID()
+	}
+	if id := utils.RequestIDFromContext(ctx); event.RequestID == nil && id != "" {
+		event.RequestID = &id
+	}
+	event.Payload = payload
+
+	return m.write(ctx, func(d *memoryData) error {
+		d.eventSeq++
+		event.Seq = d.eventSeq
+		event.CreatedAt = time.Now()
+
+		c := *event
+		d.events = append(d.events, &c)
+		if len(d.events) > maxMemoryEvents {
+			d.events = slices.Clone(d.events[len(d.events)-maxMemoryEvents:])
+		}
+		return nil
+	})
+}
+
+// DomainEvents returns the recorded events, oldest first
+func (m *Memory) DomainEvents() []models.DomainEvent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := make([]models.DomainEvent, len(m.data.events))
+	for i, e := range m.data.events {
+		events[i] = *e
+	}
+	return events
+}
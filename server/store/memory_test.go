@@ -0,0 +1,214 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/store"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+var errAbort = errors.New("abort")
+
+// forEachStore runs test against a Memory store and, when TEST_DATABASE_URL
+// names a disposable database, against Postgres too, so both implementations
+// are held to the same behavior
+func forEachStore(t *testing.T, test func(t *testing.T, s store.Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, store.NewMemory())
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		url := os.Getenv("TEST_DATABASE_URL")
+		if url == "" {
+			t.Skip("TEST_DATABASE_URL is not set")
+		}
+		db, err := database.Connect(url, database.Options{})
+		if err != nil {
+			t.Fatalf("Connect: %v", err)
+		}
+		t.Cleanup(db.Close)
+		if _, err := db.MigrateUp(context.Background()); err != nil {
+			t.Fatalf("MigrateUp: %v", err)
+		}
+		test(t, db)
+	})
+}
+
+// createUser stores a user with a unique email, so tests can share a database
+func createUser(t *testing.T, s store.Store) *models.User {
+	t.Helper()
+	id := utils.NewUserID()
+	user := &models.User{ID: id, Email: id + "@example.com", Name: "Test", Role: models.RoleUser}
+	if err := s.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return user
+}
+
+func createItem(t *testing.T, s store.Store, userID string, parentID *string, status models.ItemStatus) *models.Item {
+	t.Helper()
+	item := &models.Item{ID: utils.NewItemID(), UserID: userID, Title: "Item", Status: status, ParentID: parentID}
+	if err := s.CreateItem(context.Background(), item); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+	return item
+}
+
+func itemIDs(items []*models.Item) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestOwnershipFilters(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		alice, bob := createUser(t, s), createUser(t, s)
+		active := createItem(t, s, alice.ID, nil, models.ItemStatusActive)
+		done := createItem(t, s, alice.ID, nil, models.ItemStatusCompleted)
+		createItem(t, s, bob.ID, nil, models.ItemStatusActive)
+
+		items, err := s.GetUserItems(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetUserItems: %v", err)
+		}
+		if got, want := itemIDs(items), itemIDs([]*models.Item{active, done}); !slices.Equal(got, want) {
+			t.Errorf("GetUserItems = %v, want %v", got, want)
+		}
+
+		filter := models.ItemFilter{Statuses: []models.ItemStatus{models.ItemStatusActive}}
+		items, err = s.GetFilteredUserItems(ctx, alice.ID, filter)
+		if err != nil {
+			t.Fatalf("GetFilteredUserItems: %v", err)
+		}
+		if got, want := itemIDs(items), []string{active.ID}; !slices.Equal(got, want) {
+			t.Errorf("GetFilteredUserItems = %v, want %v", got, want)
+		}
+
+		for _, user := range []*models.User{alice, bob} {
+			session := &models.Session{ID: utils.NewSessionID(), UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}
+			if err := s.CreateSession(ctx, session); err != nil {
+				t.Fatalf("CreateSession: %v", err)
+			}
+		}
+		sessions, err := s.GetUserSessions(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetUserSessions: %v", err)
+		}
+		if len(sessions) != 1 || sessions[0].UserID != alice.ID {
+			t.Errorf("GetUserSessions returned %d sessions, want only alice's one", len(sessions))
+		}
+
+		dup := &models.User{ID: utils.NewUserID(), Email: alice.Email, Name: "Dup", Role: models.RoleUser}
+		if err := s.CreateUser(ctx, dup); !errors.Is(err, store.ErrEmailTaken) {
+			t.Errorf("CreateUser with a taken email = %v, want ErrEmailTaken", err)
+		}
+	})
+}
+
+func TestDeleteCascades(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		alice, bob := createUser(t, s), createUser(t, s)
+		root := createItem(t, s, alice.ID, nil, models.ItemStatusActive)
+		child := createItem(t, s, alice.ID, &root.ID, models.ItemStatusActive)
+		grandchild := createItem(t, s, alice.ID, &child.ID, models.ItemStatusActive)
+		sibling := createItem(t, s, alice.ID, nil, models.ItemStatusActive)
+
+		if err := s.DeleteItem(ctx, root.ID); err != nil {
+			t.Fatalf("DeleteItem: %v", err)
+		}
+		for _, id := range []string{root.ID, child.ID, grandchild.ID} {
+			if _, err := s.GetItemByID(ctx, id); err != pgx.ErrNoRows {
+				t.Errorf("GetItemByID(%s) after deleting its ancestor = %v, want ErrNoRows", id, err)
+			}
+		}
+		if _, err := s.GetItemByID(ctx, sibling.ID); err != nil {
+			t.Errorf("GetItemByID(sibling): %v", err)
+		}
+		if err := s.DeleteItem(ctx, root.ID); err == nil {
+			t.Error("DeleteItem of a deleted item succeeded")
+		}
+
+		for _, user := range []*models.User{alice, alice, bob} {
+			session := &models.Session{ID: utils.NewSessionID(), UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}
+			if err := s.CreateSession(ctx, session); err != nil {
+				t.Fatalf("CreateSession: %v", err)
+			}
+		}
+		if n, err := s.DeleteUserSessions(ctx, alice.ID); err != nil || n != 2 {
+			t.Errorf("DeleteUserSessions = %d, %v, want 2", n, err)
+		}
+		if sessions, err := s.GetUserSessions(ctx, bob.ID); err != nil || len(sessions) != 1 {
+			t.Errorf("bob has %d sessions (%v) after deleting alice's, want 1", len(sessions), err)
+		}
+	})
+}
+
+func TestWithTxRollback(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		alice := createUser(t, s)
+
+		// A failed transaction leaves nothing behind
+		id := utils.NewUserID()
+		user := &models.User{ID: id, Email: id + "@example.com", Name: "Test", Role: models.RoleUser}
+		item := &models.Item{ID: utils.NewItemID(), UserID: user.ID, Title: "Item", Status: models.ItemStatusActive}
+		err := s.WithTx(ctx, func(ctx context.Context) error {
+			if err := s.CreateUser(ctx, user); err != nil {
+				return err
+			}
+			if err := s.CreateItem(ctx, item); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if err != errAbort {
+			t.Fatalf("WithTx = %v, want errAbort", err)
+		}
+		if _, err := s.GetUserByID(ctx, user.ID); err != pgx.ErrNoRows {
+			t.Errorf("GetUserByID after rollback = %v, want ErrNoRows", err)
+		}
+		if _, err := s.GetItemByID(ctx, item.ID); err != pgx.ErrNoRows {
+			t.Errorf("GetItemByID after rollback = %v, want ErrNoRows", err)
+		}
+
+		// A failed nested transaction only undoes its own changes
+		kept := &models.Item{ID: utils.NewItemID(), UserID: alice.ID, Title: "Kept", Status: models.ItemStatusActive}
+		undone := &models.Item{ID: utils.NewItemID(), UserID: alice.ID, Title: "Undone", Status: models.ItemStatusActive}
+		err = s.WithTx(ctx, func(ctx context.Context) error {
+			if err := s.CreateItem(ctx, kept); err != nil {
+				return err
+			}
+			if err := s.WithTx(ctx, func(ctx context.Context) error {
+				if err := s.CreateItem(ctx, undone); err != nil {
+					return err
+				}
+				return errAbort
+			}); err != errAbort {
+				t.Errorf("nested WithTx = %v, want errAbort", err)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WithTx: %v", err)
+		}
+		if _, err := s.GetItemByID(ctx, kept.ID); err != nil {
+			t.Errorf("GetItemByID(kept): %v", err)
+		}
+		if _, err := s.GetItemByID(ctx, undone.ID); err != pgx.ErrNoRows {
+			t.Errorf("GetItemByID(undone) = %v, want ErrNoRows", err)
+		}
+	})
+}
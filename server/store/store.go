@@ -0,0 +1,103 @@
+// Package store defines the storage the auth and items handlers depend on.
+// *database.DB implements it on Postgres; Memory implements it in process for
+// running without a database and for tests.
+//
+// Lookups of a missing row return pgx.ErrNoRows in every implementation.
+package store
+
+import (
+	"context"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+)
+
+// Errors for violated uniqueness, returned by every implementation
+var (
+	ErrEmailTaken         = database.ErrEmailTaken
+	ErrOAuthAccountLinked = database.ErrOAuthAccountLinked
+)
+
+// Serializable returns a context whose transactions run at the serializable
+// isolation level, so WithTx retries them when a concurrent change conflicts
+// with rows they read. Memory transactions are always serialized.
+func Serializable(ctx context.Context) context.Context {
+	return database.Serializable(ctx)
+}
+
+// UserStore persists user accounts
+type UserStore interface {
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByID(ctx context.Context, id string) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	UpdateUserRole(ctx context.Context, id string, role models.UserRole) error
+	UpdateUserPassword(ctx context.Context, id, passwordHash string) error
+}
+
+// SessionStore persists login sessions
+type SessionStore interface {
+	CreateSession(ctx context.Context, session *models.Session) error
+	GetSessionByID(ctx context.Context, id string) (*models.Session, error)
+	GetUserSessions(ctx context.Context, userID string) ([]*models.Session, error)
+	DeleteSession(ctx context.Context, id string) error
+	DeleteUserSessions(ctx context.Context, userID string) (int64, error)
+	DeleteAllSessions(ctx context.Context) (int64, error)
+	CleanupExpiredSessions(ctx context.Context) (int, error)
+}
+
+// ItemStore persists items, their ordering, hierarchy and recurrence
+type ItemStore interface {
+	CreateItem(ctx context.Context, item *models.Item) error
+	GetItemByID(ctx context.Context, id string) (*models.Item, error)
+	GetUserItems(ctx context.Context, userID string) ([]*models.Item, error)
+	GetFilteredUserItems(ctx context.Context, userID string, filter models.ItemFilter) ([]*models.Item, error)
+	UpdateItem(ctx context.Context, item *models.Item) error
+	DeleteItem(ctx context.Context, id string) error
+
+	StreamUserItems(ctx context.Context, userID string, fn func(*models.Item) error) error
+	ImportItems(ctx context.Context, userID string, items []*models.Item) (created, updated int, err error)
+
+	LockUserItems(ctx context.Context, userID string) error
+	MoveItem(ctx context.Context, userID, itemID, anchorID string, before bool) (string, error)
+	RebalanceLongItemRanks(ctx context.Context) (int, error)
+
+	GetItemChildren(ctx context.Context, parentID string) ([]*models.Item, error)
+	GetItemSubtree(ctx context.Context, rootID string) ([]*models.ItemNode, error)
+	GetItemDepth(ctx context.Context, id string) (int, error)
+	GetItemSubtreeHeight(ctx context.Context, id string) (int, error)
+	IsItemDescendant(ctx context.Context, ancestorID, candidateID string) (bool, error)
+	SetItemParent(ctx context.Context, id string, parentID *string) error
+	GetItemsProgress(ctx context.Context, ids []string) (map[string]*models.ItemProgress, error)
+
+	CreateItemSeries(ctx context.Context, series *models.ItemSeries) error
+	GetItemSeriesByID(ctx context.Context, id string) (*models.ItemSeries, error)
+	LockItemSeries(ctx context.Context, id string) error
+	UpdateItemSeries(ctx context.Context, series *models.ItemSeries) error
+}
+
+// OAuthAccountStore persists links between users and OAuth provider accounts
+type OAuthAccountStore interface {
+	CreateOAuthAccount(ctx context.Context, account *models.OAuthAccount) error
+	GetOAuthAccount(ctx context.Context, provider models.OAuthProvider, providerAccountID string) (*models.OAuthAccount, error)
+}
+
+// Store is everything the handlers need, including atomic multi-step writes
+type Store interface {
+	UserStore
+	SessionStore
+	ItemStore
+	OAuthAccountStore
+
+	// WithTx runs fn atomically; every call made with the context it
+	// receives joins the transaction. Nested calls use savepoints. fn may be
+	// run again if the transaction conflicts with another one.
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+
+	// RecordDomainEvent records an event that commits with the transaction
+	RecordDomainEvent(ctx context.Context, event *models.DomainEvent, data any) error
+}
+
+var (
+	_ Store = (*database.DB)(nil)
+	_ Store = (*Memory)(nil)
+)
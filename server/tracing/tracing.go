@@ -0,0 +1,72 @@
+// Package tracing configures OpenTelemetry: W3C trace context propagation
+// and, when an OTLP endpoint is configured, span export.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Options configures Setup
+type Options struct {
+	// Endpoint is the OTLP/HTTP collector URL, e.g. http://localhost:4318.
+	// Without one spans are not exported, but incoming trace context is still
+	// propagated, so logs carry the client's trace IDs.
+	Endpoint string
+
+	ServiceName string
+	Version     string
+	Environment string
+
+	// SampleRatio is the fraction of traces recorded, including those a caller
+	// started: a caller's sampled flag is not trusted, since any client could
+	// set it to force tracing. The decision depends only on the trace ID, so
+	// services sharing a ratio agree on it.
+	SampleRatio float64
+}
+
+// Setup installs the global propagator and tracer provider. The returned
+// function flushes pending spans and stops the exporter.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+	if opts.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(opts.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+		semconv.ServiceVersion(opts.Version),
+		semconv.DeploymentEnvironmentName(opts.Environment),
+	)
+	ratio := sdktrace.TraceIDRatioBased(opts.SampleRatio)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(ratio,
+			sdktrace.WithRemoteParentSampled(ratio),
+			sdktrace.WithRemoteParentNotSampled(ratio),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns a tracer of the global provider, named after the
+// instrumenting package. Tracers obtained before Setup use its provider too.
+func Tracer(pkg string) trace.Tracer {
+	return otel.Tracer("github.com/binduni/bun-golang-react-monorepo/server/" + pkg)
+}
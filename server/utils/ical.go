@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"strings"
+	"time"
+)
+
+// ICalBuilder writes an RFC 5545 iCalendar document with CRLF line endings
+// and 75-octet line folding
+type ICalBuilder struct {
+	sb strings.Builder
+}
+
+// NewICalendar starts a VCALENDAR with the given product ID and display name
+func NewICalendar(prodID, name string) *ICalBuilder {
+	b := &ICalBuilder{}
+	b.Line("BEGIN", "VCALENDAR")
+	b.Line("VERSION", "2.0")
+	b.Line("PRODID", prodID)
+	b.Line("CALSCALE", "GREGORIAN")
+	b.Line("METHOD", "PUBLISH")
+	if name != "" {
+		b.Line("X-WR-CALNAME", EscapeICalText(name))
+	}
+	return b
+}
+
+// Line writes a single "NAME:value" content line. value must already be escaped.
+func (b *ICalBuilder) Line(name, value string) {
+	line := name + ":" + value
+	// Fold lines longer than 75 octets without splitting UTF-8 sequences. A
+	// continuation line starts with a space, leaving 74 octets of content.
+	for limit := 75; len(line) > limit; limit = 74 {
+		cut := limit
+		for cut > 0 && !isUTF8Start(line[cut]) {
+			cut--
+		}
+		b.sb.WriteString(line[:cut])
+		b.sb.WriteString("\r\n ")
+		line = line[cut:]
+	}
+	b.sb.WriteString(line)
+	b.sb.WriteString("\r\n")
+}
+
+// Time writes a UTC date-time property
+func (b *ICalBuilder) Time(name string, t time.Time) {
+	b.Line(name, FormatICalTime(t))
+}
+
+// String closes the calendar and returns the document
+func (b *ICalBuilder) String() string {
+	b.Line("END", "VCALENDAR")
+	return b.sb.String()
+}
+
+// FormatICalTime formats t as a UTC iCalendar DATE-TIME
+func FormatICalTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// EscapeICalText escapes a TEXT value per RFC 5545 section 3.3.11
+func EscapeICalText(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+	return r.Replace(s)
+}
+
+func isUTF8Start(b byte) bool {
+	return b&0xC0 != 0x80
+}
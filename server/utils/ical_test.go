@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestICalLineFolding(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "short", value: "Groceries"},
+		{name: "exactly one line", value: strings.Repeat("a", 75-len("SUMMARY:"))},
+		{name: "one octet over", value: strings.Repeat("a", 76-len("SUMMARY:"))},
+		{name: "many lines", value: strings.Repeat("abcdefghij", 40)},
+		{name: "multibyte", value: strings.Repeat("é日本", 60)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b ICalBuilder
+			b.Line("SUMMARY", tt.value)
+			out := b.sb.String()
+
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("output %q does not end with CRLF", out)
+			}
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			var unfolded strings.Builder
+			for i, line := range lines {
+				if len(line) > 75 {
+					t.Errorf("line %d is %d octets, want at most 75: %q", i, len(line), line)
+				}
+				if i > 0 {
+					if !strings.HasPrefix(line, " ") {
+						t.Fatalf("continuation line %d does not start with a space: %q", i, line)
+					}
+					line = line[1:]
+				}
+				if strings.ToValidUTF8(line, "�") != line {
+					t.Errorf("line %d splits a UTF-8 sequence: %q", i, line)
+				}
+				unfolded.WriteString(line)
+			}
+			if got, want := unfolded.String(), "SUMMARY:"+tt.value; got != want {
+				t.Errorf("unfolded = %q, want %q", got, want)
+			}
+		})
+	}
+}
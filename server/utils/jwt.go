@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -14,21 +15,23 @@ const (
 )
 
 type JWTClaims struct {
-	UserID string           `json:"sub"`
-	Email  string           `json:"email"`
-	Role   models.UserRole  `json:"role"`
-	Type   models.TokenType `json:"type"`
+	UserID    string           `json:"sub"`
+	Email     string           `json:"email"`
+	Role      models.UserRole  `json:"role"`
+	Type      models.TokenType `json:"type"`
+	SessionID string           `json:"sid,omitempty"` // Session the token was issued for
 	jwt.RegisteredClaims
 }
 
 // GenerateAccessToken generates a JWT access token
-func GenerateAccessToken(user *models.User, secret string) (string, error) {
+func GenerateAccessToken(user *models.User, sessionID, secret string) (string, error) {
 	now := time.Now()
 	claims := JWTClaims{
-		UserID: user.ID,
-		Email:  user.Email,
-		Role:   user.Role,
-		Type:   models.TokenTypeAccess,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		Type:      models.TokenTypeAccess,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenExpiry)),
@@ -40,13 +43,14 @@ func GenerateAccessToken(user *models.User, secret string) (string, error) {
 }
 
 // GenerateRefreshToken generates a JWT refresh token
-func GenerateRefreshToken(user *models.User, secret string) (string, error) {
+func GenerateRefreshToken(user *models.User, sessionID, secret string) (string, error) {
 	now := time.Now()
 	claims := JWTClaims{
-		UserID: user.ID,
-		Email:  user.Email,
-		Role:   user.Role,
-		Type:   models.TokenTypeRefresh,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		Type:      models.TokenTypeRefresh,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(RefreshTokenExpiry)),
@@ -57,8 +61,22 @@ func GenerateRefreshToken(user *models.User, secret string) (string, error) {
 	return token.SignedString([]byte(secret))
 }
 
-// ValidateToken validates and parses a JWT token
-func ValidateToken(tokenString string, secret string) (*JWTClaims, error) {
+// ValidateToken validates and parses a JWT token. Tokens signed with one of
+// the previous secrets are accepted too, so a secret can be rotated without
+// signing everyone out.
+func ValidateToken(tokenString string, secret string, previousSecrets ...string) (*JWTClaims, error) {
+	var err error
+	for _, key := range append([]string{secret}, previousSecrets...) {
+		var claims *JWTClaims
+		claims, err = parseToken(tokenString, key)
+		if !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			return claims, err
+		}
+	}
+	return nil, err
+}
+
+func parseToken(tokenString string, secret string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 		// Verify signing method
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"context"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/tracing"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -15,8 +18,14 @@ func HashPassword(password string) (string, error) {
 	return string(hash), nil
 }
 
-// VerifyPassword compares a hashed password with a plain text password
-func VerifyPassword(hashedPassword, password string) bool {
+var tracer = tracing.Tracer("utils")
+
+// VerifyPassword compares a hashed password with a plain text password. The
+// comparison is traced, as bcrypt dominates login latency.
+func VerifyPassword(ctx context.Context, hashedPassword, password string) bool {
+	_, span := tracer.Start(ctx, "bcrypt.CompareHashAndPassword")
+	defer span.End()
+
 	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 	return err == nil
 }
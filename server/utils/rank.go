@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// rankDigits are base-62 digits in ASCII order, so keys compare correctly
+// with byte-wise (COLLATE "C") string ordering
+const rankDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// MaxRankLength is the key length above which a user's ranks should be rebalanced
+const MaxRankLength = 24
+
+// RankBetween returns a key that sorts strictly between lower and upper.
+// An empty lower means "before everything", an empty upper "after everything".
+// Keys never end in the zero digit, which guarantees a key always exists
+// between two distinct valid keys.
+func RankBetween(lower, upper string) (string, error) {
+	if upper != "" && lower >= upper {
+		return "", fmt.Errorf("rank %q is not below %q", lower, upper)
+	}
+	if strings.HasSuffix(lower, "0") || strings.HasSuffix(upper, "0") {
+		return "", fmt.Errorf("rank keys must not end with %q", rankDigits[0])
+	}
+	for _, key := range []string{lower, upper} {
+		for i := 0; i < len(key); i++ {
+			if strings.IndexByte(rankDigits, key[i]) < 0 {
+				return "", fmt.Errorf("invalid rank key %q", key)
+			}
+		}
+	}
+	return rankMidpoint(lower, upper), nil
+}
+
+func rankMidpoint(lower, upper string) string {
+	if upper != "" {
+		// Shared prefix (treating a missing lower digit as zero) is kept as-is
+		n := 0
+		for n < len(upper) && rankDigitAt(lower, n) == upper[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(lower) {
+				rest = lower[n:]
+			}
+			return upper[:n] + rankMidpoint(rest, upper[n:])
+		}
+	}
+
+	digitLower := 0
+	if lower != "" {
+		digitLower = strings.IndexByte(rankDigits, lower[0])
+	}
+	digitUpper := len(rankDigits)
+	if upper != "" {
+		digitUpper = strings.IndexByte(rankDigits, upper[0])
+	}
+
+	if digitUpper-digitLower > 1 {
+		return string(rankDigits[(digitLower+digitUpper+1)/2])
+	}
+
+	// Adjacent digits: a shorter prefix of upper works if upper continues past it
+	if len(upper) > 1 {
+		return upper[:1]
+	}
+	rest := ""
+	if len(lower) > 1 {
+		rest = lower[1:]
+	}
+	return string(rankDigits[digitLower]) + rankMidpoint(rest, "")
+}
+
+func rankDigitAt(key string, i int) byte {
+	if i < len(key) {
+		return key[i]
+	}
+	return rankDigits[0]
+}
+
+// EvenRanks returns n ascending keys spread evenly across the key space,
+// used to rebalance a list whose keys have grown long
+func EvenRanks(n int) []string {
+	if n == 0 {
+		return nil
+	}
+	base := big.NewInt(int64(len(rankDigits)))
+
+	// Pick the shortest width leaving room for several inserts between neighbours
+	width := 1
+	space := new(big.Int).Set(base)
+	minSpace := big.NewInt(int64(n+1) * 16)
+	for space.Cmp(minSpace) < 0 {
+		space.Mul(space, base)
+		width++
+	}
+	step := new(big.Int).Div(space, big.NewInt(int64(n+1)))
+
+	ranks := make([]string, n)
+	value := new(big.Int)
+	for i := range ranks {
+		value.Add(value, step)
+		ranks[i] = strings.TrimRight(encodeRank(value, width, base), rankDigits[:1])
+	}
+	return ranks
+}
+
+func encodeRank(value *big.Int, width int, base *big.Int) string {
+	digits := make([]byte, width)
+	v := new(big.Int).Set(value)
+	mod := new(big.Int)
+	for i := width - 1; i >= 0; i-- {
+		v.DivMod(v, base, mod)
+		digits[i] = rankDigits[mod.Int64()]
+	}
+	return string(digits)
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRankBetween(t *testing.T) {
+	tests := []struct {
+		name         string
+		lower, upper string
+		wantErr      bool
+	}{
+		{name: "empty list", lower: "", upper: ""},
+		{name: "before everything", lower: "", upper: "V"},
+		{name: "after everything", lower: "V", upper: ""},
+		{name: "wide gap", lower: "1", upper: "z"},
+		{name: "adjacent digits", lower: "A", upper: "B"},
+		{name: "shared prefix", lower: "AB", upper: "AC"},
+		{name: "lower is a prefix of upper", lower: "A", upper: "A1"},
+		{name: "upper continues past adjacent digit", lower: "A", upper: "BZ"},
+		{name: "before the smallest key", lower: "", upper: "1"},
+		{name: "after the largest key", lower: "z", upper: ""},
+		{name: "long keys", lower: "zzzzzzzy", upper: "zzzzzzzz"},
+		{name: "equal keys", lower: "V", upper: "V", wantErr: true},
+		{name: "reversed keys", lower: "b", upper: "a", wantErr: true},
+		{name: "trailing zero", lower: "A0", upper: "B", wantErr: true},
+		{name: "invalid digit", lower: "A-", upper: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RankBetween(tt.lower, tt.upper)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("RankBetween(%q, %q) = %q, want error", tt.lower, tt.upper, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RankBetween(%q, %q): %v", tt.lower, tt.upper, err)
+			}
+			if got <= tt.lower || (tt.upper != "" && got >= tt.upper) {
+				t.Errorf("RankBetween(%q, %q) = %q, not strictly between", tt.lower, tt.upper, got)
+			}
+			if strings.HasSuffix(got, "0") {
+				t.Errorf("RankBetween(%q, %q) = %q ends with the zero digit", tt.lower, tt.upper, got)
+			}
+		})
+	}
+}
+
+// Repeatedly inserting at the same spot must keep producing valid keys
+func TestRankBetweenRepeatedInserts(t *testing.T) {
+	for _, side := range []string{"lower", "upper"} {
+		lower, upper := "A", "B"
+		for i := 0; i < 200; i++ {
+			mid, err := RankBetween(lower, upper)
+			if err != nil {
+				t.Fatalf("%s insert %d: RankBetween(%q, %q): %v", side, i, lower, upper, err)
+			}
+			if mid <= lower || mid >= upper {
+				t.Fatalf("%s insert %d: %q not between %q and %q", side, i, mid, lower, upper)
+			}
+			if side == "lower" {
+				upper = mid
+			} else {
+				lower = mid
+			}
+		}
+	}
+}
+
+func TestEvenRanks(t *testing.T) {
+	if got := EvenRanks(0); got != nil {
+		t.Errorf("EvenRanks(0) = %v, want nil", got)
+	}
+
+	for _, n := range []int{1, 2, 3, 61, 62, 1000, 50000} {
+		ranks := EvenRanks(n)
+		if len(ranks) != n {
+			t.Fatalf("EvenRanks(%d) returned %d keys", n, len(ranks))
+		}
+		if !sort.StringsAreSorted(ranks) {
+			t.Errorf("EvenRanks(%d) is not sorted", n)
+		}
+		for i, rank := range ranks {
+			if rank == "" || strings.HasSuffix(rank, "0") || len(rank) > MaxRankLength {
+				t.Fatalf("EvenRanks(%d)[%d] = %q is not a valid short key", n, i, rank)
+			}
+			if i > 0 && ranks[i-1] == rank {
+				t.Fatalf("EvenRanks(%d) repeats %q", n, rank)
+			}
+		}
+
+		// Every gap, including before the first key, still accepts inserts
+		prev := ""
+		for _, rank := range append(ranks, "") {
+			if _, err := RankBetween(prev, rank); err != nil {
+				t.Fatalf("EvenRanks(%d): no key between %q and %q: %v", n, prev, rank, err)
+			}
+			prev = rank
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+)
+
+// HeaderRequestID carries the request ID on API requests and responses, and
+// on outgoing calls made on behalf of a request
+const HeaderRequestID = "X-Request-Id"
+
+// maxRequestIDLength keeps accepted IDs within Postgres' 63-byte
+// application_name alongside the server's name
+const maxRequestIDLength = 40
+
+type requestIDKey struct{}
+
+// ValidRequestID reports whether an incoming request ID is safe to adopt: at
+// most 40 letters, digits, '-', '_', '.' or ':', so it cannot forge log lines
+// or break out of SQL and header values
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// WithRequestID returns a context carrying a request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the context's request ID, or ""
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDTransport sets the X-Request-Id header on outgoing requests whose
+// context carries a request ID
+type RequestIDTransport struct {
+	Base http.RoundTripper // http.DefaultTransport if nil
+}
+
+func (t *RequestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if id := RequestIDFromContext(req.Context()); id != "" && req.Header.Get(HeaderRequestID) == "" {
+		// RoundTrippers must not modify the caller's request
+		req = req.Clone(req.Context())
+		req.Header.Set(HeaderRequestID, id)
+	}
+	return base.RoundTrip(req)
+}
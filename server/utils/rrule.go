@@ -0,0 +1,537 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the RRULE FREQ value
+type Frequency string
+
+const (
+	FreqDaily   Frequency = "DAILY"
+	FreqWeekly  Frequency = "WEEKLY"
+	FreqMonthly Frequency = "MONTHLY"
+	FreqYearly  Frequency = "YEARLY"
+)
+
+// maxRRulePeriods bounds expansion so rules that never match (e.g. BYMONTHDAY=30;BYMONTH=2)
+// cannot loop forever
+const maxRRulePeriods = 50000
+
+// WeekdayNum is a BYDAY entry such as MO, 1MO or -1FR (N == 0 means every such weekday)
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// RRule is a parsed RFC 5545 recurrence rule.
+// Supported parts: FREQ, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH, BYSETPOS, WKST.
+type RRule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	BySetPos   []int
+	WeekStart  time.Weekday
+
+	// untilFloating marks an UNTIL without a "Z" suffix, which is interpreted
+	// in the series timezone rather than UTC
+	untilFloating bool
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// ParseRRule parses an RRULE value such as "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10".
+// An optional "RRULE:" prefix is accepted.
+func ParseRRule(s string) (*RRule, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "RRULE:"), "rrule:")
+	if s == "" {
+		return nil, fmt.Errorf("empty recurrence rule")
+	}
+
+	rule := &RRule{Interval: 1, WeekStart: time.Monday}
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		key = strings.ToUpper(key)
+		value = strings.ToUpper(value)
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate rule part %s", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			switch Frequency(value) {
+			case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+				rule.Freq = Frequency(value)
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", value)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, floating, err := parseRRuleTime(value)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = until
+			rule.untilFloating = floating
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				wd, err := parseWeekdayNum(v)
+				if err != nil {
+					return nil, err
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", v)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("invalid BYMONTH %q", v)
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(n))
+			}
+		case "BYSETPOS":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n == 0 || n < -366 || n > 366 {
+					return nil, fmt.Errorf("invalid BYSETPOS %q", v)
+				}
+				rule.BySetPos = append(rule.BySetPos, n)
+			}
+		case "WKST":
+			day, ok := weekdayCodes[value]
+			if !ok {
+				return nil, fmt.Errorf("invalid WKST %q", value)
+			}
+			rule.WeekStart = day
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, fmt.Errorf("COUNT and UNTIL cannot both be set")
+	}
+	for _, wd := range rule.ByDay {
+		if wd.N != 0 && rule.Freq != FreqMonthly && rule.Freq != FreqYearly {
+			return nil, fmt.Errorf("BYDAY ordinals are only valid with MONTHLY or YEARLY")
+		}
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq == FreqWeekly {
+		return nil, fmt.Errorf("BYMONTHDAY is not valid with WEEKLY")
+	}
+	if len(rule.BySetPos) > 0 && len(rule.ByDay) == 0 && len(rule.ByMonthDay) == 0 && len(rule.ByMonth) == 0 {
+		return nil, fmt.Errorf("BYSETPOS requires another BYxxx part")
+	}
+
+	return rule, nil
+}
+
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+	}
+	day, ok := weekdayCodes[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+	}
+	wd := WeekdayNum{Day: day}
+	if prefix := s[:len(s)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+func parseRRuleTime(s string) (time.Time, bool, error) {
+	if t, err := time.Parse("20060102T150405Z", s); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse("20060102T150405", s); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse("20060102", s); err == nil {
+		// A date-only UNTIL includes the whole day
+		return t.Add(24*time.Hour - time.Second), true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid UNTIL %q", s)
+}
+
+// String returns the canonical RRULE value (without the "RRULE:" prefix)
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			months[i] = strconv.Itoa(int(m))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = weekdayNames[wd.Day]
+			if wd.N != 0 {
+				days[i] = strconv.Itoa(wd.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.BySetPos) > 0 {
+		positions := make([]string, len(r.BySetPos))
+		for i, n := range r.BySetPos {
+			positions[i] = strconv.Itoa(n)
+		}
+		parts = append(parts, "BYSETPOS="+strings.Join(positions, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayNames[r.WeekStart])
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		if r.untilFloating {
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102T150405"))
+		} else {
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// SetUntil ends the rule at the given instant, clearing any COUNT
+func (r *RRule) SetUntil(t time.Time) {
+	r.Count = 0
+	r.Until = t.UTC()
+	r.untilFloating = false
+}
+
+// Occurrence is a single expanded instance of a recurrence rule
+type Occurrence struct {
+	Time  time.Time
+	Index int // 1-based position in the series, counted from DTSTART
+}
+
+// Next returns the first occurrence strictly after "after", skipping any
+// times listed in exdates. Occurrences are expanded from dtstart in loc so
+// wall-clock times stay fixed across DST transitions. The boolean is false
+// when the series has ended.
+func (r *RRule) Next(dtstart time.Time, loc *time.Location, after time.Time, exdates []time.Time) (Occurrence, bool) {
+	var found Occurrence
+	ok := false
+	r.each(dtstart, loc, func(o Occurrence) bool {
+		if !o.Time.After(after) || containsTime(exdates, o.Time) {
+			return true
+		}
+		found, ok = o, true
+		return false
+	})
+	return found, ok
+}
+
+// IndexOf returns the 1-based index of the occurrence at t, or 0 if t is not
+// an occurrence of the rule
+func (r *RRule) IndexOf(dtstart time.Time, loc *time.Location, t time.Time) int {
+	index := 0
+	r.each(dtstart, loc, func(o Occurrence) bool {
+		if o.Time.Equal(t) {
+			index = o.Index
+		}
+		return o.Time.Before(t)
+	})
+	return index
+}
+
+// each calls fn for every occurrence in order until fn returns false or the
+// series ends
+func (r *RRule) each(dtstart time.Time, loc *time.Location, fn func(Occurrence) bool) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	start := dtstart.In(loc)
+	until := r.Until
+	if r.untilFloating && !until.IsZero() {
+		until = time.Date(until.Year(), until.Month(), until.Day(),
+			until.Hour(), until.Minute(), until.Second(), 0, loc)
+	}
+
+	index := 0
+	for period := 0; period < maxRRulePeriods; period++ {
+		for _, day := range r.periodDays(start, period) {
+			t := time.Date(day.Year(), day.Month(), day.Day(),
+				start.Hour(), start.Minute(), start.Second(), 0, loc)
+			if t.Before(start) {
+				continue
+			}
+			if !until.IsZero() && t.After(until) {
+				return
+			}
+			index++
+			if r.Count > 0 && index > r.Count {
+				return
+			}
+			if !fn(Occurrence{Time: t, Index: index}) {
+				return
+			}
+		}
+	}
+}
+
+// periodDays returns the sorted candidate dates (at midnight UTC) for the
+// n-th FREQ/INTERVAL period after dtstart
+func (r *RRule) periodDays(start time.Time, n int) []time.Time {
+	base := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	step := n * r.Interval
+	var days []time.Time
+
+	switch r.Freq {
+	case FreqDaily:
+		day := base.AddDate(0, 0, step)
+		if r.matchesMonth(day) && r.matchesMonthDay(day) && r.matchesWeekday(day) {
+			days = append(days, day)
+		}
+
+	case FreqWeekly:
+		offset := (int(base.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := base.AddDate(0, 0, -offset+7*step)
+		for i := 0; i < 7; i++ {
+			day := weekStart.AddDate(0, 0, i)
+			if len(r.ByDay) == 0 && day.Weekday() != base.Weekday() {
+				continue
+			}
+			if r.matchesMonth(day) && r.matchesWeekday(day) {
+				days = append(days, day)
+			}
+		}
+
+	case FreqMonthly:
+		month := time.Date(base.Year(), base.Month()+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+		if r.matchesMonth(month) {
+			days = r.expandRange(month, month.AddDate(0, 1, 0), base.Day())
+		}
+
+	case FreqYearly:
+		year := base.Year() + step
+		months := r.ByMonth
+		if len(months) == 0 && len(r.ByDay) == 0 {
+			months = []time.Month{base.Month()}
+		}
+		if len(months) == 0 {
+			// BYDAY ordinals without BYMONTH count within the whole year
+			from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+			days = r.expandRange(from, from.AddDate(1, 0, 0), 0)
+		} else {
+			for _, m := range months {
+				from := time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+				if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+					if base.Day() <= daysIn(from) {
+						days = append(days, from.AddDate(0, 0, base.Day()-1))
+					}
+					continue
+				}
+				days = append(days, r.expandRange(from, from.AddDate(0, 1, 0), base.Day())...)
+			}
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return r.selectSetPos(dedupeDays(days))
+}
+
+// selectSetPos applies BYSETPOS to a period's sorted candidates, e.g. the
+// last weekday of the month with BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1
+func (r *RRule) selectSetPos(days []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 {
+		return days
+	}
+	var selected []time.Time
+	for _, n := range r.BySetPos {
+		switch {
+		case n > 0 && n <= len(days):
+			selected = append(selected, days[n-1])
+		case n < 0 && -n <= len(days):
+			selected = append(selected, days[len(days)+n])
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Before(selected[j]) })
+	return dedupeDays(selected)
+}
+
+// expandRange applies BYMONTHDAY and BYDAY within [from, to). defaultDay is
+// used when neither is set (0 disables the default).
+func (r *RRule) expandRange(from, to time.Time, defaultDay int) []time.Time {
+	var days []time.Time
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+			if r.matchesMonthDay(d) && (len(r.ByDay) == 0 || r.matchesWeekday(d)) {
+				days = append(days, d)
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			var matches []time.Time
+			for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+				if d.Weekday() == wd.Day {
+					matches = append(matches, d)
+				}
+			}
+			switch {
+			case wd.N == 0:
+				days = append(days, matches...)
+			case wd.N > 0 && wd.N <= len(matches):
+				days = append(days, matches[wd.N-1])
+			case wd.N < 0 && -wd.N <= len(matches):
+				days = append(days, matches[len(matches)+wd.N])
+			}
+		}
+	case defaultDay > 0:
+		// Months without the DTSTART day (e.g. the 31st) are skipped per RFC 5545
+		if defaultDay <= daysIn(from) {
+			days = append(days, from.AddDate(0, 0, defaultDay-1))
+		}
+	}
+
+	return days
+}
+
+func (r *RRule) matchesMonth(day time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if day.Month() == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RRule) matchesMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := daysIn(day)
+	for _, n := range r.ByMonthDay {
+		if n > 0 && day.Day() == n {
+			return true
+		}
+		if n < 0 && day.Day() == last+n+1 {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWeekday checks BYDAY ignoring ordinals (used where ordinals are not allowed
+// or as a limiter alongside BYMONTHDAY)
+func (r *RRule) matchesWeekday(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if day.Weekday() == wd.Day {
+			return true
+		}
+	}
+	return false
+}
+
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func dedupeDays(days []time.Time) []time.Time {
+	var out []time.Time
+	for _, d := range days {
+		if len(out) == 0 || !d.Equal(out[len(out)-1]) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func containsTime(list []time.Time, t time.Time) bool {
+	for _, v := range list {
+		if v.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTimezone checks that name is a loadable IANA timezone
+func ValidateTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return loc, nil
+}
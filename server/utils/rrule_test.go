@@ -0,0 +1,258 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// expand returns up to n occurrences of rule from dtstart, formatted in loc
+func expand(t *testing.T, rule string, dtstart time.Time, loc *time.Location, n int) []string {
+	t.Helper()
+	r, err := ParseRRule(rule)
+	if err != nil {
+		t.Fatalf("ParseRRule(%q): %v", rule, err)
+	}
+	var got []string
+	r.each(dtstart, loc, func(o Occurrence) bool {
+		got = append(got, o.Time.In(loc).Format("2006-01-02 15:04 MST"))
+		return len(got) < n
+	})
+	return got
+}
+
+func TestRRuleExpansion(t *testing.T) {
+	utc := time.UTC
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		loc     *time.Location
+		n       int
+		want    []string
+	}{
+		{
+			name:    "daily with interval",
+			rule:    "FREQ=DAILY;INTERVAL=2",
+			dtstart: time.Date(2025, 1, 30, 9, 0, 0, 0, utc),
+			loc:     utc,
+			n:       3,
+			want:    []string{"2025-01-30 09:00 UTC", "2025-02-01 09:00 UTC", "2025-02-03 09:00 UTC"},
+		},
+		{
+			name:    "weekly BYDAY expands within the week",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,WE,FR",
+			dtstart: time.Date(2025, 3, 5, 8, 0, 0, 0, utc), // Wednesday
+			loc:     utc,
+			n:       4,
+			want:    []string{"2025-03-05 08:00 UTC", "2025-03-07 08:00 UTC", "2025-03-10 08:00 UTC", "2025-03-12 08:00 UTC"},
+		},
+		{
+			name:    "monthly BYMONTHDAY skips short months",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=31",
+			dtstart: time.Date(2025, 1, 31, 12, 0, 0, 0, utc),
+			loc:     utc,
+			n:       3,
+			want:    []string{"2025-01-31 12:00 UTC", "2025-03-31 12:00 UTC", "2025-05-31 12:00 UTC"},
+		},
+		{
+			name:    "monthly negative BYMONTHDAY",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-1",
+			dtstart: time.Date(2024, 1, 31, 12, 0, 0, 0, utc),
+			loc:     utc,
+			n:       3,
+			want:    []string{"2024-01-31 12:00 UTC", "2024-02-29 12:00 UTC", "2024-03-31 12:00 UTC"},
+		},
+		{
+			name:    "monthly BYDAY ordinal",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR",
+			dtstart: time.Date(2025, 1, 1, 17, 0, 0, 0, utc),
+			loc:     utc,
+			n:       3,
+			want:    []string{"2025-01-31 17:00 UTC", "2025-02-28 17:00 UTC", "2025-03-28 17:00 UTC"},
+		},
+		{
+			name:    "yearly BYMONTH with BYDAY ordinal",
+			rule:    "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH",
+			dtstart: time.Date(2024, 1, 1, 15, 0, 0, 0, utc),
+			loc:     utc,
+			n:       2,
+			want:    []string{"2024-11-28 15:00 UTC", "2025-11-27 15:00 UTC"},
+		},
+		{
+			name:    "BYSETPOS picks the last weekday of the month",
+			rule:    "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+			dtstart: time.Date(2025, 5, 1, 10, 0, 0, 0, utc),
+			loc:     utc,
+			n:       3,
+			want:    []string{"2025-05-30 10:00 UTC", "2025-06-30 10:00 UTC", "2025-07-31 10:00 UTC"},
+		},
+		{
+			name:    "BYSETPOS with several positions",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=1,15,-1;BYSETPOS=1,-1",
+			dtstart: time.Date(2025, 2, 1, 10, 0, 0, 0, utc),
+			loc:     utc,
+			n:       4,
+			want:    []string{"2025-02-01 10:00 UTC", "2025-02-28 10:00 UTC", "2025-03-01 10:00 UTC", "2025-03-31 10:00 UTC"},
+		},
+		{
+			name:    "COUNT ends the series",
+			rule:    "FREQ=WEEKLY;COUNT=2",
+			dtstart: time.Date(2025, 6, 2, 9, 0, 0, 0, utc),
+			loc:     utc,
+			n:       10,
+			want:    []string{"2025-06-02 09:00 UTC", "2025-06-09 09:00 UTC"},
+		},
+		{
+			name:    "UTC UNTIL is inclusive",
+			rule:    "FREQ=DAILY;UNTIL=20250603T090000Z",
+			dtstart: time.Date(2025, 6, 1, 9, 0, 0, 0, utc),
+			loc:     utc,
+			n:       10,
+			want:    []string{"2025-06-01 09:00 UTC", "2025-06-02 09:00 UTC", "2025-06-03 09:00 UTC"},
+		},
+		{
+			name:    "date-only UNTIL covers the whole day in the series timezone",
+			rule:    "FREQ=DAILY;UNTIL=20250602",
+			dtstart: time.Date(2025, 6, 1, 23, 0, 0, 0, newYork),
+			loc:     newYork,
+			n:       10,
+			want:    []string{"2025-06-01 23:00 EDT", "2025-06-02 23:00 EDT"},
+		},
+		{
+			name:    "wall-clock time is kept across spring forward",
+			rule:    "FREQ=DAILY",
+			dtstart: time.Date(2025, 3, 8, 9, 0, 0, 0, newYork),
+			loc:     newYork,
+			n:       3,
+			want:    []string{"2025-03-08 09:00 EST", "2025-03-09 09:00 EDT", "2025-03-10 09:00 EDT"},
+		},
+		{
+			name:    "wall-clock time is kept across fall back",
+			rule:    "FREQ=WEEKLY",
+			dtstart: time.Date(2025, 10, 27, 9, 0, 0, 0, newYork),
+			loc:     newYork,
+			n:       2,
+			want:    []string{"2025-10-27 09:00 EDT", "2025-11-03 09:00 EST"},
+		},
+		{
+			name:    "a rule that never matches stops at the period cap",
+			rule:    "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30",
+			dtstart: time.Date(2025, 1, 1, 0, 0, 0, 0, utc),
+			loc:     utc,
+			n:       1,
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := expand(t, tt.rule, tt.dtstart, tt.loc, tt.n)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("occurrence %d = %s, want %s", i+1, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRRulePeriodCap(t *testing.T) {
+	// A daily rule stops after maxRRulePeriods days even without COUNT or UNTIL
+	r, err := ParseRRule("FREQ=DAILY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	r.each(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.UTC, func(Occurrence) bool {
+		count++
+		return true
+	})
+	if count != maxRRulePeriods {
+		t.Fatalf("got %d occurrences, want %d", count, maxRRulePeriods)
+	}
+}
+
+func TestRRuleNextAndIndexOfAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	r, err := ParseRRule("FREQ=DAILY;COUNT=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Clocks go forward at 02:00 on 2025-03-30
+	dtstart := time.Date(2025, 3, 28, 8, 30, 0, 0, loc)
+	beforeChange := time.Date(2025, 3, 29, 8, 30, 0, 0, loc)
+	afterChange := time.Date(2025, 3, 30, 8, 30, 0, 0, loc)
+
+	next, ok := r.Next(dtstart, loc, beforeChange, nil)
+	if !ok || !next.Time.Equal(afterChange) || next.Index != 3 {
+		t.Fatalf("Next = %v (index %d, ok %v), want %v (index 3)", next.Time, next.Index, ok, afterChange)
+	}
+	if got := afterChange.Sub(beforeChange); got != 23*time.Hour {
+		t.Fatalf("occurrences are %v apart, want 23h", got)
+	}
+
+	next, ok = r.Next(dtstart, loc, beforeChange, []time.Time{afterChange})
+	if !ok || next.Index != 4 || next.Time.Hour() != 8 {
+		t.Fatalf("Next skipping an EXDATE = %v (index %d), want index 4 at 08:30", next.Time, next.Index)
+	}
+
+	if got := r.IndexOf(dtstart, loc, afterChange); got != 3 {
+		t.Errorf("IndexOf after the change = %d, want 3", got)
+	}
+	if got := r.IndexOf(dtstart, loc, afterChange.Add(-time.Hour)); got != 0 {
+		t.Errorf("IndexOf an hour early = %d, want 0", got)
+	}
+
+	last := time.Date(2025, 4, 1, 8, 30, 0, 0, loc)
+	if _, ok := r.Next(dtstart, loc, last, nil); ok {
+		t.Error("Next after the last occurrence should report the series ended")
+	}
+}
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    string
+		wantErr bool
+	}{
+		{rule: "RRULE:freq=weekly;byday=mo,we", want: "FREQ=WEEKLY;BYDAY=MO,WE"},
+		{rule: "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", want: "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1"},
+		{rule: "FREQ=DAILY;UNTIL=20250601T120000Z", want: "FREQ=DAILY;UNTIL=20250601T120000Z"},
+		{rule: "", wantErr: true},
+		{rule: "INTERVAL=2", wantErr: true},
+		{rule: "FREQ=HOURLY", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=3;UNTIL=20250601", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYDAY=1MO", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYMONTHDAY=1", wantErr: true},
+		{rule: "FREQ=MONTHLY;BYSETPOS=1", wantErr: true},
+		{rule: "FREQ=MONTHLY;BYDAY=MO;BYSETPOS=0", wantErr: true},
+		{rule: "FREQ=DAILY;FREQ=WEEKLY", wantErr: true},
+	}
+	for _, tt := range tests {
+		r, err := ParseRRule(tt.rule)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseRRule(%q) = %s, want error", tt.rule, r)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRRule(%q): %v", tt.rule, err)
+			continue
+		}
+		if got := r.String(); got != tt.want {
+			t.Errorf("ParseRRule(%q).String() = %s, want %s", tt.rule, got, tt.want)
+		}
+	}
+}
@@ -10,6 +10,13 @@ const (
 	PrefixItem         = "item"
 	PrefixSession      = "sess"
 	PrefixOAuthAccount = "oauth"
+	PrefixItemSeries   = "series"
+	PrefixConnection   = "conn"
+	PrefixWebhook      = "whk"
+	PrefixDelivery     = "whd"
+	PrefixEvent        = "evt"
+	PrefixJob          = "job"
+	PrefixRequest      = "req"
 )
 
 // NewUserID generates a new TypeID for a user
@@ -36,6 +43,12 @@ func NewOAuthAccountID() string {
 	return tid.String()
 }
 
+// NewItemSeriesID generates a new TypeID for a recurring item series
+func NewItemSeriesID() string {
+	tid, _ := typeid.WithPrefix(PrefixItemSeries)
+	return tid.String()
+}
+
 // ValidateTypeID validates a TypeID string format
 func ValidateTypeID(s string) bool {
 	// Basic validation - check format prefix_base32
@@ -50,3 +63,39 @@ func ValidateTypeID(s string) bool {
 	}
 	return false
 }
+
+// NewConnectionID generates a new TypeID for a real-time connection
+func NewConnectionID() string {
+	tid, _ := typeid.WithPrefix(PrefixConnection)
+	return tid.String()
+}
+
+// NewWebhookID generates a new TypeID for a webhook endpoint
+func NewWebhookID() string {
+	tid, _ := typeid.WithPrefix(PrefixWebhook)
+	return tid.String()
+}
+
+// NewDeliveryID generates a new TypeID for a webhook delivery
+func NewDeliveryID() string {
+	tid, _ := typeid.WithPrefix(PrefixDelivery)
+	return tid.String()
+}
+
+// NewEventID generates a new TypeID for an outgoing event
+func NewEventID() string {
+	tid, _ := typeid.WithPrefix(PrefixEvent)
+	return tid.String()
+}
+
+// NewJobID generates a new TypeID for a background job
+func NewJobID() string {
+	tid, _ := typeid.WithPrefix(PrefixJob)
+	return tid.String()
+}
+
+// NewRequestID generates a new TypeID for an API request
+func NewRequestID() string {
+	tid, _ := typeid.WithPrefix(PrefixRequest)
+	return tid.String()
+}
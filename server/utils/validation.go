@@ -22,3 +22,20 @@ func ValidatePassword(password string) bool {
 	// Minimum 8 characters
 	return len(password) >= 8
 }
+
+const maxTagLength = 50
+
+// NormalizeTags lowercases, trims and deduplicates tags, dropping empty or overlong ones
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || len(tag) > maxTagLength || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
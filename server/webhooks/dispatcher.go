@@ -0,0 +1,280 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/binduni/bun-golang-react-monorepo/server/database"
+	"github.com/binduni/bun-golang-react-monorepo/server/logging"
+	"github.com/binduni/bun-golang-react-monorepo/server/models"
+	"github.com/binduni/bun-golang-react-monorepo/server/utils"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is dead-lettered
+	MaxAttempts = 10
+
+	retryBase = 30 * time.Second
+	retryMax  = 12 * time.Hour
+
+	deliveryTimeout = 10 * time.Second
+	recordTimeout   = 5 * time.Second
+
+	// claimLease must comfortably exceed a delivery attempt
+	claimLease = 2 * time.Minute
+
+	pollInterval    = 2 * time.Second
+	batchSize       = 16
+	concurrency     = 4
+	maxResponseBody = 4096
+
+	userAgent = "Monorepo-Webhooks/1.0"
+)
+
+var errPrivateAddress = errors.New("webhook destination resolves to a private address")
+
+// NewEvent wraps data in an event envelope with a fresh event ID
+func NewEvent(eventType models.WebhookEventType, data any) *models.WebhookEvent {
+	return &models.WebhookEvent{
+		ID:        utils.NewEventID(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+}
+
+// Backoff returns the delay before the next attempt after attempt failures:
+// exponential from 30s, capped at 12h, with ±20% jitter
+func Backoff(attempt int) time.Duration {
+	delay := retryMax
+	if attempt < 20 {
+		delay = min(retryBase<<(attempt-1), retryMax)
+	}
+	spread := int64(delay) / 5
+	return delay + time.Duration(rand.Int64N(2*spread+1)-spread)
+}
+
+// ValidateURL checks that an endpoint URL is an absolute http(s) URL.
+// Plain http is only accepted when allowInsecure is set.
+func ValidateURL(raw string, allowInsecure bool) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return errors.New("URL must be absolute")
+	}
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && allowInsecure:
+	default:
+		return errors.New("URL must use https")
+	}
+	if u.User != nil {
+		return errors.New("URL must not contain credentials")
+	}
+	if u.Fragment != "" {
+		return errors.New("URL must not contain a fragment")
+	}
+	return nil
+}
+
+// Dispatcher delivers queued webhook deliveries. Any number of dispatchers
+// may run against the same database; deliveries are claimed with SKIP LOCKED.
+type Dispatcher struct {
+	db     *database.DB
+	client *http.Client
+}
+
+// NewDispatcher creates a dispatcher. Unless allowPrivate is set, requests to
+// loopback, private and link-local addresses are refused at connect time.
+func NewDispatcher(db *database.DB, allowPrivate bool) *Dispatcher {
+	return &Dispatcher{
+		db:     db,
+		client: newHTTPClient(allowPrivate),
+	}
+}
+
+// Run processes deliveries until ctx is done. In-flight deliveries are
+// allowed to finish before it returns.
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		claimed, err := d.processBatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Webhook dispatcher failed", "error", err)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if claimed == batchSize {
+			continue // More work is probably waiting
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func (d *Dispatcher) processBatch(ctx context.Context) (int, error) {
+	deliveries, err := d.db.ClaimWebhookDeliveries(ctx, batchSize, claimLease)
+	if err != nil {
+		return 0, fmt.Errorf("claim deliveries: %w", err)
+	}
+
+	endpoints := make(map[string]*models.WebhookEndpoint)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		endpoint, ok := endpoints[delivery.EndpointID]
+		if !ok {
+			if endpoint, err = d.db.GetWebhookEndpoint(ctx, delivery.EndpointID); err != nil {
+				// Deleted endpoints take their deliveries with them; otherwise the lease retries it
+				continue
+			}
+			endpoints[delivery.EndpointID] = endpoint
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			d.deliver(ctx, delivery, endpoint)
+		}()
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery, endpoint *models.WebhookEndpoint) {
+	// Let an attempt that has started finish even if shutdown begins
+	ctx = context.WithoutCancel(ctx)
+
+	// Tie the delivery to the API request that caused the event
+	if delivery.RequestID != nil {
+		ctx = logging.WithRequestID(ctx, *delivery.RequestID)
+	}
+
+	attempt := &models.WebhookDeliveryAttempt{
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts,
+	}
+	start := time.Now()
+	status, body, err := d.send(ctx, delivery, endpoint)
+	attempt.DurationMs = int(time.Since(start).Milliseconds())
+
+	if status != 0 {
+		attempt.ResponseStatus = &status
+		attempt.ResponseBody = &body
+	}
+	if err == nil && (status < 200 || status > 299) {
+		err = fmt.Errorf("unexpected response status %d", status)
+	}
+
+	next := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+	case delivery.Attempts >= MaxAttempts:
+		delivery.Status = models.WebhookDeliveryDead
+	default:
+		delivery.Status = models.WebhookDeliveryPending
+		next = next.Add(Backoff(delivery.Attempts))
+	}
+	if err != nil {
+		message := err.Error()
+		attempt.Error = &message
+	}
+
+	recordCtx, cancel := context.WithTimeout(ctx, recordTimeout)
+	defer cancel()
+	if err := d.db.RecordWebhookAttempt(recordCtx, delivery, attempt, next); err != nil {
+		logging.FromContext(ctx).Error("Failed to record webhook attempt", "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery *models.WebhookDelivery, endpoint *models.WebhookEndpoint) (int, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderEventType, string(delivery.EventType))
+	req.Header.Set(HeaderAttempt, strconv.Itoa(delivery.Attempts))
+	req.Header.Set(HeaderSignature, SignatureHeader(endpoint.SigningSecrets(now), now, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20)) // Allow connection reuse
+	body := strings.ReplaceAll(strings.ToValidUTF8(string(data), ""), "\x00", "")
+	return resp.StatusCode, body, nil
+}
+
+func newHTTPClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		// Checked after DNS resolution so hostnames cannot smuggle internal addresses
+		Control: func(_, address string, _ syscall.RawConn) error {
+			if allowPrivate {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: deliveryTimeout,
+		Transport: &utils.RequestIDTransport{Base: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ResponseHeaderTimeout: deliveryTimeout,
+			MaxIdleConnsPerHost:   2,
+			IdleConnTimeout:       90 * time.Second,
+		}},
+		// Redirects are reported as failures rather than followed
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip))
+}
@@ -0,0 +1,90 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEventID   = "X-Webhook-Id"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderEventType = "X-Webhook-Event"
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderAttempt   = "X-Webhook-Attempt"
+)
+
+// DefaultTolerance is how far a signature timestamp may be from the receiver's clock
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	ErrInvalidSignatureHeader = errors.New("invalid signature header")
+	ErrSignatureMismatch      = errors.New("signature mismatch")
+	ErrTimestampOutOfRange    = errors.New("signature timestamp outside tolerance")
+)
+
+// Sign computes the hex HMAC-SHA256 of "<timestamp>.<body>"
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignatureHeader builds "t=<unix>,v1=<sig>[,v1=<sig>...]" with one
+// signature per active secret, so receivers can verify during a rotation
+func SignatureHeader(secrets []string, timestamp time.Time, body []byte) string {
+	ts := timestamp.Unix()
+	var b strings.Builder
+	b.WriteString("t=")
+	b.WriteString(strconv.FormatInt(ts, 10))
+	for _, secret := range secrets {
+		b.WriteString(",v1=")
+		b.WriteString(Sign(secret, ts, body))
+	}
+	return b.String()
+}
+
+// Verify checks a signature header against body using secret, rejecting
+// timestamps more than tolerance away from now to prevent replays
+func Verify(header, secret string, body []byte, tolerance time.Duration, now time.Time) error {
+	var timestamp int64
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrInvalidSignatureHeader
+		}
+		switch key {
+		case "t":
+			ts, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrInvalidSignatureHeader
+			}
+			timestamp = ts
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == 0 || len(signatures) == 0 {
+		return ErrInvalidSignatureHeader
+	}
+
+	if diff := now.Sub(time.Unix(timestamp, 0)); diff > tolerance || diff < -tolerance {
+		return ErrTimestampOutOfRange
+	}
+
+	expected := []byte(Sign(secret, timestamp, body))
+	for _, signature := range signatures {
+		if hmac.Equal(expected, []byte(signature)) {
+			return nil
+		}
+	}
+	return ErrSignatureMismatch
+}
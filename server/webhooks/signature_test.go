@@ -0,0 +1,68 @@
+package webhooks
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignKnownVector(t *testing.T) {
+	// echo -n '1700000000.{"ok":true}' | openssl dgst -sha256 -hmac secret
+	got := Sign("secret", 1700000000, []byte(`{"ok":true}`))
+	want := "c1afc7c2df3db0690d7d75954610ed1a1d959ce96355ccb8c0a8bc09fd0cfc27"
+	if got != want {
+		t.Errorf("Sign() = %s, want %s", got, want)
+	}
+}
+
+func TestSignatureHeader(t *testing.T) {
+	body := []byte("{}")
+	got := SignatureHeader([]string{"a", "b"}, time.Unix(1700000000, 0), body)
+	want := "t=1700000000,v1=" + Sign("a", 1700000000, body) + ",v1=" + Sign("b", 1700000000, body)
+	if got != want {
+		t.Errorf("SignatureHeader() = %s, want %s", got, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"type":"item.created"}`)
+	now := time.Unix(1700000000, 0)
+	header := SignatureHeader([]string{"new-secret", "old-secret"}, now, body)
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	tests := []struct {
+		name    string
+		header  string
+		secret  string
+		body    []byte
+		now     time.Time
+		wantErr error
+	}{
+		{name: "current secret", header: header, secret: "new-secret", body: body, now: now},
+		{name: "previous secret during rotation", header: header, secret: "old-secret", body: body, now: now},
+		{name: "within tolerance", header: header, secret: "new-secret", body: body, now: now.Add(DefaultTolerance)},
+		{name: "clock behind sender", header: header, secret: "new-secret", body: body, now: now.Add(-DefaultTolerance)},
+		{name: "spaces after commas", header: "t=" + ts + ", v1=" + Sign("s", now.Unix(), body), secret: "s", body: body, now: now},
+		{name: "unknown parts are ignored", header: "t=" + ts + ",v0=abc,v1=" + Sign("s", now.Unix(), body), secret: "s", body: body, now: now},
+		{name: "wrong secret", header: header, secret: "other", body: body, now: now, wantErr: ErrSignatureMismatch},
+		{name: "tampered body", header: header, secret: "new-secret", body: []byte(`{"type":"item.deleted"}`), now: now, wantErr: ErrSignatureMismatch},
+		{name: "tampered timestamp", header: "t=" + strconv.FormatInt(now.Unix()+1, 10) + ",v1=" + Sign("s", now.Unix(), body), secret: "s", body: body, now: now, wantErr: ErrSignatureMismatch},
+		{name: "replayed too late", header: header, secret: "new-secret", body: body, now: now.Add(DefaultTolerance + time.Second), wantErr: ErrTimestampOutOfRange},
+		{name: "from the future", header: header, secret: "new-secret", body: body, now: now.Add(-DefaultTolerance - time.Second), wantErr: ErrTimestampOutOfRange},
+		{name: "empty header", header: "", secret: "s", body: body, now: now, wantErr: ErrInvalidSignatureHeader},
+		{name: "missing timestamp", header: "v1=" + Sign("s", now.Unix(), body), secret: "s", body: body, now: now, wantErr: ErrInvalidSignatureHeader},
+		{name: "missing signature", header: "t=" + ts, secret: "s", body: body, now: now, wantErr: ErrInvalidSignatureHeader},
+		{name: "malformed timestamp", header: "t=yesterday,v1=abc", secret: "s", body: body, now: now, wantErr: ErrInvalidSignatureHeader},
+		{name: "part without a value", header: "t=" + ts + ",v1", secret: "s", body: body, now: now, wantErr: ErrInvalidSignatureHeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.header, tt.secret, tt.body, DefaultTolerance, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}